@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/andrescris/products/pkg/catalogio"
+	"github.com/andrescris/products/pkg/maintenance"
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/repository"
+)
+
+// runCommand ejecuta un subcomando de mantenimiento en lugar del servidor.
+func runCommand(ctx context.Context, store repository.Store, name string, args []string) error {
+	switch name {
+	case "reconcile-stock":
+		return reconcileStockCommand(ctx, store, args)
+	case "backfill-derived":
+		return backfillDerivedCommand(ctx, store, args)
+	case "migrate-money":
+		return migrateMoneyCommand(ctx, store, args)
+	case "purge-trash":
+		return purgeTrashCommand(ctx, store, args)
+	case "import-csv":
+		return importCSVCommand(ctx, store, args)
+	case "export":
+		return exportCommand(ctx, store, args)
+	default:
+		return fmt.Errorf("unknown command %q (available: reconcile-stock, backfill-derived, migrate-money, purge-trash, import-csv, export)", name)
+	}
+}
+
+// reconcileStockCommand compara el stock de cada SKU con el saldo del libro
+// de movimientos. Termina con error si encuentra diferencias.
+func reconcileStockCommand(ctx context.Context, store repository.Store, args []string) error {
+	fs := flag.NewFlagSet("reconcile-stock", flag.ExitOnError)
+	fs.Parse(args)
+
+	report, err := maintenance.ReconcileStock(ctx, store, store)
+	if err != nil {
+		return err
+	}
+	if err := maintenance.WriteStockReconciliation(os.Stdout, report); err != nil {
+		return err
+	}
+	if len(report.Drifts) > 0 {
+		return fmt.Errorf("stock drift detected in %d SKUs", len(report.Drifts))
+	}
+	return nil
+}
+
+// backfillDerivedCommand repara filter_price y el resto de campos derivados
+// de los productos guardados antes de que se recalcularan en cada escritura.
+func backfillDerivedCommand(ctx context.Context, store repository.Store, args []string) error {
+	fs := flag.NewFlagSet("backfill-derived", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "only report the products that would change")
+	fs.Parse(args)
+
+	result, err := maintenance.BackfillDerivedFields(ctx, store, *dryRun, os.Stdout)
+	if err != nil {
+		return err
+	}
+	verb := "fixed"
+	if *dryRun {
+		verb = "would be fixed"
+	}
+	fmt.Printf("Products checked: %d, %s: %d\n", result.ProductsChecked, verb, len(result.ProductsFixed))
+	return nil
+}
+
+// migrateMoneyCommand convierte los importes decimales guardados antes de
+// usar unidades menores.
+func migrateMoneyCommand(ctx context.Context, store repository.Store, args []string) error {
+	fs := flag.NewFlagSet("migrate-money", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "only report the products that would change")
+	fs.Parse(args)
+
+	result, err := maintenance.MigrateMoney(ctx, store, *dryRun, os.Stdout)
+	if err != nil {
+		return err
+	}
+	verb := "migrated"
+	if *dryRun {
+		verb = "would be migrated"
+	}
+	fmt.Printf("Products checked: %d, %s: %d\n", result.ProductsChecked, verb, len(result.ProductsMigrated))
+	return nil
+}
+
+// purgeTrashCommand elimina definitivamente lo que lleva más de -days días en
+// la papelera.
+func purgeTrashCommand(ctx context.Context, store repository.Store, args []string) error {
+	fs := flag.NewFlagSet("purge-trash", flag.ExitOnError)
+	days := fs.Int("days", 30, "retention period in days")
+	dryRun := fs.Bool("dry-run", false, "only report what would be purged")
+	fs.Parse(args)
+
+	cutoff := time.Now().UTC().AddDate(0, 0, -*days)
+	result, err := maintenance.PurgeTrash(ctx, store, cutoff, *dryRun, os.Stdout)
+	if err != nil {
+		return err
+	}
+	verb := "purged"
+	if *dryRun {
+		verb = "would be purged"
+	}
+	fmt.Printf("Deleted before %s, %s: %d products, variations of %d products\n",
+		cutoff.Format(time.RFC3339), verb, len(result.ProductsPurged), len(result.VariationsPurged))
+	return nil
+}
+
+// importCSVCommand importa un CSV con una fila por SKU en un subdominio, igual
+// que POST /products/import.
+func importCSVCommand(ctx context.Context, store repository.Store, args []string) error {
+	fs := flag.NewFlagSet("import-csv", flag.ExitOnError)
+	path := fs.String("file", "", "CSV file to import")
+	subdomain := fs.String("subdomain", "", "subdomain of the products")
+	projectID := fs.String("project", "", "project of the new products")
+	currency := fs.String("currency", "", "currency of new products without a currency column")
+	mappingPath := fs.String("mapping", "", "JSON file mapping columns to fields")
+	dryRun := fs.Bool("dry-run", false, "only validate the file and report what would change")
+	fs.Parse(args)
+
+	if *path == "" || *subdomain == "" || *projectID == "" {
+		return fmt.Errorf("-file, -subdomain and -project are required")
+	}
+	var mapping map[string]string
+	if *mappingPath != "" {
+		data, err := os.ReadFile(*mappingPath)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &mapping); err != nil {
+			return fmt.Errorf("reading mapping %s: %w", *mappingPath, err)
+		}
+	}
+	file, err := os.Open(*path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	ctx = repository.WithStockChange(ctx, repository.StockChange{Reason: models.MovementImport, Source: "cli import-csv"})
+	report, err := catalogio.ImportCSV(ctx, store, store, store, file, catalogio.ImportOptions{
+		Subdomain: *subdomain,
+		ProjectID: *projectID,
+		Currency:  *currency,
+		Mapping:   mapping,
+		DryRun:    *dryRun,
+	})
+	if report != nil {
+		// Con un fallo a medias el informe dice qué productos ya se guardaron.
+		if err := catalogio.WriteImportReport(os.Stdout, report); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if report.Partial {
+		return fmt.Errorf("the import was partially applied (%d products saved) and has %d errors", report.Written, len(report.Errors))
+	}
+	if len(report.Errors) > 0 {
+		return fmt.Errorf("the file has %d errors", len(report.Errors))
+	}
+	return nil
+}
+
+// exportCommand escribe el catálogo de un subdominio en un archivo CSV o JSON
+// Lines, igual que GET /products/export.
+func exportCommand(ctx context.Context, store repository.Store, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	path := fs.String("file", "", "output file")
+	subdomain := fs.String("subdomain", "", "subdomain of the products")
+	format := fs.String("format", catalogio.FormatCSV, "csv or jsonl")
+	status := fs.String("status", "", "only export products in this status")
+	fs.Parse(args)
+
+	if *path == "" || *subdomain == "" {
+		return fmt.Errorf("-file and -subdomain are required")
+	}
+	if !catalogio.ValidFormat(*format) {
+		return fmt.Errorf("-format must be csv or jsonl")
+	}
+	file, err := os.Create(*path)
+	if err != nil {
+		return err
+	}
+	result, err := catalogio.Export(ctx, store, file, catalogio.ExportOptions{
+		Subdomain: *subdomain,
+		Format:    *format,
+		Status:    models.ProductStatus(*status),
+	})
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Exported %d products (%d rows) to %s\n", result.Products, result.Rows, *path)
+	return nil
+}
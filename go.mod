@@ -3,6 +3,7 @@ module github.com/andrescris/products
 go 1.24.3
 
 require (
+	cloud.google.com/go/firestore v1.18.0
 	github.com/andrescris/apiKeyService v0.0.0-20250802180704-7fa0cd9d7143
 	github.com/andrescris/firestore v0.0.0-20250727205732-52a86365bed4
 	github.com/andrescris/query-service v0.0.0-20250802014736-a13fa0783865
@@ -21,7 +22,6 @@ require (
 	cloud.google.com/go/auth v0.16.1 // indirect
 	cloud.google.com/go/auth/oauth2adapt v0.2.8 // indirect
 	cloud.google.com/go/compute/metadata v0.7.0 // indirect
-	cloud.google.com/go/firestore v1.18.0 // indirect
 	cloud.google.com/go/iam v1.5.2 // indirect
 	cloud.google.com/go/longrunning v0.6.7 // indirect
 	cloud.google.com/go/monitoring v1.24.2 // indirect
@@ -1,13 +1,18 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	apiKeyMiddleware "github.com/andrescris/apiKeyService/pkg/middleware"
 	"github.com/andrescris/firestore/lib/firebase"
 	handlers "github.com/andrescris/products/pkg/Handlers"
 	"github.com/andrescris/products/pkg/middleware"
+	"github.com/andrescris/products/pkg/repository"
+	"github.com/andrescris/products/pkg/workers"
 	"github.com/andrescris/query-service/queryservice"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -31,9 +36,62 @@ func main() {
 		log.Fatalf("CRITICAL: Firestore client is nil immediately after initialization!")
 	}
 
+	// 3. Repositorio de productos. STORAGE_BACKEND=memory permite levantar la API
+	// sin depender de datos reales (útil para pruebas locales).
+	var store repository.Store = repository.NewFirestoreRepository(firestoreClient)
+	if os.Getenv("STORAGE_BACKEND") == "memory" {
+		log.Println("Using in-memory product repository")
+		store = repository.NewMemoryRepository()
+	}
+
+	// Subcomandos de mantenimiento, p. ej. `go run . reconcile-stock`.
+	if len(os.Args) > 1 {
+		if err := runCommand(context.Background(), store, os.Args[1], os.Args[2:]); err != nil {
+			log.Printf("Command %s failed: %v", os.Args[1], err)
+			firebase.Close()
+			os.Exit(1)
+		}
+		return
+	}
+
+	productHandler := handlers.NewProductHandler(store, store, store, store, store, store, store)
+	reservationHandler := handlers.NewReservationHandler(store)
+	stockHandler := handlers.NewStockHandler(store, store, store)
+	revisionHandler := handlers.NewRevisionHandler(store, store, store)
+	locationHandler := handlers.NewLocationHandler(store, store)
+	priceListHandler := handlers.NewPriceListHandler(store)
+	exchangeRateHandler := handlers.NewExchangeRateHandler(store, store)
+	taxHandler := handlers.NewTaxHandler(store, store)
+	categoryHandler := handlers.NewCategoryHandler(store, store)
+	auditHandler := handlers.NewAuditHandler(store)
+	webhookHandler := handlers.NewWebhookHandler(store)
+	eventStreamHandler := handlers.NewEventStreamHandler(store)
+
+	// Registro de auditoría de las rutas de escritura y de la consulta genérica.
+	audit := middleware.AuditMiddleware(store)
+
+	// Las reservas vencidas se devuelven al stock en segundo plano.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	workers.StartReservationSweeper(ctx, store, durationFromEnv("RESERVATION_SWEEP_INTERVAL", time.Minute))
+	workers.StartSaleScheduler(ctx, store, durationFromEnv("SALE_SCHEDULER_INTERVAL", time.Minute))
+	workers.StartPublishScheduler(ctx, store, durationFromEnv("PUBLISH_SCHEDULER_INTERVAL", time.Minute))
+	// Reparto del outbox de eventos y envío de webhooks con reintentos.
+	workers.StartWebhookDelivery(ctx, store, workers.NewWebhookClient(10*time.Second),
+		durationFromEnv("WEBHOOK_DELIVERY_INTERVAL", 10*time.Second))
+	// Retención del registro de eventos (stream SSE): EVENT_RETENTION_DAYS=0 la desactiva.
+	if days := intFromEnv("EVENT_RETENTION_DAYS", 7); days > 0 {
+		workers.StartEventRetention(ctx, store, durationFromEnv("EVENT_PURGE_INTERVAL", time.Hour), time.Duration(days)*24*time.Hour)
+	}
+	// Retención de la papelera: TRASH_RETENTION_DAYS=0 la desactiva.
+	if days := intFromEnv("TRASH_RETENTION_DAYS", 30); days > 0 {
+		workers.StartTrashRetention(ctx, store, durationFromEnv("TRASH_PURGE_INTERVAL", time.Hour),
+			time.Duration(days)*24*time.Hour, os.Getenv("TRASH_PURGE_DRY_RUN") == "true")
+	}
+
 	r := gin.Default()
 
-	// 3. Inyecta la dependencia globalmente
+	// 4. Inyecta la dependencia globalmente
 	r.Use(func(c *gin.Context) {
 		c.Set("firestoreClient", firestoreClient)
 		c.Next()
@@ -43,36 +101,137 @@ func main() {
 	{
 		// Endpoint genérico para consultas, ahora también para productos
 		api.POST("/collections/:collection/query",
+			audit,
 			apiKeyMiddleware.AuthMiddleware("read:products"), // Protegido con permiso de lectura
+			middleware.AuditQuerySubdomain(),
 			queryservice.ConditionalSubdomainFilterMiddleware(),
 			queryservice.QueryHandler,
 		)
 
+		// Registro de auditoría (?subdomain=, from, to, route, uid, apiKey, productId; sin subdomain, las entradas sin subdominio)
+		api.GET("/audit-log", apiKeyMiddleware.AuthMiddleware("read:audit"), auditHandler.ListAuditEntries)
+
+		// Webhooks por subdominio y su historial de envíos
+		webhooks := api.Group("/webhooks")
+		{
+			webhooks.GET("", apiKeyMiddleware.AuthMiddleware("read:products"), webhookHandler.ListWebhooks)
+			webhooks.GET("/:webhookId", apiKeyMiddleware.AuthMiddleware("read:products"), webhookHandler.GetWebhook)
+			webhooks.GET("/:webhookId/deliveries", apiKeyMiddleware.AuthMiddleware("read:products"), webhookHandler.ListDeliveries)
+			webhooks.POST("", audit, apiKeyMiddleware.AuthMiddleware("write:products"), webhookHandler.CreateWebhook)
+			webhooks.PATCH("/:webhookId", audit, apiKeyMiddleware.AuthMiddleware("write:products"), webhookHandler.UpdateWebhook)
+			webhooks.DELETE("/:webhookId", audit, apiKeyMiddleware.AuthMiddleware("write:products"), webhookHandler.DeleteWebhook)
+			// Reintento manual de un envío (p. ej. uno en dead letter)
+			webhooks.POST("/:webhookId/deliveries/:deliveryId/replay", audit, apiKeyMiddleware.AuthMiddleware("write:products"), webhookHandler.ReplayDelivery)
+		}
+
+		// Ubicaciones de inventario (tiendas, bodegas) por proyecto
+		locations := api.Group("/locations")
+		{
+			locations.GET("", apiKeyMiddleware.AuthMiddleware("read:products"), locationHandler.ListLocations)
+			locations.POST("", audit, apiKeyMiddleware.AuthMiddleware("write:products"), locationHandler.CreateLocation)
+			locations.PATCH("/:locationId", audit, apiKeyMiddleware.AuthMiddleware("write:products"), locationHandler.UpdateLocation)
+		}
+
+		// Listas de precios por grupo de clientes (B2B)
+		priceLists := api.Group("/price-lists")
+		{
+			priceLists.GET("", apiKeyMiddleware.AuthMiddleware("read:products"), priceListHandler.ListPriceLists)
+			priceLists.GET("/:priceListId", apiKeyMiddleware.AuthMiddleware("read:products"), priceListHandler.GetPriceList)
+			priceLists.POST("", audit, apiKeyMiddleware.AuthMiddleware("write:products"), priceListHandler.CreatePriceList)
+			priceLists.PATCH("/:priceListId", audit, apiKeyMiddleware.AuthMiddleware("write:products"), priceListHandler.UpdatePriceList)
+		}
+
+		// Tabla de tipos de cambio por proyecto (parámetro currency en lecturas)
+		exchangeRates := api.Group("/exchange-rates")
+		{
+			exchangeRates.GET("/:projectId", apiKeyMiddleware.AuthMiddleware("read:products"), exchangeRateHandler.GetExchangeRates)
+			exchangeRates.PUT("/:projectId", audit, apiKeyMiddleware.AuthMiddleware("write:products"), exchangeRateHandler.PutExchangeRates)
+		}
+
+		// Tabla de impuestos por proyecto y región (desglose neto/impuesto/bruto)
+		taxRates := api.Group("/tax-rates")
+		{
+			taxRates.GET("/:projectId", apiKeyMiddleware.AuthMiddleware("read:products"), taxHandler.GetTaxTable)
+			taxRates.PUT("/:projectId", audit, apiKeyMiddleware.AuthMiddleware("write:products"), taxHandler.PutTaxTable)
+		}
+
+		// Árbol de categorías por proyecto
+		categories := api.Group("/categories")
+		{
+			categories.GET("", apiKeyMiddleware.AuthMiddleware("read:products"), categoryHandler.ListCategories)
+			categories.GET("/:categoryId", apiKeyMiddleware.AuthMiddleware("read:products"), categoryHandler.GetCategory)
+			categories.POST("", audit, apiKeyMiddleware.AuthMiddleware("write:products"), categoryHandler.CreateCategory)
+			categories.PATCH("/:categoryId", audit, apiKeyMiddleware.AuthMiddleware("write:products"), categoryHandler.UpdateCategory)
+			categories.POST("/:categoryId/move", audit, apiKeyMiddleware.AuthMiddleware("write:products"), categoryHandler.MoveCategory)
+			categories.DELETE("/:categoryId", audit, apiKeyMiddleware.AuthMiddleware("write:products"), categoryHandler.DeleteCategory)
+		}
+
 		products := api.Group("/products")
 		{
 
 			// --- RUTAS DE LECTURA (PÚBLICAS O SEMIPÚBLICAS) ---
 			// No necesitan el middleware de "write:products"
-			products.GET("/:id", middleware.SessionAuthMiddleware(), handlers.GetProductByID)
-			products.POST("/search", middleware.SessionAuthMiddleware(), handlers.ListProducts)
+			products.GET("/:id", middleware.SessionAuthMiddleware(), productHandler.GetProductByID)
+			products.POST("/search", middleware.SessionAuthMiddleware(), productHandler.ListProducts)
+			// Stream SSE de cambios del subdominio (?productId=, ?category=, Last-Event-ID)
+			products.GET("/events", middleware.SessionAuthMiddleware(), eventStreamHandler.StreamEvents)
+			// Cotización con precios por volumen y lista de precios de la sesión
+			products.POST("/quote", middleware.SessionAuthMiddleware(), productHandler.QuoteProducts)
+			// Libro de movimientos de stock: solo para integraciones con API Key.
+			products.GET("/:id/stock-movements", apiKeyMiddleware.AuthMiddleware("read:products"), stockHandler.ListStockMovements)
+			// Historial de revisiones y diff entre dos revisiones (?to=, por defecto la actual)
+			products.GET("/:id/revisions", apiKeyMiddleware.AuthMiddleware("read:products"), revisionHandler.ListRevisions)
+			products.GET("/:id/revisions/:rev", apiKeyMiddleware.AuthMiddleware("read:products"), revisionHandler.GetRevision)
+			products.GET("/:id/revisions/:rev/diff", apiKeyMiddleware.AuthMiddleware("read:products"), revisionHandler.DiffRevisions)
+			// Papelera: productos eliminados de un subdominio (?subdomain=)
+			products.GET("/trash", apiKeyMiddleware.AuthMiddleware("read:products"), productHandler.ListTrash)
+			// Exportación completa del catálogo (format=csv|jsonl), en streaming
+			products.GET("/export", apiKeyMiddleware.AuthMiddleware("read:products"), productHandler.ExportProducts)
 			// --- RUTAS DE ESCRITURA ---
 			// Protegidas con el permiso "write:products"
 			writeRoutes := products.Group("/")
-			writeRoutes.Use(apiKeyMiddleware.AuthMiddleware("write:products"))
+			writeRoutes.Use(audit, apiKeyMiddleware.AuthMiddleware("write:products"))
 			{
-				writeRoutes.POST("/", handlers.CreateProduct)
-				writeRoutes.PATCH("/:id", handlers.UpdateProduct)
-				writeRoutes.DELETE("/:id", handlers.DeleteProduct)
+				writeRoutes.POST("/", productHandler.CreateProduct)
+				// Altas, cambios y bajas en lote (resultado por operación; atomic = todo o nada)
+				writeRoutes.POST("/bulk", productHandler.BulkProducts)
+				// Importación CSV por SKU (dry_run=true solo valida)
+				writeRoutes.POST("/import", productHandler.ImportProducts)
+				writeRoutes.PATCH("/:id", productHandler.UpdateProduct)
+				writeRoutes.DELETE("/:id", productHandler.DeleteProduct)
+				writeRoutes.POST("/:id/restore", productHandler.RestoreProduct)
+				// Vuelve al estado de una revisión anterior (queda como revisión nueva)
+				writeRoutes.POST("/:id/revisions/:rev/rollback", revisionHandler.RollbackRevision)
+
+				// Ciclo de vida: borrador → publicado → archivado
+				writeRoutes.POST("/:id/publish", productHandler.PublishProduct)
+				writeRoutes.POST("/:id/unpublish", productHandler.UnpublishProduct)
+				writeRoutes.POST("/:id/archive", productHandler.ArchiveProduct)
 
 				// --- RUTAS DE VARIACIONES CORREGIDAS ---
 				// Usamos :id en lugar de :productId para ser consistentes
 
 				// Crear una nueva variación para un producto existente
-				writeRoutes.POST("/:id/variations", handlers.CreateVariation)
+				writeRoutes.POST("/:id/variations", productHandler.CreateVariation)
+				// Generar las variaciones que faltan de la matriz de opciones
+				writeRoutes.POST("/:id/variations/generate", productHandler.GenerateVariations)
 				// Actualizar una variación específica
-				writeRoutes.PATCH("/:id/variations/:variationId", handlers.UpdateVariation)
+				writeRoutes.PATCH("/:id/variations/:variationId", productHandler.UpdateVariation)
 				// Eliminar (desactivar) una variación específica
-				writeRoutes.DELETE("/:id/variations/:variationId", handlers.DeleteVariation)
+				writeRoutes.DELETE("/:id/variations/:variationId", productHandler.DeleteVariation)
+				writeRoutes.POST("/:id/variations/:variationId/restore", productHandler.RestoreVariation)
+
+				// Ajuste manual de stock (queda registrado en el libro de movimientos)
+				writeRoutes.POST("/:id/stock-adjustments", stockHandler.AdjustStock)
+
+				// Transferencia de stock entre ubicaciones (una sola transacción)
+				writeRoutes.POST("/stock-transfers", locationHandler.TransferStock)
+
+				// --- RESERVAS DE STOCK PARA CHECKOUT ---
+				writeRoutes.POST("/reservations", reservationHandler.CreateReservation)
+				writeRoutes.GET("/reservations/:reservationId", reservationHandler.GetReservation)
+				writeRoutes.POST("/reservations/:reservationId/commit", reservationHandler.CommitReservation)
+				writeRoutes.POST("/reservations/:reservationId/release", reservationHandler.ReleaseReservation)
 			}
 
 		}
@@ -85,3 +244,30 @@ func main() {
 	log.Printf("🚀 Servidor de API de Productos iniciado en http://localhost:%s", port)
 	r.Run(":" + port)
 }
+
+// durationFromEnv lee una duración (formato de time.ParseDuration) o usa el valor por defecto.
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("Invalid %s=%q, using %s", key, value, fallback)
+		return fallback
+	}
+	return d
+}
+
+func intFromEnv(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Printf("Invalid %s=%q, using %d", key, value, fallback)
+		return fallback
+	}
+	return n
+}
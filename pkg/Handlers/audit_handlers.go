@@ -0,0 +1,80 @@
+package Handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/andrescris/products/pkg/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// AuditHandler expone el registro de auditoría a los administradores.
+type AuditHandler struct {
+	audit repository.AuditRepository
+}
+
+// NewAuditHandler crea el handler del registro de auditoría.
+func NewAuditHandler(audit repository.AuditRepository) *AuditHandler {
+	return &AuditHandler{audit: audit}
+}
+
+// ListAuditEntries consulta el registro de un subdominio, de lo más reciente
+// a lo más antiguo. Sin subdomain lista las entradas que quedaron sin
+// subdominio (peticiones rechazadas antes de identificar el recurso), que no
+// pertenecen a ningún cliente. Filtros opcionales: from y to (RFC 3339; to es
+// exclusivo), route, uid, apiKey y productId.
+func (h *AuditHandler) ListAuditEntries(c *gin.Context) {
+	filter := repository.AuditFilter{
+		Subdomain: c.Query("subdomain"),
+		Route:     c.Query("route"),
+		UID:       c.Query("uid"),
+		APIKey:    c.Query("apiKey"),
+		ProductID: c.Query("productId"),
+	}
+	filter.Unattributed = filter.Subdomain == ""
+	for param, target := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": param + " must be an RFC 3339 timestamp.", "details": err.Error()})
+			return
+		}
+		*target = t
+	}
+	limit, offset, ok := pagination(c)
+	if !ok {
+		return
+	}
+
+	allowedSubdomains, ok := getSubdomainsFromContext(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Could not verify user permissions."})
+		return
+	}
+	if !filter.Unattributed && !isSubdomainAllowed(allowedSubdomains, filter.Subdomain) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to access resources in this subdomain."})
+		return
+	}
+
+	entries, err := h.audit.ListAuditEntries(context.Background(), filter, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list audit entries", "details": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"success": true,
+		"count":   len(entries),
+		"limit":   limit,
+		"offset":  offset,
+		"data":    entries,
+	}
+	if len(entries) == limit {
+		response["nextOffset"] = offset + limit
+	}
+	c.JSON(http.StatusOK, response)
+}
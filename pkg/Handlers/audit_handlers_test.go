@@ -0,0 +1,62 @@
+package Handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+func TestListAuditEntries(t *testing.T) {
+	api := newTestAPI(t)
+	now := time.Now().UTC()
+	for _, entry := range []models.AuditEntry{
+		{ID: "aud-1", Subdomain: "s", Route: "/api/v1/products/:id", Status: 200, CreatedAt: now.Add(-3 * time.Minute)},
+		{ID: "aud-2", Subdomain: "other", Route: "/api/v1/products/:id", Status: 200, CreatedAt: now.Add(-2 * time.Minute)},
+		{ID: "aud-3", Route: "/api/v1/products/:id", Status: 401, CreatedAt: now.Add(-time.Minute)},
+		{ID: "aud-4", Route: "/api/v1/collections/:collection/query", Status: 401, CreatedAt: now},
+	} {
+		if err := api.store.RecordAudit(context.Background(), &entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		want   []string
+	}{
+		{"own subdomain", "?subdomain=s", http.StatusOK, []string{"aud-1"}},
+		{"another subdomain", "?subdomain=other", http.StatusForbidden, nil},
+		{"entries without subdomain", "", http.StatusOK, []string{"aud-4", "aud-3"}},
+		{"without subdomain by route", "?route=/api/v1/products/:id", http.StatusOK, []string{"aud-3"}},
+		{"without subdomain by time", "?to=" + now.Add(-30*time.Second).Format(time.RFC3339Nano), http.StatusOK, []string{"aud-3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var entries []models.AuditEntry
+			rec := api.do(http.MethodGet, "/api/v1/audit-log"+tt.query, nil)
+			if tt.status != http.StatusOK {
+				expect(t, rec, tt.status, nil)
+				return
+			}
+			expect(t, rec, tt.status, &entries)
+			ids := []string{}
+			for _, entry := range entries {
+				ids = append(ids, entry.ID)
+			}
+			if len(ids) != len(tt.want) {
+				t.Fatalf("entries = %v, want %v", ids, tt.want)
+			}
+			for i := range ids {
+				if ids[i] != tt.want[i] {
+					t.Errorf("entries = %v, want %v", ids, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package Handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/andrescris/products/pkg/middleware"
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/repository"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxBulkOperations es el máximo de operaciones por petición.
+	maxBulkOperations = 1000
+	// bulkBatchSize es cuántas operaciones se escriben por transacción. El
+	// modo atómico escribe todo en una sola, así que no admite más.
+	bulkBatchSize = 100
+)
+
+const (
+	bulkCreate     = "create"
+	bulkUpdate     = "update"
+	bulkDeactivate = "deactivate"
+)
+
+type bulkOperation struct {
+	Op string `json:"op"`
+	// ID y Version (el equivalente a If-Match, opcional) son para update y deactivate.
+	ID      string                 `json:"id"`
+	Version *int64                 `json:"version"`
+	Product *models.Product        `json:"product"`
+	Fields  map[string]interface{} `json:"fields"`
+}
+
+type bulkRequest struct {
+	// Atomic aplica todas las operaciones o ninguna.
+	Atomic     bool            `json:"atomic"`
+	Operations []bulkOperation `json:"operations"`
+}
+
+// bulkResult es el resultado de una operación. Status y Error son los que
+// habría devuelto el endpoint individual; Details lleva el resto de su cuerpo.
+type bulkResult struct {
+	Index   int    `json:"index"`
+	Op      string `json:"op"`
+	ID      string `json:"id,omitempty"`
+	Success bool   `json:"success"`
+	Status  int    `json:"status"`
+	Version int64  `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Details gin.H  `json:"details,omitempty"`
+}
+
+func (r *bulkResult) fail(err *requestError) {
+	r.Success = false
+	r.Status = err.status
+	r.Error, _ = err.body["error"].(string)
+	r.Details = nil
+	for key, value := range err.body {
+		if key == "error" {
+			continue
+		}
+		if r.Details == nil {
+			r.Details = gin.H{}
+		}
+		r.Details[key] = value
+	}
+}
+
+func (r *bulkResult) succeed(product *models.Product, status int) {
+	r.Success = true
+	r.Status = status
+	r.ID = product.ID
+	r.Version = product.Version
+}
+
+// errNotApplied es el resultado de las operaciones válidas de un lote
+// atómico que no se escribió.
+var errNotApplied = &requestError{http.StatusFailedDependency, gin.H{"error": "Not applied: another operation of the atomic batch failed."}}
+
+// BulkProducts aplica hasta maxBulkOperations altas, cambios y bajas con las
+// mismas validaciones que los endpoints individuales y devuelve el resultado
+// de cada una. Sin atomic, las operaciones válidas se escriben en lotes de
+// bulkBatchSize aunque otras fallen; con atomic se escriben todas o ninguna.
+func (h *ProductHandler) BulkProducts(c *gin.Context) {
+	var req bulkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if len(req.Operations) == 0 || len(req.Operations) > maxBulkOperations {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "operations must contain between 1 and 1000 items."})
+		return
+	}
+	if req.Atomic && len(req.Operations) > bulkBatchSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Atomic requests accept at most 100 operations."})
+		return
+	}
+
+	allowedSubdomains, ok := getSubdomainsFromContext(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Could not verify user permissions."})
+		return
+	}
+
+	// Sin motivo en el context, cada escritura usa el suyo (stock inicial o ajuste).
+	ctx := stockContext(c, "")
+	results := make([]bulkResult, len(req.Operations))
+	writes := make([]repository.BulkWrite, 0, len(req.Operations))
+	indexes := make([]int, 0, len(req.Operations))
+	seen := map[string]bool{}
+	subdomains := map[string]bool{}
+	for i, op := range req.Operations {
+		results[i] = bulkResult{Index: i, Op: op.Op, ID: op.ID}
+		write, subdomain, err := h.prepareBulkOperation(ctx, allowedSubdomains, op)
+		if subdomain != "" {
+			subdomains[subdomain] = true
+		}
+		if err == nil && seen[write.ProductID] {
+			err = repositoryError(repository.ErrDuplicateBulkProduct, "")
+		}
+		if err != nil {
+			results[i].fail(err)
+			continue
+		}
+		seen[write.ProductID] = true
+		writes = append(writes, write)
+		indexes = append(indexes, i)
+	}
+	if len(subdomains) == 1 {
+		for subdomain := range subdomains {
+			middleware.SetAuditSubdomain(c, subdomain)
+		}
+	}
+
+	switch {
+	case req.Atomic && len(writes) < len(req.Operations):
+		for _, i := range indexes {
+			results[i].fail(errNotApplied)
+		}
+	case req.Atomic:
+		h.applyBulk(ctx, writes, indexes, results, true)
+	default:
+		for start := 0; start < len(writes); start += bulkBatchSize {
+			end := min(start+bulkBatchSize, len(writes))
+			h.applyBulk(ctx, writes[start:end], indexes[start:end], results, false)
+		}
+	}
+
+	applied := 0
+	for _, result := range results {
+		if result.Success {
+			applied++
+		}
+	}
+	status := http.StatusOK
+	if applied < len(results) {
+		status = http.StatusMultiStatus
+	}
+	c.JSON(status, gin.H{
+		"success": applied == len(results),
+		"atomic":  req.Atomic,
+		"applied": applied,
+		"failed":  len(results) - applied,
+		"data":    results,
+	})
+}
+
+// prepareBulkOperation valida una operación como lo haría su endpoint
+// individual y la traduce a una escritura. También devuelve el subdominio
+// afectado, si se llegó a conocer.
+func (h *ProductHandler) prepareBulkOperation(ctx context.Context, allowedSubdomains []interface{}, op bulkOperation) (repository.BulkWrite, string, *requestError) {
+	switch op.Op {
+	case bulkCreate:
+		if op.Product == nil {
+			return repository.BulkWrite{}, "", badRequest("product is required for create operations.")
+		}
+		product := op.Product
+		if err := validateNewProduct(product); err != nil {
+			return repository.BulkWrite{}, product.Subdomain, err
+		}
+		if !isSubdomainAllowed(allowedSubdomains, product.Subdomain) {
+			return repository.BulkWrite{}, product.Subdomain, &requestError{http.StatusForbidden, gin.H{"error": "You do not have permission to create resources in this subdomain."}}
+		}
+		if err := h.prepareNewProduct(ctx, product); err != nil {
+			return repository.BulkWrite{}, product.Subdomain, err
+		}
+		return repository.BulkWrite{Create: product, ProductID: product.ID}, product.Subdomain, nil
+
+	case bulkUpdate, bulkDeactivate:
+		if op.ID == "" {
+			return repository.BulkWrite{}, "", badRequest("id is required for update and deactivate operations.")
+		}
+		if op.Op == bulkUpdate && len(op.Fields) == 0 {
+			return repository.BulkWrite{}, "", badRequest("fields is required for update operations.")
+		}
+		product, err := h.repo.Get(ctx, op.ID)
+		if err != nil {
+			return repository.BulkWrite{}, "", repositoryError(err, "Failed to load product")
+		}
+		if !isSubdomainAllowed(allowedSubdomains, product.Subdomain) {
+			return repository.BulkWrite{}, product.Subdomain, &requestError{http.StatusForbidden, gin.H{"error": "You do not have permission to modify resources in this subdomain."}}
+		}
+
+		write := repository.BulkWrite{ProductID: op.ID, ExpectedVersion: repository.AnyVersion}
+		if op.Version != nil {
+			write.ExpectedVersion = *op.Version
+		}
+		if op.Op == bulkDeactivate {
+			write.Deactivate = true
+			return write, product.Subdomain, nil
+		}
+		if err := h.prepareUpdate(product, op.Fields); err != nil {
+			return repository.BulkWrite{}, product.Subdomain, err
+		}
+		write.Updates = op.Fields
+		return write, product.Subdomain, nil
+
+	default:
+		return repository.BulkWrite{}, "", badRequest("op must be create, update or deactivate.")
+	}
+}
+
+// applyBulk escribe un lote y anota el resultado de cada operación. Si una
+// escritura falla en un lote no atómico, las demás se reintentan una a una.
+func (h *ProductHandler) applyBulk(ctx context.Context, writes []repository.BulkWrite, indexes []int, results []bulkResult, atomic bool) {
+	products, err := h.bulk.ApplyBulk(ctx, writes)
+	if err == nil {
+		for j, product := range products {
+			status := http.StatusOK
+			if writes[j].Create != nil {
+				status = http.StatusCreated
+			}
+			results[indexes[j]].succeed(product, status)
+		}
+		return
+	}
+
+	var bulkErr *repository.BulkError
+	if !errors.As(err, &bulkErr) {
+		for _, i := range indexes {
+			results[i].fail(repositoryError(err, "Failed to write products"))
+		}
+		return
+	}
+	if atomic || len(writes) == 1 {
+		for j, i := range indexes {
+			if j == bulkErr.Index {
+				results[i].fail(repositoryError(bulkErr.Err, "Failed to write product"))
+			} else {
+				results[i].fail(errNotApplied)
+			}
+		}
+		return
+	}
+	for j := range writes {
+		h.applyBulk(ctx, writes[j:j+1], indexes[j:j+1], results, false)
+	}
+}
@@ -0,0 +1,108 @@
+package Handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/andrescris/firestore/lib/firebase"
+)
+
+func TestBulkProducts(t *testing.T) {
+	rename := func(version int64) map[string]interface{} {
+		return map[string]interface{}{"op": "update", "id": "{A}", "version": version, "fields": map[string]interface{}{"name": "Renamed"}}
+	}
+	create := map[string]interface{}{"op": "create", "product": simpleProductBody("B")}
+	invalid := simpleProductBody("B")
+	delete(invalid, "name")
+
+	tests := []struct {
+		name       string
+		atomic     bool
+		operations []map[string]interface{}
+		status     int
+		statuses   []int
+		// products y version son el total de productos y la versión de A tras la petición.
+		products int
+		version  int64
+	}{
+		{"all applied", false, []map[string]interface{}{rename(1), create}, http.StatusOK, []int{200, 201}, 2, 2},
+		{"stale version fails alone", false, []map[string]interface{}{rename(9), create}, http.StatusMultiStatus, []int{412, 201}, 2, 1},
+		{"invalid create fails alone", false, []map[string]interface{}{rename(1), {"op": "create", "product": invalid}}, http.StatusMultiStatus, []int{200, 400}, 1, 2},
+		{"same product twice", false, []map[string]interface{}{rename(1), rename(1)}, http.StatusMultiStatus, []int{200, 409}, 1, 2},
+		{"unknown op", false, []map[string]interface{}{{"op": "upsert"}, create}, http.StatusMultiStatus, []int{400, 201}, 2, 1},
+		{"atomic all applied", true, []map[string]interface{}{rename(1), create}, http.StatusOK, []int{200, 201}, 2, 2},
+		{"atomic write conflict", true, []map[string]interface{}{create, rename(9)}, http.StatusMultiStatus, []int{424, 412}, 1, 1},
+		{"atomic invalid operation", true, []map[string]interface{}{rename(1), {"op": "create", "product": invalid}}, http.StatusMultiStatus, []int{424, 400}, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newTestAPI(t)
+			a := createProduct(t, api, simpleProductBody("A"))
+			operations := make([]map[string]interface{}, len(tt.operations))
+			for i, op := range tt.operations {
+				operations[i] = map[string]interface{}{}
+				for key, value := range op {
+					if value == "{A}" {
+						value = a.ID
+					}
+					operations[i][key] = value
+				}
+			}
+
+			rec := api.do(http.MethodPost, "/api/v1/products/bulk", map[string]interface{}{"atomic": tt.atomic, "operations": operations})
+			var results []bulkResult
+			expect(t, rec, tt.status, &results)
+			if len(results) != len(tt.statuses) {
+				t.Fatalf("results = %+v", results)
+			}
+			for i, result := range results {
+				if result.Status != tt.statuses[i] || result.Success != (result.Status < 300) {
+					t.Errorf("results[%d] = %+v, want status %d", i, result, tt.statuses[i])
+				}
+			}
+
+			products, err := api.store.Query(context.Background(), firebase.QueryOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(products) != tt.products {
+				t.Errorf("products = %d, want %d", len(products), tt.products)
+			}
+			current, err := api.store.Get(context.Background(), a.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if current.Version != tt.version {
+				t.Errorf("version of A = %d, want %d", current.Version, tt.version)
+			}
+		})
+	}
+}
+
+func TestBulkProductsLimits(t *testing.T) {
+	api := newTestAPI(t)
+	operations := make([]map[string]interface{}, bulkBatchSize+1)
+	for i := range operations {
+		operations[i] = map[string]interface{}{"op": "deactivate", "id": "x"}
+	}
+	tests := []struct {
+		name string
+		body interface{}
+	}{
+		{"no operations", map[string]interface{}{"operations": []interface{}{}}},
+		{"atomic over the batch size", map[string]interface{}{"atomic": true, "operations": operations}},
+		{"invalid json", `{"operations": `},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := api.do(http.MethodPost, "/api/v1/products/bulk", tt.body)
+			expect(t, rec, http.StatusBadRequest, nil)
+			var body map[string]json.RawMessage
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body["error"] == nil {
+				t.Errorf("body = %s, want an error", rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package Handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/andrescris/products/pkg/catalogio"
+	"github.com/andrescris/products/pkg/middleware"
+	"github.com/andrescris/products/pkg/models"
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportSize es el tamaño máximo del archivo de importación.
+const maxImportSize = 20 << 20
+
+// ImportProducts importa un CSV (campo multipart "file") con una fila por
+// SKU: crea los productos nuevos y actualiza los existentes por SKU. El
+// campo opcional "mapping" es un objeto JSON columna → campo. Con
+// dry_run=true solo valida. Si alguna fila tiene errores no se escribe nada
+// y responde 422 con el informe.
+func (h *ProductHandler) ImportProducts(c *gin.Context) {
+	subdomain := c.Query("subdomain")
+	projectID := c.Query("project_id")
+	if subdomain == "" || projectID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The subdomain and project_id query parameters are required."})
+		return
+	}
+	dryRun := false
+	if value := c.Query("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run must be true or false."})
+			return
+		}
+		dryRun = parsed
+	}
+
+	allowedSubdomains, ok := getSubdomainsFromContext(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Could not verify user permissions."})
+		return
+	}
+	middleware.SetAuditSubdomain(c, subdomain)
+	if !isSubdomainAllowed(allowedSubdomains, subdomain) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to access resources in this subdomain."})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A CSV file is required in the file field.", "details": err.Error()})
+		return
+	}
+	var mapping map[string]string
+	if raw := c.PostForm("mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "mapping must be a JSON object of column to field.", "details": err.Error()})
+			return
+		}
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read the file", "details": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	ctx := stockContext(c, models.MovementImport)
+	report, err := catalogio.ImportCSV(ctx, h.repo, h.categories, h.bulk, file, catalogio.ImportOptions{
+		Subdomain: subdomain,
+		ProjectID: projectID,
+		Currency:  c.Query("currency"),
+		Mapping:   mapping,
+		DryRun:    dryRun,
+	})
+	if errors.Is(err, catalogio.ErrInvalidFile) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The file is not a valid CSV.", "details": err.Error()})
+		return
+	}
+	if err != nil {
+		response := gin.H{"error": "Failed to import products", "details": err.Error()}
+		if report != nil && report.Partial {
+			response["error"] = "Failed to import products. The import was partially applied: see data.products[].written."
+			response["data"] = report
+		}
+		c.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	if len(report.Errors) > 0 {
+		message := "The file has errors."
+		if report.Partial {
+			message = "The import failed and was partially applied: see data.products[].written."
+		}
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"success": false, "error": message, "data": report})
+		return
+	}
+	message := "Products imported successfully"
+	if dryRun {
+		message = "Dry run: no changes were saved"
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": message, "data": report})
+}
+
+// ExportProducts descarga el catálogo del subdominio en CSV (una fila por
+// SKU, con las columnas de la importación) o JSON Lines (un producto por
+// línea). La respuesta se escribe a medida que se recorre el catálogo.
+func (h *ProductHandler) ExportProducts(c *gin.Context) {
+	subdomain := c.Query("subdomain")
+	if subdomain == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The subdomain query parameter is required."})
+		return
+	}
+	format := c.DefaultQuery("format", catalogio.FormatCSV)
+	if !catalogio.ValidFormat(format) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or jsonl."})
+		return
+	}
+	status := models.ProductStatus(c.Query("status"))
+	if status != "" && status != models.StatusDraft && status != models.StatusPublished && status != models.StatusArchived {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be draft, published or archived."})
+		return
+	}
+
+	allowedSubdomains, ok := getSubdomainsFromContext(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Could not verify user permissions."})
+		return
+	}
+	if !isSubdomainAllowed(allowedSubdomains, subdomain) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to access resources in this subdomain."})
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if format == catalogio.FormatJSONL {
+		contentType = "application/x-ndjson"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-products.%s"`, subdomain, format))
+	c.Status(http.StatusOK)
+
+	// Una vez empezada la respuesta ya no se puede cambiar el código: un
+	// error a mitad deja el archivo truncado y solo queda registrarlo.
+	result, err := catalogio.Export(c.Request.Context(), h.repo, c.Writer, catalogio.ExportOptions{
+		Subdomain: subdomain,
+		Format:    format,
+		Status:    status,
+	})
+	if err != nil {
+		log.Printf("HANDLER ERROR: export of %s failed after %d products: %v", subdomain, result.Products, err)
+		c.Abort()
+	}
+}
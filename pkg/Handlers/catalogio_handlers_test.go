@@ -0,0 +1,41 @@
+package Handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestExportProducts(t *testing.T) {
+	api := newTestAPI(t)
+	createProduct(t, api, simpleProductBody("A"))
+
+	tests := []struct {
+		name        string
+		query       string
+		status      int
+		contentType string
+	}{
+		{"csv by default", "subdomain=s", http.StatusOK, "text/csv"},
+		{"jsonl", "subdomain=s&format=jsonl", http.StatusOK, "application/x-ndjson"},
+		{"missing subdomain", "", http.StatusBadRequest, ""},
+		{"unknown format", "subdomain=s&format=xml", http.StatusBadRequest, ""},
+		{"unknown status", "subdomain=s&status=gone", http.StatusBadRequest, ""},
+		{"other subdomain", "subdomain=other", http.StatusForbidden, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := api.do(http.MethodGet, "/api/v1/products/export?"+tt.query, nil)
+			expect(t, rec, tt.status, nil)
+			if tt.contentType == "" {
+				return
+			}
+			if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, tt.contentType) {
+				t.Errorf("Content-Type = %q, want %q", got, tt.contentType)
+			}
+			if !strings.Contains(rec.Body.String(), "Tee A") {
+				t.Errorf("body does not contain product Tee A: %s", rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,327 @@
+package Handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/andrescris/firestore/lib/firebase"
+	"github.com/andrescris/products/pkg/middleware"
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CategoryHandler gestiona el árbol de categorías de cada proyecto.
+type CategoryHandler struct {
+	categories repository.CategoryRepository
+	products   repository.ProductRepository
+}
+
+// NewCategoryHandler crea los handlers de categorías.
+func NewCategoryHandler(categories repository.CategoryRepository, products repository.ProductRepository) *CategoryHandler {
+	return &CategoryHandler{categories: categories, products: products}
+}
+
+// resolveCategory carga la categoría que un producto quiere referenciar y
+// verifica que sea del mismo proyecto. Devuelve un error legible para el cliente.
+func resolveCategory(ctx context.Context, repo repository.CategoryRepository, projectID, id string) (*models.Category, error) {
+	category, err := repo.GetCategory(ctx, id)
+	if errors.Is(err, repository.ErrCategoryNotFound) {
+		return nil, fmt.Errorf("category %s does not exist", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if category.ProjectID != projectID {
+		return nil, fmt.Errorf("category %s does not belong to project %s", id, projectID)
+	}
+	return category, nil
+}
+
+// checkVariationAttributes valida los atributos de las variaciones contra el
+// esquema de la categoría del producto. Si hay infracciones responde 422 con
+// todas ellas y devuelve false.
+func checkVariationAttributes(c *gin.Context, repo repository.CategoryRepository, product *models.Product, variations ...models.Variation) bool {
+	if err := validateVariationAttributes(repo, product, variations...); err != nil {
+		err.respond(c)
+		return false
+	}
+	return true
+}
+
+// validateVariationAttributes es la validación de checkVariationAttributes
+// sin responder.
+func validateVariationAttributes(repo repository.CategoryRepository, product *models.Product, variations ...models.Variation) *requestError {
+	if product.CategoryID == "" || len(variations) == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+	category, err := repo.GetCategory(ctx, product.CategoryID)
+	if err != nil {
+		return &requestError{http.StatusInternalServerError, gin.H{"error": "Failed to load category schema", "details": err.Error()}}
+	}
+	schema, err := repository.CategorySchema(ctx, repo, category)
+	if err != nil {
+		return &requestError{http.StatusInternalServerError, gin.H{"error": "Failed to load category schema", "details": err.Error()}}
+	}
+
+	violations := []models.AttributeViolation{}
+	for _, v := range variations {
+		violations = append(violations, models.ValidateAttributes(schema, v.SKU, v.Attributes)...)
+	}
+	if len(violations) > 0 {
+		return &requestError{http.StatusUnprocessableEntity, gin.H{
+			"error":      "Variation attributes do not match the category schema.",
+			"categoryId": category.ID,
+			"violations": violations,
+		}}
+	}
+	return nil
+}
+
+func (h *CategoryHandler) CreateCategory(c *gin.Context) {
+	var category models.Category
+	if err := c.ShouldBindJSON(&category); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if category.Name == "" || category.ProjectID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required fields: name and project_id are required."})
+		return
+	}
+	if category.Slug == "" {
+		category.Slug = category.Name
+	}
+	category.Slug = repository.Slugify(category.Slug)
+	if category.Slug == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "slug must contain at least one letter or digit."})
+		return
+	}
+	if err := models.ValidateDefinitions(category.Attributes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	allowedSubdomains, ok := getSubdomainsFromContext(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Could not verify user permissions."})
+		return
+	}
+	middleware.SetAuditSubdomain(c, category.Subdomain)
+	if !isSubdomainAllowed(allowedSubdomains, category.Subdomain) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to create resources in this subdomain."})
+		return
+	}
+
+	now := time.Now().UTC()
+	category.ID = "cat-" + uuid.New().String()
+	middleware.AddAuditTarget(c, "categoryId", category.ID)
+	category.CreatedAt = now
+	category.UpdatedAt = now
+
+	if err := h.categories.CreateCategory(context.Background(), &category); err != nil {
+		respondCategoryError(c, err, "Failed to create category")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "Category created successfully", "data": category})
+}
+
+func (h *CategoryHandler) ListCategories(c *gin.Context) {
+	projectID := c.Query("project_id")
+	if projectID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The project_id query parameter is required."})
+		return
+	}
+
+	allowedSubdomains, ok := getSubdomainsFromContext(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Could not verify user permissions."})
+		return
+	}
+
+	categories, err := h.categories.ListCategories(context.Background(), projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list categories", "details": err.Error()})
+		return
+	}
+
+	visible := []models.Category{}
+	for _, category := range categories {
+		if isSubdomainAllowed(allowedSubdomains, category.Subdomain) {
+			visible = append(visible, category)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "count": len(visible), "data": visible})
+}
+
+func (h *CategoryHandler) GetCategory(c *gin.Context) {
+	category, ok := h.loadAuthorizedCategory(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": category})
+}
+
+func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
+	current, ok := h.loadAuthorizedCategory(c)
+	if !ok {
+		return
+	}
+
+	var updates struct {
+		Name       *string                       `json:"name"`
+		Slug       *string                       `json:"slug"`
+		Attributes *[]models.AttributeDefinition `json:"attributes"`
+	}
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format", "details": err.Error()})
+		return
+	}
+	var slug string
+	if updates.Slug != nil {
+		if slug = repository.Slugify(*updates.Slug); slug == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "slug must contain at least one letter or digit."})
+			return
+		}
+	}
+	if updates.Attributes != nil {
+		if err := models.ValidateDefinitions(*updates.Attributes); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	h.saveCategory(c, current, "Category updated successfully", func(category *models.Category) error {
+		if updates.Name != nil && *updates.Name != "" {
+			category.Name = *updates.Name
+		}
+		if slug != "" {
+			category.Slug = slug
+		}
+		if updates.Attributes != nil {
+			category.Attributes = *updates.Attributes
+		}
+		return nil
+	})
+}
+
+// MoveCategory cambia el padre de una categoría (parentId vacío la convierte
+// en raíz). La rama completa y sus productos se actualizan con la nueva ruta.
+func (h *CategoryHandler) MoveCategory(c *gin.Context) {
+	current, ok := h.loadAuthorizedCategory(c)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		ParentID *string `json:"parentId"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format", "details": err.Error()})
+		return
+	}
+	if body.ParentID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required field: parentId (empty string moves the category to the root)."})
+		return
+	}
+
+	h.saveCategory(c, current, "Category moved successfully", func(category *models.Category) error {
+		category.ParentID = *body.ParentID
+		return nil
+	})
+}
+
+// saveCategory aplica fn, recalcula la rama y, si cambió la ruta de la
+// categoría, actualiza la guardada en los productos de cada categoría de la
+// rama. Los cambios de atributos no tocan los productos.
+func (h *CategoryHandler) saveCategory(c *gin.Context, current *models.Category, message string, fn func(*models.Category) error) {
+	ctx := context.Background()
+	subtree, err := h.categories.UpdateCategory(ctx, current.ID, fn)
+	if err != nil {
+		respondCategoryError(c, err, "Failed to update category")
+		return
+	}
+
+	reindexed := 0
+	if repository.CategoryPathChanged(current, &subtree[0]) {
+		reindexed, err = repository.ReindexCategoryProducts(stockContext(c, models.MovementAdjustment), h.products, subtree)
+	}
+	if err != nil {
+		log.Printf("HANDLER ERROR: updating products of category %s: %v", current.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Category saved but its products could not be updated", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": message, "data": subtree[0], "descendants": subtree[1:], "productsUpdated": reindexed})
+}
+
+func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
+	category, ok := h.loadAuthorizedCategory(c)
+	if !ok {
+		return
+	}
+
+	ctx := context.Background()
+	products, err := h.products.Query(ctx, firebase.QueryOptions{
+		Filters: []firebase.QueryFilter{{Field: "categoryId", Operator: "==", Value: category.ID}},
+		Limit:   1,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check category products", "details": err.Error()})
+		return
+	}
+	if len(products) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "The category still has products. Move them to another category first."})
+		return
+	}
+
+	if err := h.categories.DeleteCategory(ctx, category.ID); err != nil {
+		respondCategoryError(c, err, "Failed to delete category")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Category deleted successfully"})
+}
+
+// loadAuthorizedCategory carga la categoría de la ruta y verifica que su
+// subdominio esté permitido para la API Key.
+func (h *CategoryHandler) loadAuthorizedCategory(c *gin.Context) (*models.Category, bool) {
+	category, err := h.categories.GetCategory(context.Background(), c.Param("categoryId"))
+	if err != nil {
+		respondCategoryError(c, err, "Failed to load category")
+		return nil, false
+	}
+
+	allowedSubdomains, ok := getSubdomainsFromContext(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Could not verify user permissions."})
+		return nil, false
+	}
+	middleware.SetAuditSubdomain(c, category.Subdomain)
+	if !isSubdomainAllowed(allowedSubdomains, category.Subdomain) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to access this resource."})
+		return nil, false
+	}
+	return category, true
+}
+
+func respondCategoryError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, repository.ErrCategoryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+	case errors.Is(err, repository.ErrCategoryCycle), errors.Is(err, repository.ErrCategoryProject):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrCategorySlugTaken), errors.Is(err, repository.ErrCategoryHasChildren):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message, "details": err.Error()})
+	}
+}
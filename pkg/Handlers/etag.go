@@ -0,0 +1,41 @@
+package Handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// productETag construye el ETag a partir de la versión del producto.
+func productETag(product *models.Product) string {
+	return fmt.Sprintf(`"%d"`, product.Version)
+}
+
+// setProductETag expone la versión actual para que el cliente la use en If-Match.
+func setProductETag(c *gin.Context, product *models.Product) {
+	c.Header("ETag", productETag(product))
+}
+
+// expectedVersionFromRequest lee If-Match. Sin cabecera (o con "*") no se
+// comprueba la versión. Si el valor no es un ETag válido responde 400 y
+// devuelve ok=false.
+func expectedVersionFromRequest(c *gin.Context) (int64, bool) {
+	ifMatch := strings.TrimSpace(c.GetHeader("If-Match"))
+	if ifMatch == "" || ifMatch == "*" {
+		return repository.AnyVersion, true
+	}
+
+	tag := strings.TrimPrefix(ifMatch, "W/")
+	tag = strings.Trim(tag, `"`)
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || version < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid If-Match header. Use the ETag returned by the API."})
+		return 0, false
+	}
+	return version, true
+}
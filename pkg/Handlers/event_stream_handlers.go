@@ -0,0 +1,305 @@
+package Handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/repository"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	eventPollInterval = time.Second
+	eventHeartbeat    = 15 * time.Second
+	// eventSettleDelay retrasa los eventos más recientes: una escritura puede
+	// confirmarse después de otra con un ID posterior y el cursor se la
+	// saltaría.
+	eventSettleDelay = 2 * time.Second
+	eventBatchSize   = 100
+)
+
+// EventStreamHandler transmite los cambios de catálogo de un subdominio por
+// Server-Sent Events, leyendo del registro de eventos.
+type EventStreamHandler struct {
+	events       repository.EventRepository
+	pollInterval time.Duration
+	heartbeat    time.Duration
+	settleDelay  time.Duration
+}
+
+// NewEventStreamHandler crea el handler del stream de eventos.
+func NewEventStreamHandler(events repository.EventRepository) *EventStreamHandler {
+	return &EventStreamHandler{
+		events:       events,
+		pollInterval: eventPollInterval,
+		heartbeat:    eventHeartbeat,
+		settleDelay:  eventSettleDelay,
+	}
+}
+
+// eventFilter son los filtros opcionales del stream.
+type eventFilter struct {
+	productIDs map[string]bool
+	categoryID string
+}
+
+func (f eventFilter) matches(event models.ProductEvent) bool {
+	if len(f.productIDs) > 0 && !f.productIDs[event.ProductID] {
+		return false
+	}
+	if f.categoryID != "" {
+		found := false
+		for _, id := range event.CategoryIDs {
+			if id == f.categoryID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func changesStatus(event models.ProductEvent) bool {
+	changes, _ := event.Data["changes"].([]interface{})
+	for _, item := range changes {
+		if change, ok := item.(map[string]interface{}); ok && change["field"] == "status" {
+			return true
+		}
+	}
+	return false
+}
+
+// eventProductRemoved es el aviso que recibe la tienda cuando un producto deja
+// de estar publicado (pasa a borrador, se archiva o va a la papelera).
+const eventProductRemoved models.EventType = "product.removed"
+
+// storefrontEvent es la parte de un evento que ve la tienda: sin el actor ni
+// los datos de la papelera.
+type storefrontEvent struct {
+	ID             string                 `json:"id"`
+	Type           models.EventType       `json:"type"`
+	ProductID      string                 `json:"productId"`
+	VariationID    string                 `json:"variationId,omitempty"`
+	ProductVersion int64                  `json:"productVersion"`
+	ProductStatus  models.ProductStatus   `json:"productStatus,omitempty"`
+	CategoryIDs    []string               `json:"categoryIds,omitempty"`
+	Data           map[string]interface{} `json:"data"`
+	CreatedAt      time.Time              `json:"createdAt"`
+}
+
+// removalNotice es el único dato que se envía de un producto no publicado.
+type removalNotice struct {
+	ProductID string               `json:"productId"`
+	Status    models.ProductStatus `json:"status"`
+}
+
+// hiddenFields son los campos que la tienda no recibe: quién hizo el cambio y
+// la papelera.
+var hiddenFields = map[string]bool{
+	"actor":                true,
+	"deletedAt":            true,
+	"deletedBy":            true,
+	"variation_deleted_at": true,
+}
+
+// storefrontPayload devuelve el tipo y el cuerpo con que se envía el evento,
+// o false si no se envía. Los productos que no están publicados no existen
+// para la tienda (GET /:id devuelve 404): de ellos solo se avisa, sin el
+// documento, cuando dejan de estar publicados.
+func storefrontPayload(event models.ProductEvent) (models.EventType, interface{}, bool) {
+	if event.ProductStatus != models.StatusPublished {
+		leaves := event.Type == models.EventProductDeactivated ||
+			(event.Type == models.EventProductUpdated && changesStatus(event))
+		if !leaves {
+			return "", nil, false
+		}
+		return eventProductRemoved, removalNotice{ProductID: event.ProductID, Status: event.ProductStatus}, true
+	}
+
+	data := map[string]interface{}{}
+	for key, value := range event.Data {
+		switch key {
+		case "product", "variation":
+			value = withoutHidden(value)
+			if product, ok := value.(map[string]interface{}); ok && key == "product" {
+				if variations, ok := product["variations"].([]interface{}); ok {
+					visible := make([]interface{}, len(variations))
+					for i, variation := range variations {
+						visible[i] = withoutHidden(variation)
+					}
+					product["variations"] = visible
+				}
+			}
+		case "movements":
+			if movements, ok := value.([]interface{}); ok {
+				visible := make([]interface{}, len(movements))
+				for i, movement := range movements {
+					visible[i] = withoutHidden(movement)
+				}
+				value = visible
+			}
+		case "changes":
+			if changes, ok := value.([]interface{}); ok {
+				visible := []interface{}{}
+				for _, item := range changes {
+					if change, ok := item.(map[string]interface{}); ok && hiddenPath(fmt.Sprint(change["field"])) {
+						continue
+					}
+					visible = append(visible, item)
+				}
+				value = visible
+			}
+		}
+		data[key] = value
+	}
+	return event.Type, storefrontEvent{
+		ID:             event.ID,
+		Type:           event.Type,
+		ProductID:      event.ProductID,
+		VariationID:    event.VariationID,
+		ProductVersion: event.ProductVersion,
+		ProductStatus:  event.ProductStatus,
+		CategoryIDs:    event.CategoryIDs,
+		Data:           data,
+		CreatedAt:      event.CreatedAt,
+	}, true
+}
+
+// withoutHidden copia un documento sin los campos de hiddenFields.
+func withoutHidden(value interface{}) interface{} {
+	doc, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+	visible := make(map[string]interface{}, len(doc))
+	for key, field := range doc {
+		if !hiddenFields[key] {
+			visible[key] = field
+		}
+	}
+	return visible
+}
+
+// hiddenPath indica si la ruta de un FieldChange (p. ej.
+// "variations[v1].deletedAt") pasa por un campo oculto.
+func hiddenPath(field string) bool {
+	for _, segment := range strings.Split(field, ".") {
+		if i := strings.IndexByte(segment, '['); i >= 0 {
+			segment = segment[:i]
+		}
+		if hiddenFields[segment] {
+			return true
+		}
+	}
+	return false
+}
+
+// StreamEvents abre un stream SSE con los eventos del subdominio de la
+// sesión. Cada mensaje lleva el ID del evento, así que el cliente puede
+// retomar con la cabecera Last-Event-ID (o ?lastEventId=) mientras el evento
+// siga en el registro. Sin cursor empieza desde ahora. Filtros opcionales:
+// productId (separados por comas) y category (incluye toda la rama).
+func (h *EventStreamHandler) StreamEvents(c *gin.Context) {
+	subdomain, subdomainExists := c.Get("subdomain")
+	if !subdomainExists {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied. Subdomain context is required."})
+		return
+	}
+
+	filter := eventFilter{productIDs: map[string]bool{}, categoryID: c.Query("category")}
+	for _, value := range c.QueryArray("productId") {
+		for _, id := range strings.Split(value, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				filter.productIDs[id] = true
+			}
+		}
+	}
+
+	cursor := c.GetHeader("Last-Event-ID")
+	if cursor == "" {
+		cursor = c.Query("lastEventId")
+	}
+	if cursor == "" {
+		cursor = repository.EventCursor(time.Now().UTC().Add(-h.settleDelay))
+	} else if !strings.HasPrefix(cursor, "evt-") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Last-Event-ID is not a valid event ID."})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	ctx := c.Request.Context()
+	poll := time.NewTicker(h.pollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		var err error
+		cursor, err = h.sendEvents(ctx, c.Writer, subdomain.(string), cursor, filter)
+		if err != nil {
+			// El cliente reconecta con el último ID que recibió.
+			log.Printf("HANDLER ERROR: streaming events of %s: %v", subdomain, err)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": keep-alive\n\n")
+			c.Writer.Flush()
+		case <-poll.C:
+		}
+	}
+}
+
+// sendEvents escribe los eventos posteriores a cursor que ya se asentaron y
+// devuelve el nuevo cursor.
+func (h *EventStreamHandler) sendEvents(ctx context.Context, w gin.ResponseWriter, subdomain, cursor string, filter eventFilter) (string, error) {
+	settled := time.Now().UTC().Add(-h.settleDelay)
+	defer w.Flush()
+	for {
+		events, err := h.events.ListEvents(ctx, subdomain, cursor, eventBatchSize)
+		if err != nil {
+			return cursor, err
+		}
+		for _, event := range events {
+			if event.CreatedAt.After(settled) {
+				return cursor, nil
+			}
+			cursor = event.ID
+			if !filter.matches(event) {
+				continue
+			}
+			eventType, payload, ok := storefrontPayload(event)
+			if !ok {
+				continue
+			}
+			data, err := json.Marshal(payload)
+			if err != nil {
+				return cursor, err
+			}
+			if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, eventType, data); err != nil {
+				return cursor, err
+			}
+		}
+		if len(events) < eventBatchSize {
+			return cursor, nil
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package Handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andrescris/products/pkg/models"
+	"github.com/gin-gonic/gin"
+)
+
+// streamMessage es un mensaje SSE ya separado en sus líneas.
+type streamMessage struct {
+	event string
+	data  string
+}
+
+// readStream envía los eventos asentados del subdominio "s" y los devuelve.
+func readStream(t *testing.T, api *testAPI) []streamMessage {
+	t.Helper()
+	handler := NewEventStreamHandler(api.store)
+	handler.settleDelay = 0
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	if _, err := handler.sendEvents(context.Background(), c.Writer, "s", "", eventFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	messages := []streamMessage{}
+	for _, block := range strings.Split(strings.TrimSpace(rec.Body.String()), "\n\n") {
+		var message streamMessage
+		for _, line := range strings.Split(block, "\n") {
+			if value, ok := strings.CutPrefix(line, "event: "); ok {
+				message.event = value
+			} else if value, ok := strings.CutPrefix(line, "data: "); ok {
+				message.data = value
+			}
+		}
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+func TestStreamEventsHidesActorsAndUnpublishedProducts(t *testing.T) {
+	api := newTestAPI(t)
+	key := []string{"X-API-KEY", "storefront-secret"}
+	var product models.Product
+	expect(t, api.do(http.MethodPost, "/api/v1/products/", simpleProductBody("A"), key...), http.StatusCreated, &product)
+	path := "/api/v1/products/" + product.ID
+	expect(t, api.do(http.MethodPost, path+"/stock-adjustments", `{"delta": 2, "reason": "adjustment"}`, key...), http.StatusOK, nil)
+	expect(t, api.do(http.MethodPost, path+"/archive", nil, key...), http.StatusOK, nil)
+	expect(t, api.do(http.MethodPatch, path, `{"description": "archived copy"}`, key...), http.StatusOK, nil)
+	expect(t, api.do(http.MethodDelete, path, nil, key...), http.StatusOK, nil)
+
+	messages := readStream(t, api)
+	events := make([]string, len(messages))
+	for i, message := range messages {
+		events[i] = message.event
+		for _, hidden := range []string{"actor", "apiKey", "sha256:", "deletedAt", "deletedBy"} {
+			if strings.Contains(message.data, hidden) {
+				t.Errorf("%s message exposes %q: %s", message.event, hidden, message.data)
+			}
+		}
+	}
+	// El cambio de descripción del producto archivado no se envía y la baja
+	// solo llega como aviso.
+	want := []string{"product.created", "stock.changed", "stock.changed", "product.removed", "product.removed"}
+	if strings.Join(events, ",") != strings.Join(want, ",") {
+		t.Fatalf("events = %v, want %v", events, want)
+	}
+	if !strings.Contains(messages[0].data, `"Tee A"`) {
+		t.Errorf("published snapshot missing: %s", messages[0].data)
+	}
+	for _, message := range messages[3:] {
+		var notice map[string]interface{}
+		if err := json.Unmarshal([]byte(message.data), &notice); err != nil {
+			t.Fatal(err)
+		}
+		if len(notice) != 2 || notice["productId"] != product.ID || notice["status"] != string(models.StatusArchived) {
+			t.Errorf("removal notice = %s", message.data)
+		}
+	}
+}
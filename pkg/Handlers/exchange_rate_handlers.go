@@ -0,0 +1,181 @@
+package Handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/andrescris/firestore/lib/firebase"
+	"github.com/andrescris/products/pkg/middleware"
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/pricing"
+	"github.com/andrescris/products/pkg/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// ExchangeRateHandler gestiona la tabla de tipos de cambio de cada proyecto.
+type ExchangeRateHandler struct {
+	rates    repository.ExchangeRateRepository
+	products repository.ProductRepository
+}
+
+// NewExchangeRateHandler crea los handlers de tipos de cambio. products se
+// usa para comprobar a quién pertenece el proyecto.
+func NewExchangeRateHandler(rates repository.ExchangeRateRepository, products repository.ProductRepository) *ExchangeRateHandler {
+	return &ExchangeRateHandler{rates: rates, products: products}
+}
+
+func (h *ExchangeRateHandler) GetExchangeRates(c *gin.Context) {
+	table, err := h.rates.GetExchangeRates(context.Background(), c.Param("projectId"))
+	if err != nil {
+		respondExchangeRateError(c, err, "Failed to load exchange rates")
+		return
+	}
+
+	allowedSubdomains, ok := getSubdomainsFromContext(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Could not verify user permissions."})
+		return
+	}
+	middleware.SetAuditSubdomain(c, table.Subdomain)
+	if !isSubdomainAllowed(allowedSubdomains, table.Subdomain) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to access this resource."})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": table})
+}
+
+// PutExchangeRates reemplaza la tabla completa del proyecto.
+func (h *ExchangeRateHandler) PutExchangeRates(c *gin.Context) {
+	projectID := c.Param("projectId")
+	ctx := context.Background()
+
+	var table models.ExchangeRateTable
+	if err := c.ShouldBindJSON(&table); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	table.ProjectID = projectID
+	if err := pricing.ValidateExchangeRates(&table); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	allowedSubdomains, ok := getSubdomainsFromContext(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Could not verify user permissions."})
+		return
+	}
+	middleware.SetAuditSubdomain(c, table.Subdomain)
+	if !isSubdomainAllowed(allowedSubdomains, table.Subdomain) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to modify resources in this subdomain."})
+		return
+	}
+
+	// Una tabla existente solo la puede reemplazar quien tenga acceso a su subdominio.
+	existing, err := h.rates.GetExchangeRates(ctx, projectID)
+	if err != nil && !errors.Is(err, repository.ErrExchangeRatesNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load exchange rates", "details": err.Error()})
+		return
+	}
+	if existing != nil && !isSubdomainAllowed(allowedSubdomains, existing.Subdomain) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to modify resources in this subdomain."})
+		return
+	}
+	if !authorizeProject(c, h.products, projectID, table.Subdomain, allowedSubdomains) {
+		return
+	}
+
+	table.UpdatedAt = time.Now().UTC()
+	if err := h.rates.SaveExchangeRates(ctx, &table); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save exchange rates", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Exchange rates saved successfully", "data": table})
+}
+
+// maxNotInValues es el máximo de valores que Firestore admite en not-in.
+const maxNotInValues = 10
+
+// authorizeProject comprueba que el proyecto no tenga productos en
+// subdominios a los que el usuario no tiene acceso: las tablas de un proyecto
+// (tipos de cambio, impuestos) no se guardan por subdominio y, sin esta
+// comprobación, otro cliente podría crear la de un proyecto ajeno. Con más
+// subdominios de los que admite not-in, exige que todos los productos sean
+// de subdomain. Si no, ya respondió y devuelve false.
+func authorizeProject(c *gin.Context, products repository.ProductRepository, projectID, subdomain string, allowedSubdomains []interface{}) bool {
+	other := firebase.QueryFilter{Field: "subdomain", Operator: "not-in", Value: allowedSubdomains}
+	if len(allowedSubdomains) > maxNotInValues {
+		other = firebase.QueryFilter{Field: "subdomain", Operator: "!=", Value: subdomain}
+	}
+	foreign, err := products.Query(context.Background(), firebase.QueryOptions{
+		Filters: []firebase.QueryFilter{{Field: "project_id", Operator: "==", Value: projectID}, other},
+		Limit:   1,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check project products", "details": err.Error()})
+		return false
+	}
+	if len(foreign) > 0 {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "The project has products in a subdomain you do not have access to."})
+		return false
+	}
+	return true
+}
+
+func respondExchangeRateError(c *gin.Context, err error, message string) {
+	if errors.Is(err, repository.ErrExchangeRatesNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Exchange rate table not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message, "details": err.Error()})
+}
+
+// displayCurrency lee el parámetro currency de la petición. Devuelve "" si no
+// se pidió conversión y false si el código no es válido (ya respondió 400).
+func displayCurrency(c *gin.Context) (string, bool) {
+	currency := strings.ToUpper(c.Query("currency"))
+	if currency == "" {
+		return "", true
+	}
+	if !models.ValidCurrency(currency) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "currency must be a valid ISO 4217 code."})
+		return "", false
+	}
+	return currency, true
+}
+
+// convertProducts convierte los productos a la moneda pedida con la tabla de
+// su proyecto. Una tabla de otro subdominio no se usa. Si falta un tipo de
+// cambio responde 422 y devuelve false.
+func convertProducts(c *gin.Context, rates repository.ExchangeRateRepository, currency string, products ...*models.Product) bool {
+	tables := map[string]*models.ExchangeRateTable{}
+	for _, product := range products {
+		if product.Currency == currency {
+			continue
+		}
+		table, cached := tables[product.ProjectID]
+		if !cached {
+			loaded, err := rates.GetExchangeRates(context.Background(), product.ProjectID)
+			if err != nil && !errors.Is(err, repository.ErrExchangeRatesNotFound) {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load exchange rates", "details": err.Error()})
+				return false
+			}
+			table = loaded
+			tables[product.ProjectID] = table
+		}
+		if table != nil && table.Subdomain != product.Subdomain {
+			table = nil
+		}
+
+		if err := pricing.ConvertProduct(product, table, currency); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "productId": product.ID})
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,66 @@
+package Handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+func TestPutExchangeRatesChecksProjectOwner(t *testing.T) {
+	tests := []struct {
+		name      string
+		subdomain string
+		status    int
+	}{
+		{"project owner", "s", http.StatusOK},
+		{"another subdomain", "other", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newTestAPI(t)
+			createProduct(t, api, simpleProductBody("A"))
+			body := map[string]interface{}{"subdomain": tt.subdomain, "baseCurrency": "USD", "rates": map[string]float64{"EUR": 0.5}}
+			expect(t, api.do(http.MethodPut, "/api/v1/exchange-rates/proj", body, "X-Test-Subdomain", tt.subdomain), tt.status, nil)
+			_, err := api.store.GetExchangeRates(context.Background(), "proj")
+			if saved := err == nil; saved != (tt.status == http.StatusOK) {
+				t.Errorf("table saved = %v, want %v", saved, tt.status == http.StatusOK)
+			}
+		})
+	}
+}
+
+func TestConvertIgnoresOtherSubdomainRates(t *testing.T) {
+	tests := []struct {
+		name      string
+		subdomain string
+		status    int
+	}{
+		{"own table", "s", http.StatusOK},
+		{"table of another subdomain", "other", http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newTestAPI(t)
+			product := createProduct(t, api, simpleProductBody("A"))
+			table := &models.ExchangeRateTable{ProjectID: "proj", Subdomain: tt.subdomain, BaseCurrency: "USD", Rates: map[string]float64{"EUR": 0.5}}
+			if err := api.store.SaveExchangeRates(context.Background(), table); err != nil {
+				t.Fatal(err)
+			}
+			var converted struct {
+				Currency string  `json:"currency"`
+				Price    float64 `json:"price"`
+			}
+			rec := api.do(http.MethodGet, "/api/v1/products/"+product.ID+"?currency=EUR", nil)
+			if tt.status != http.StatusOK {
+				expect(t, rec, tt.status, nil)
+				return
+			}
+			expect(t, rec, tt.status, &converted)
+			if converted.Currency != "EUR" || converted.Price != 10 {
+				t.Errorf("converted = %+v, want 10 EUR", converted)
+			}
+		})
+	}
+}
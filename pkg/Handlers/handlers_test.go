@@ -0,0 +1,125 @@
+package Handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andrescris/products/pkg/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// testAPI monta las rutas de productos sobre un MemoryRepository, sin los
+// middlewares de autenticación: la cabecera X-Test-Subdomain hace las veces
+// de la sesión o la API Key (por defecto, el subdominio "s").
+type testAPI struct {
+	t      *testing.T
+	router *gin.Engine
+	store  *repository.MemoryRepository
+}
+
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	store := repository.NewMemoryRepository()
+
+	productHandler := NewProductHandler(store, store, store, store, store, store, store)
+	reservationHandler := NewReservationHandler(store)
+	stockHandler := NewStockHandler(store, store, store)
+	revisionHandler := NewRevisionHandler(store, store, store)
+	exchangeRateHandler := NewExchangeRateHandler(store, store)
+	taxHandler := NewTaxHandler(store, store)
+	auditHandler := NewAuditHandler(store)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		subdomain := c.GetHeader("X-Test-Subdomain")
+		if subdomain == "" {
+			subdomain = "s"
+		}
+		c.Set("subdomain", subdomain)
+		c.Set("allowed_subdomains", []interface{}{subdomain})
+		c.Next()
+	})
+	products := r.Group("/api/v1/products")
+	products.GET("/:id", productHandler.GetProductByID)
+	products.POST("/search", productHandler.ListProducts)
+	products.GET("/:id/stock-movements", stockHandler.ListStockMovements)
+	products.GET("/:id/revisions", revisionHandler.ListRevisions)
+	products.GET("/:id/revisions/:rev/diff", revisionHandler.DiffRevisions)
+	products.GET("/export", productHandler.ExportProducts)
+	products.POST("/", productHandler.CreateProduct)
+	products.POST("/bulk", productHandler.BulkProducts)
+	products.POST("/import", productHandler.ImportProducts)
+	products.PATCH("/:id", productHandler.UpdateProduct)
+	products.DELETE("/:id", productHandler.DeleteProduct)
+	products.POST("/:id/revisions/:rev/rollback", revisionHandler.RollbackRevision)
+	products.POST("/:id/publish", productHandler.PublishProduct)
+	products.POST("/:id/archive", productHandler.ArchiveProduct)
+	products.POST("/:id/variations", productHandler.CreateVariation)
+	products.PATCH("/:id/variations/:variationId", productHandler.UpdateVariation)
+	products.DELETE("/:id/variations/:variationId", productHandler.DeleteVariation)
+	products.POST("/:id/stock-adjustments", stockHandler.AdjustStock)
+	products.POST("/reservations", reservationHandler.CreateReservation)
+	products.POST("/reservations/:reservationId/commit", reservationHandler.CommitReservation)
+	products.POST("/reservations/:reservationId/release", reservationHandler.ReleaseReservation)
+	exchangeRates := r.Group("/api/v1/exchange-rates")
+	exchangeRates.GET("/:projectId", exchangeRateHandler.GetExchangeRates)
+	exchangeRates.PUT("/:projectId", exchangeRateHandler.PutExchangeRates)
+	taxRates := r.Group("/api/v1/tax-rates")
+	taxRates.GET("/:projectId", taxHandler.GetTaxTable)
+	taxRates.PUT("/:projectId", taxHandler.PutTaxTable)
+	r.GET("/api/v1/audit-log", auditHandler.ListAuditEntries)
+
+	return &testAPI{t: t, router: r, store: store}
+}
+
+// do envía la petición con body (string tal cual, o cualquier otro valor en
+// JSON) y pares de cabeceras nombre, valor.
+func (a *testAPI) do(method, path string, body interface{}, headers ...string) *httptest.ResponseRecorder {
+	a.t.Helper()
+	var reader *bytes.Reader
+	switch b := body.(type) {
+	case nil:
+		reader = bytes.NewReader(nil)
+	case string:
+		reader = bytes.NewReader([]byte(b))
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			a.t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	a.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// expect comprueba el código de la respuesta y decodifica su campo data en v
+// (si v no es nil).
+func expect(t *testing.T, rec *httptest.ResponseRecorder, status int, v interface{}) {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("status = %d, want %d; body: %s", rec.Code, status, strings.TrimSpace(rec.Body.String()))
+	}
+	if v == nil {
+		return
+	}
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if err := json.Unmarshal(envelope.Data, v); err != nil {
+		t.Fatalf("decoding data: %v; body: %s", err, rec.Body.String())
+	}
+}
@@ -0,0 +1,244 @@
+package Handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/andrescris/products/pkg/middleware"
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// LocationHandler gestiona las ubicaciones de inventario y las transferencias
+// de stock entre ellas.
+type LocationHandler struct {
+	locations repository.LocationRepository
+	products  repository.ProductRepository
+}
+
+// NewLocationHandler crea los handlers de ubicaciones.
+func NewLocationHandler(locations repository.LocationRepository, products repository.ProductRepository) *LocationHandler {
+	return &LocationHandler{locations: locations, products: products}
+}
+
+type stockTransferRequest struct {
+	FromLocationID string                     `json:"fromLocationId"`
+	ToLocationID   string                     `json:"toLocationId"`
+	Lines          []models.StockTransferLine `json:"lines"`
+	Note           string                     `json:"note"`
+}
+
+// validateLocations verifica que cada ubicación exista, esté activa y
+// pertenezca al proyecto. Devuelve un error legible para el cliente.
+func validateLocations(ctx context.Context, repo repository.LocationRepository, projectID string, ids []string) error {
+	for _, id := range ids {
+		location, err := repo.GetLocation(ctx, id)
+		if errors.Is(err, repository.ErrLocationNotFound) {
+			return fmt.Errorf("location %s does not exist", id)
+		}
+		if err != nil {
+			return err
+		}
+		if location.ProjectID != projectID || !location.Active {
+			return fmt.Errorf("location %s is not an active location of project %s", id, projectID)
+		}
+	}
+	return nil
+}
+
+func (h *LocationHandler) CreateLocation(c *gin.Context) {
+	var location models.Location
+	if err := c.ShouldBindJSON(&location); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if location.Name == "" || location.ProjectID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required fields: name and project_id are required."})
+		return
+	}
+
+	allowedSubdomains, ok := getSubdomainsFromContext(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Could not verify user permissions."})
+		return
+	}
+	middleware.SetAuditSubdomain(c, location.Subdomain)
+	if !isSubdomainAllowed(allowedSubdomains, location.Subdomain) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to create resources in this subdomain."})
+		return
+	}
+
+	now := time.Now().UTC()
+	location.ID = "loc-" + uuid.New().String()
+	middleware.AddAuditTarget(c, "locationId", location.ID)
+	location.Active = true
+	location.CreatedAt = now
+	location.UpdatedAt = now
+
+	if err := h.locations.CreateLocation(context.Background(), &location); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create location", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "Location created successfully", "data": location})
+}
+
+func (h *LocationHandler) ListLocations(c *gin.Context) {
+	projectID := c.Query("project_id")
+	if projectID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The project_id query parameter is required."})
+		return
+	}
+
+	allowedSubdomains, ok := getSubdomainsFromContext(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Could not verify user permissions."})
+		return
+	}
+
+	locations, err := h.locations.ListLocations(context.Background(), projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list locations", "details": err.Error()})
+		return
+	}
+
+	// Solo se devuelven las ubicaciones de subdominios permitidos para la API Key.
+	visible := []models.Location{}
+	for _, location := range locations {
+		if isSubdomainAllowed(allowedSubdomains, location.Subdomain) {
+			visible = append(visible, location)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "count": len(visible), "data": visible})
+}
+
+func (h *LocationHandler) UpdateLocation(c *gin.Context) {
+	locationID := c.Param("locationId")
+	ctx := context.Background()
+
+	location, err := h.locations.GetLocation(ctx, locationID)
+	if err != nil {
+		respondLocationError(c, err, "Failed to load location")
+		return
+	}
+
+	allowedSubdomains, ok := getSubdomainsFromContext(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Could not verify user permissions."})
+		return
+	}
+	middleware.SetAuditSubdomain(c, location.Subdomain)
+	if !isSubdomainAllowed(allowedSubdomains, location.Subdomain) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to modify resources in this subdomain."})
+		return
+	}
+
+	var updates struct {
+		Name   *string `json:"name"`
+		Code   *string `json:"code"`
+		Active *bool   `json:"active"`
+	}
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format", "details": err.Error()})
+		return
+	}
+
+	updated, err := h.locations.UpdateLocation(ctx, locationID, func(l *models.Location) error {
+		if updates.Name != nil && *updates.Name != "" {
+			l.Name = *updates.Name
+		}
+		if updates.Code != nil {
+			l.Code = *updates.Code
+		}
+		if updates.Active != nil {
+			l.Active = *updates.Active
+		}
+		return nil
+	})
+	if err != nil {
+		respondLocationError(c, err, "Failed to update location")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Location updated successfully", "data": updated})
+}
+
+func (h *LocationHandler) TransferStock(c *gin.Context) {
+	var req stockTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if req.FromLocationID == "" || req.ToLocationID == "" || len(req.Lines) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "fromLocationId, toLocationId and at least one line are required."})
+		return
+	}
+	for i, line := range req.Lines {
+		if line.ProductID == "" || line.Quantity <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Each line requires productId and a positive quantity.", "line": i})
+			return
+		}
+	}
+
+	ctx := context.Background()
+	from, err := h.locations.GetLocation(ctx, req.FromLocationID)
+	if err != nil {
+		respondLocationError(c, err, "Failed to load location")
+		return
+	}
+
+	// Ambas ubicaciones deben ser del mismo proyecto y el subdominio de la
+	// transferencia es el de la ubicación de origen.
+	allowedSubdomains, ok := getSubdomainsFromContext(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Could not verify user permissions."})
+		return
+	}
+	middleware.SetAuditSubdomain(c, from.Subdomain)
+	if !isSubdomainAllowed(allowedSubdomains, from.Subdomain) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to move stock in this subdomain."})
+		return
+	}
+	if err := validateLocations(ctx, h.locations, from.ProjectID, []string{req.FromLocationID, req.ToLocationID}); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	transfer := models.StockTransfer{
+		ID:             "trf-" + uuid.New().String(),
+		Subdomain:      from.Subdomain,
+		FromLocationID: req.FromLocationID,
+		ToLocationID:   req.ToLocationID,
+		Lines:          req.Lines,
+		Note:           req.Note,
+		Actor:          middleware.ActorFromContext(c),
+		CreatedAt:      time.Now().UTC(),
+	}
+	middleware.AddAuditTarget(c, "transferId", transfer.ID)
+	if err := h.locations.TransferStock(stockContext(c, models.MovementAdjustment), &transfer); err != nil {
+		var shortage *repository.InsufficientStockError
+		if errors.As(err, &shortage) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Insufficient stock at the source location.", "shortages": shortage.Shortages})
+			return
+		}
+		respondRepositoryError(c, err, "Failed to transfer stock")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "Stock transferred successfully", "data": transfer})
+}
+
+// respondLocationError traduce los errores de ubicaciones a respuestas HTTP.
+func respondLocationError(c *gin.Context, err error, message string) {
+	if errors.Is(err, repository.ErrLocationNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Location not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message, "details": err.Error()})
+}
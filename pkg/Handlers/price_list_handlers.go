@@ -0,0 +1,261 @@
+package Handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/andrescris/products/pkg/middleware"
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/pricing"
+	"github.com/andrescris/products/pkg/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Claims de la sesión que eligen la lista de precios. price_list_id tiene
+// prioridad sobre customer_group.
+const (
+	priceListClaim     = "price_list_id"
+	customerGroupClaim = "customer_group"
+)
+
+// PriceListHandler gestiona las listas de precios por grupo de clientes.
+type PriceListHandler struct {
+	priceLists repository.PriceListRepository
+}
+
+// NewPriceListHandler crea los handlers de listas de precios.
+func NewPriceListHandler(priceLists repository.PriceListRepository) *PriceListHandler {
+	return &PriceListHandler{priceLists: priceLists}
+}
+
+// priceListForRequest devuelve la lista de precios que corresponde a la sesión
+// en el subdominio, o nil si la sesión no tiene una (precio normal). Los
+// errores se registran y no impiden responder con el precio normal.
+func priceListForRequest(c *gin.Context, repo repository.PriceListRepository, subdomain string) *models.PriceList {
+	claims, ok := c.Get("claims")
+	if !ok || repo == nil {
+		return nil
+	}
+	values, ok := claims.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	ctx := context.Background()
+	var list *models.PriceList
+	var err error
+	if id, _ := values[priceListClaim].(string); id != "" {
+		list, err = repo.GetPriceList(ctx, id)
+	} else if group, _ := values[customerGroupClaim].(string); group != "" {
+		list, err = repo.FindPriceList(ctx, subdomain, group)
+	} else {
+		return nil
+	}
+
+	if errors.Is(err, repository.ErrPriceListNotFound) {
+		return nil
+	}
+	if err != nil {
+		log.Printf("HANDLER ERROR: resolving price list for subdomain %s: %v", subdomain, err)
+		return nil
+	}
+	if !list.Active || list.Subdomain != subdomain {
+		return nil
+	}
+	return list
+}
+
+// ensureGroupAvailable impide tener dos listas activas para el mismo grupo de
+// clientes en un subdominio.
+func (h *PriceListHandler) ensureGroupAvailable(c *gin.Context, list *models.PriceList) bool {
+	if !list.Active {
+		return true
+	}
+	existing, err := h.priceLists.FindPriceList(context.Background(), list.Subdomain, list.CustomerGroup)
+	if errors.Is(err, repository.ErrPriceListNotFound) {
+		return true
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check price lists", "details": err.Error()})
+		return false
+	}
+	if existing.ID != list.ID {
+		c.JSON(http.StatusConflict, gin.H{"error": "An active price list already exists for this customer group.", "priceListId": existing.ID})
+		return false
+	}
+	return true
+}
+
+func (h *PriceListHandler) CreatePriceList(c *gin.Context) {
+	var list models.PriceList
+	if err := c.ShouldBindJSON(&list); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if list.Name == "" || list.ProjectID == "" || list.CustomerGroup == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required fields: name, project_id and customerGroup are required."})
+		return
+	}
+	if err := pricing.ValidatePriceList(&list); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	allowedSubdomains, ok := getSubdomainsFromContext(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Could not verify user permissions."})
+		return
+	}
+	middleware.SetAuditSubdomain(c, list.Subdomain)
+	if !isSubdomainAllowed(allowedSubdomains, list.Subdomain) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to create resources in this subdomain."})
+		return
+	}
+
+	now := time.Now().UTC()
+	list.ID = "pl-" + uuid.New().String()
+	middleware.AddAuditTarget(c, "priceListId", list.ID)
+	list.Active = true
+	list.CreatedAt = now
+	list.UpdatedAt = now
+
+	if !h.ensureGroupAvailable(c, &list) {
+		return
+	}
+	if err := h.priceLists.CreatePriceList(context.Background(), &list); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create price list", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "Price list created successfully", "data": list})
+}
+
+func (h *PriceListHandler) ListPriceLists(c *gin.Context) {
+	projectID := c.Query("project_id")
+	if projectID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The project_id query parameter is required."})
+		return
+	}
+
+	allowedSubdomains, ok := getSubdomainsFromContext(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Could not verify user permissions."})
+		return
+	}
+
+	lists, err := h.priceLists.ListPriceLists(context.Background(), projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list price lists", "details": err.Error()})
+		return
+	}
+
+	visible := []models.PriceList{}
+	for _, list := range lists {
+		if isSubdomainAllowed(allowedSubdomains, list.Subdomain) {
+			visible = append(visible, list)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "count": len(visible), "data": visible})
+}
+
+func (h *PriceListHandler) GetPriceList(c *gin.Context) {
+	list, ok := h.loadAuthorizedPriceList(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": list})
+}
+
+func (h *PriceListHandler) UpdatePriceList(c *gin.Context) {
+	current, ok := h.loadAuthorizedPriceList(c)
+	if !ok {
+		return
+	}
+
+	var updates struct {
+		Name          *string                  `json:"name"`
+		CustomerGroup *string                  `json:"customerGroup"`
+		Active        *bool                    `json:"active"`
+		Overrides     *map[string]models.Money `json:"overrides"`
+		Rules         *[]models.PriceRule      `json:"rules"`
+	}
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format", "details": err.Error()})
+		return
+	}
+
+	apply := func(l *models.PriceList) error {
+		if updates.Name != nil && *updates.Name != "" {
+			l.Name = *updates.Name
+		}
+		if updates.CustomerGroup != nil && *updates.CustomerGroup != "" {
+			l.CustomerGroup = *updates.CustomerGroup
+		}
+		if updates.Active != nil {
+			l.Active = *updates.Active
+		}
+		if updates.Overrides != nil {
+			l.Overrides = *updates.Overrides
+		}
+		if updates.Rules != nil {
+			l.Rules = *updates.Rules
+		}
+		return pricing.ValidatePriceList(l)
+	}
+
+	// Se valida primero sobre una copia para responder 400/409 antes de escribir.
+	preview := *current
+	if err := apply(&preview); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.ensureGroupAvailable(c, &preview) {
+		return
+	}
+
+	updated, err := h.priceLists.UpdatePriceList(context.Background(), current.ID, apply)
+	if err != nil {
+		respondPriceListError(c, err, "Failed to update price list")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Price list updated successfully", "data": updated})
+}
+
+// loadAuthorizedPriceList carga la lista de la ruta y verifica que su
+// subdominio esté permitido para la API Key.
+func (h *PriceListHandler) loadAuthorizedPriceList(c *gin.Context) (*models.PriceList, bool) {
+	list, err := h.priceLists.GetPriceList(context.Background(), c.Param("priceListId"))
+	if err != nil {
+		respondPriceListError(c, err, "Failed to load price list")
+		return nil, false
+	}
+
+	allowedSubdomains, ok := getSubdomainsFromContext(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Could not verify user permissions."})
+		return nil, false
+	}
+	middleware.SetAuditSubdomain(c, list.Subdomain)
+	if !isSubdomainAllowed(allowedSubdomains, list.Subdomain) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to access this resource."})
+		return nil, false
+	}
+	return list, true
+}
+
+func respondPriceListError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, repository.ErrPriceListNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Price list not found"})
+	case errors.Is(err, pricing.ErrInvalidPricing):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message, "details": err.Error()})
+	}
+}
@@ -3,17 +3,38 @@ package Handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/andrescris/firestore/lib/firebase"
-	"github.com/andrescris/firestore/lib/firebase/firestore"
+	"github.com/andrescris/products/pkg/middleware"
 	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/pricing"
+	"github.com/andrescris/products/pkg/repository"
 	"github.com/gin-gonic/gin" // <-- CORRECCIÓN AQUÍ
 	"github.com/google/uuid"
 )
 
+// ProductHandler agrupa los handlers de productos y los repositorios que usan.
+type ProductHandler struct {
+	repo       repository.ProductRepository
+	locations  repository.LocationRepository
+	priceLists repository.PriceListRepository
+	rates      repository.ExchangeRateRepository
+	taxes      repository.TaxTableRepository
+	categories repository.CategoryRepository
+	bulk       repository.BulkRepository
+}
+
+// NewProductHandler crea los handlers sobre los repositorios indicados.
+func NewProductHandler(repo repository.ProductRepository, locations repository.LocationRepository, priceLists repository.PriceListRepository, rates repository.ExchangeRateRepository, taxes repository.TaxTableRepository, categories repository.CategoryRepository, bulk repository.BulkRepository) *ProductHandler {
+	return &ProductHandler{repo: repo, locations: locations, priceLists: priceLists, rates: rates, taxes: taxes, categories: categories, bulk: bulk}
+}
+
 // --- Helper para Permisos ---
 func isSubdomainAllowed(allowed []interface{}, target string) bool {
 	for _, s := range allowed {
@@ -38,67 +59,163 @@ func getSubdomainsFromContext(c *gin.Context) ([]interface{}, bool) {
 	return subdomains, true
 }
 
+// requestError es una respuesta de error ya decidida. Las validaciones que
+// comparten los endpoints individuales y el masivo la devuelven en lugar de
+// responder directamente.
+type requestError struct {
+	status int
+	body   gin.H
+}
+
+func (e *requestError) respond(c *gin.Context) {
+	c.JSON(e.status, e.body)
+}
+
+func badRequest(message string) *requestError {
+	return &requestError{status: http.StatusBadRequest, body: gin.H{"error": message}}
+}
+
+// respondRepositoryError traduce los errores del repositorio a respuestas HTTP.
+func respondRepositoryError(c *gin.Context, err error, message string) {
+	repositoryError(err, message).respond(c)
+}
+
+// repositoryError es la respuesta HTTP que corresponde a un error del repositorio.
+func repositoryError(err error, message string) *requestError {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return &requestError{http.StatusNotFound, gin.H{"error": "Product not found"}}
+	case errors.Is(err, repository.ErrVariationNotFound):
+		return &requestError{http.StatusNotFound, gin.H{"error": "Variation not found"}}
+	case errors.Is(err, repository.ErrRevisionNotFound):
+		return &requestError{http.StatusNotFound, gin.H{"error": "Revision not found"}}
+	case errors.Is(err, repository.ErrVersionConflict):
+		return &requestError{http.StatusPreconditionFailed, gin.H{"error": "The product was modified by another request. Reload it and retry with the new ETag."}}
+	case errors.Is(err, repository.ErrDuplicateSKU):
+		return &requestError{http.StatusConflict, gin.H{"error": "A variation with this SKU already exists for this product."}}
+	case errors.Is(err, repository.ErrLocationRequired), errors.Is(err, repository.ErrNotPerLocation), errors.Is(err, repository.ErrSameLocation):
+		return badRequest(err.Error())
+	case errors.Is(err, pricing.ErrInvalidPricing), errors.Is(err, repository.ErrInvalidUpdate),
+		errors.Is(err, repository.ErrNoOptions), errors.Is(err, repository.ErrInvalidSKUPattern),
+		errors.Is(err, repository.ErrInvalidBundle), errors.Is(err, repository.ErrBundleCycle), errors.Is(err, repository.ErrBundleStock):
+		return badRequest(err.Error())
+	case errors.Is(err, repository.ErrInvalidTransition), errors.Is(err, repository.ErrNotDraft), errors.Is(err, repository.ErrNotDeleted),
+		errors.Is(err, repository.ErrDuplicateBulkProduct):
+		return &requestError{http.StatusConflict, gin.H{"error": err.Error()}}
+	default:
+		return &requestError{http.StatusInternalServerError, gin.H{"error": message, "details": err.Error()}}
+	}
+}
+
 // --- Handlers ---
 
-func CreateProduct(c *gin.Context) {
+func (h *ProductHandler) CreateProduct(c *gin.Context) {
 	var product models.Product
 	if err := c.ShouldBindJSON(&product); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
 		return
 	}
+	if err := validateNewProduct(&product); err != nil {
+		err.respond(c)
+		return
+	}
+
+	allowedSubdomains, ok := getSubdomainsFromContext(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Could not verify user permissions."})
+		return
+	}
+
+	middleware.SetAuditSubdomain(c, product.Subdomain)
+	if !isSubdomainAllowed(allowedSubdomains, product.Subdomain) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to create resources in this subdomain."})
+		return
+	}
+
+	ctx := stockContext(c, models.MovementInitial)
+	if err := h.prepareNewProduct(ctx, &product); err != nil {
+		err.respond(c)
+		return
+	}
+	middleware.AddAuditTarget(c, "id", product.ID)
+
+	if err := h.repo.Create(ctx, &product); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create product", "details": err.Error()})
+		return
+	}
+
+	setProductETag(c, &product)
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "Product created successfully", "data": product})
+}
 
+// validateNewProduct aplica a un producto nuevo las validaciones que no
+// necesitan leer nada del repositorio.
+func validateNewProduct(product *models.Product) *requestError {
 	// --- LÓGICA DE VALIDACIÓN HÍBRIDA ---
 	if len(product.Variations) == 0 {
 		// Es un producto simple: validar campos principales
 		if product.Name == "" || product.SKU == "" || product.Price <= 0 || product.ProjectID == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Para un producto simple, se requieren: name, sku, price y project_id."})
-			return
+			return badRequest("Para un producto simple, se requieren: name, sku, price y project_id.")
 		}
 	} else {
 		// Es un producto con variaciones: validar campos de cada variación
 		if product.Name == "" || product.ProjectID == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Para un producto con variaciones, se requieren: name y project_id."})
-			return
+			return badRequest("Para un producto con variaciones, se requieren: name y project_id.")
 		}
 		for _, v := range product.Variations {
 			if v.SKU == "" || v.Price <= 0 {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Cada variación debe tener un sku y un price válido.", "variation_sku": v.SKU})
-				return
+				return &requestError{http.StatusBadRequest, gin.H{"error": "Cada variación debe tener un sku y un price válido.", "variation_sku": v.SKU}}
 			}
 		}
 	}
 
-	if len(product.Variations) == 0 {
-		// Es un producto simple. El precio de filtro es el precio principal.
-		product.FilterPrice = product.Price
-	} else {
-		// Es un producto con variaciones. Calculamos el precio mínimo.
-		var minPrice float64 = -1
-		for _, v := range product.Variations {
-			if minPrice == -1 || v.Price < minPrice {
-				minPrice = v.Price
-			}
-		}
-		product.FilterPrice = minPrice
+	product.Currency = strings.ToUpper(product.Currency)
+	if !models.ValidCurrency(product.Currency) {
+		return badRequest("currency must be a valid ISO 4217 code.")
+	}
+
+	if err := pricing.ValidateProduct(product); err != nil {
+		return badRequest(err.Error())
+	}
+	if err := models.ValidateOptions(product.Options); err != nil {
+		return badRequest(err.Error())
+	}
+	if product.Status != "" && product.Status != models.StatusDraft && product.Status != models.StatusPublished {
+		return badRequest("status must be draft or published when creating a product.")
 	}
 
 	// VALIDACIÓN ACTUALIZADA:
 	// Eliminamos la validación de 'price' porque ahora pertenece a las variaciones.
 	// Mantenemos las validaciones para los campos que sí son del producto principal.
 	if product.Name == "" || product.ProjectID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required fields: name and project_id are required."})
-		return
+		return badRequest("Missing required fields: name and project_id are required.")
 	}
+	return nil
+}
 
-	allowedSubdomains, ok := getSubdomainsFromContext(c)
-	if !ok {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Could not verify user permissions."})
-		return
+// prepareNewProduct valida lo que depende de otros documentos (ubicaciones,
+// categoría, bundle) y completa IDs, fechas y estado de un producto nuevo ya
+// autorizado.
+func (h *ProductHandler) prepareNewProduct(ctx context.Context, product *models.Product) *requestError {
+	// El stock por ubicación solo puede usar ubicaciones activas del proyecto.
+	if err := validateLocations(ctx, h.locations, product.ProjectID, repository.InventoryLocations(product)); err != nil {
+		return badRequest(err.Error())
+	}
+
+	// Con categoryId, la ruta de la categoría y su linaje los fija el árbol.
+	product.CategoryIDs = nil
+	if product.CategoryID != "" {
+		category, err := resolveCategory(ctx, h.categories, product.ProjectID, product.CategoryID)
+		if err != nil {
+			return badRequest(err.Error())
+		}
+		repository.SetProductCategory(product, category)
 	}
-
-	if !isSubdomainAllowed(allowedSubdomains, product.Subdomain) {
-		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to create resources in this subdomain."})
-		return
+	if err := validateVariationAttributes(h.categories, product, product.Variations...); err != nil {
+		return err
+	}
+	if err := repository.ValidateBundle(ctx, h.repo, product); err != nil {
+		return repositoryError(err, "Failed to validate bundle")
 	}
 
 	// LÓGICA DE CREACIÓN:
@@ -111,38 +228,46 @@ func CreateProduct(c *gin.Context) {
 	// Por ejemplo:
 	for i := range product.Variations {
 		product.Variations[i].ID = "var-" + uuid.New().String()
+		product.Variations[i].Active = true
 	}
 
 	product.ID = "prod-" + uuid.New().String()
 	now := time.Now().UTC()
 	product.CreatedAt = now
 	product.UpdatedAt = now
-	product.Active = true
-
-	var data map[string]interface{}
-	jsonData, _ := json.Marshal(product)
-	json.Unmarshal(jsonData, &data)
 
-	ctx := context.Background()
-	if err := firestore.CreateDocumentWithID(ctx, "products", product.ID, data); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create product", "details": err.Error()})
-		return
+	// Los productos nacen como borrador salvo que se pidan publicados. Con
+	// publishAt futuro el borrador queda programado; active sigue al estado.
+	if product.Status == "" {
+		product.Status = models.StatusDraft
+	}
+	if product.PublishAt != nil {
+		if product.PublishAt.After(now) {
+			product.Status = models.StatusDraft
+			publishAt := product.PublishAt.UTC().Truncate(time.Second)
+			product.PublishAt = &publishAt
+		} else {
+			product.Status = models.StatusPublished
+		}
 	}
-
-	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "Product created successfully", "data": product})
+	return nil
 }
 
-func UpdateProduct(c *gin.Context) {
+func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 	productID := c.Param("id")
-	ctx := context.Background()
+	ctx := stockContext(c, models.MovementAdjustment)
+
+	expectedVersion, ok := expectedVersionFromRequest(c)
+	if !ok {
+		return
+	}
 
 	// --- Esta parte de verificación de permisos sigue igual ---
-	productDoc, err := firestore.GetDocument(ctx, "products", productID)
+	product, err := h.repo.Get(ctx, productID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		respondRepositoryError(c, err, "Failed to load product")
 		return
 	}
-	productSubdomain, _ := productDoc.Data["subdomain"].(string)
 
 	allowedSubdomains, ok := getSubdomainsFromContext(c)
 	if !ok {
@@ -150,7 +275,8 @@ func UpdateProduct(c *gin.Context) {
 		return
 	}
 
-	if !isSubdomainAllowed(allowedSubdomains, productSubdomain) {
+	middleware.SetAuditSubdomain(c, product.Subdomain)
+	if !isSubdomainAllowed(allowedSubdomains, product.Subdomain) {
 		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to modify resources in this subdomain."})
 		return
 	}
@@ -161,23 +287,169 @@ func UpdateProduct(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format", "details": err.Error()})
 		return
 	}
+	if err := h.prepareUpdate(product, updates); err != nil {
+		err.respond(c)
+		return
+	}
+
+	updated, err := h.repo.Update(ctx, productID, expectedVersion, updates)
+	if err != nil {
+		respondRepositoryError(c, err, "Failed to update product")
+		return
+	}
+
+	setProductETag(c, updated)
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Product updated successfully"})
+}
+
+// prepareUpdate limpia y valida una actualización parcial del producto
+// (quita los campos protegidos y resuelve categoría, estado y bundle).
+func (h *ProductHandler) prepareUpdate(product *models.Product, updates map[string]interface{}) *requestError {
 	// Medida de seguridad: Eliminar campos que no deberían ser actualizables por esta vía.
 	delete(updates, "id")
 	delete(updates, "createdAt")
 	delete(updates, "subdomain")
 	delete(updates, "project_id")
 	delete(updates, "variations") // ¡MUY IMPORTANTE! Evita que se borren las variaciones.
+	delete(updates, "version")
+	delete(updates, "updatedAt")
+	delete(updates, "inventory") // El stock por ubicación se cambia con ajustes y transferencias.
+	delete(updates, "category_ids")
+	// La papelera se gestiona con DELETE y los endpoints de restauración: una
+	// fecha de baja falsa adelantaría el borrado definitivo.
+	delete(updates, "deletedAt")
+	delete(updates, "deletedBy")
+	delete(updates, "variation_deleted_at")
+	// El estado se cambia con los endpoints de publicación; active se
+	// traduce al estado equivalente por compatibilidad.
+	delete(updates, "status")
+	delete(updates, "publishAt")
+	if value, ok := updates["active"]; ok {
+		delete(updates, "active")
+		active, isBool := value.(bool)
+		if !isBool {
+			return badRequest("active must be a boolean.")
+		}
+		to := models.StatusArchived
+		if active {
+			to = models.StatusPublished
+		}
+		if err := repository.SetStatus(to)(product); err != nil {
+			return repositoryError(err, "Failed to change status")
+		}
+		updates["status"] = product.Status
+	}
+	if err := h.resolveCategoryUpdate(product, updates); err != nil {
+		return err
+	}
+	if value, ok := updates["currency"]; ok {
+		currency, _ := value.(string)
+		if !models.ValidCurrency(currency) {
+			return badRequest("currency must be a valid ISO 4217 code.")
+		}
+		updates["currency"] = strings.ToUpper(currency)
+	}
+	if value, ok := updates["options"]; ok {
+		var options []models.ProductOption
+		data, _ := json.Marshal(value)
+		if err := json.Unmarshal(data, &options); err != nil {
+			return &requestError{http.StatusBadRequest, gin.H{"error": "options must be a list of {name, values}.", "details": err.Error()}}
+		}
+		if err := models.ValidateOptions(options); err != nil {
+			return badRequest(err.Error())
+		}
+	}
+	if err := h.validateBundleUpdate(product, updates); err != nil {
+		return err
+	}
+	if _, ok := updates["stock"]; ok && len(product.Inventory) > 0 {
+		return badRequest(repository.ErrLocationRequired.Error())
+	}
+	return nil
+}
 
-	updates["updatedAt"] = time.Now().UTC()
-	if err := firestore.UpdateDocument(ctx, "products", productID, updates); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update product", "details": err.Error()})
-		return
+// resolveCategoryUpdate traduce un cambio de categoryId a la ruta y el linaje
+// de la categoría. Mientras el producto esté enlazado al árbol, category no
+// se puede cambiar directamente.
+func (h *ProductHandler) resolveCategoryUpdate(product *models.Product, updates map[string]interface{}) *requestError {
+	value, ok := updates["categoryId"]
+	if !ok {
+		if _, ok := updates["category"]; ok && product.CategoryID != "" {
+			return badRequest("category is derived from categoryId; update categoryId instead.")
+		}
+		return nil
 	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Product updated successfully"})
+	id, ok := value.(string)
+	if !ok {
+		return badRequest("categoryId must be a string.")
+	}
+	if id == "" {
+		// Se desenlaza del árbol; category queda como texto libre.
+		updates["category_ids"] = nil
+		return nil
+	}
+
+	category, err := resolveCategory(context.Background(), h.categories, product.ProjectID, id)
+	if err != nil {
+		return badRequest(err.Error())
+	}
+	updates["category"] = category.Path
+	updates["category_ids"] = category.Lineage()
+	return nil
+}
+
+// validateBundleUpdate valida la definición de bundle que resultaría de la
+// actualización y guarda los componentes ya resueltos. En un bundle también
+// valida los cambios de stock y de opciones, que no puede tener.
+func (h *ProductHandler) validateBundleUpdate(product *models.Product, updates map[string]interface{}) *requestError {
+	_, typeChanged := updates["type"]
+	_, componentsChanged := updates["components"]
+	_, stockChanged := updates["stock"]
+	_, optionsChanged := updates["options"]
+	if !typeChanged && !componentsChanged && !((stockChanged || optionsChanged) && product.IsBundle()) {
+		return nil
+	}
+
+	preview, err := previewUpdates(product, updates)
+	if err != nil {
+		return &requestError{http.StatusBadRequest, gin.H{"error": "Invalid bundle data", "details": err.Error()}}
+	}
+
+	if err := repository.ValidateBundle(context.Background(), h.repo, preview); err != nil {
+		return repositoryError(err, "Failed to validate bundle")
+	}
+	if componentsChanged {
+		updates["components"] = preview.Components
+	}
+	return nil
+}
+
+// previewUpdates devuelve cómo quedaría el producto con los campos de updates,
+// sin guardarlo.
+func previewUpdates(product *models.Product, updates map[string]interface{}) (*models.Product, error) {
+	data, err := json.Marshal(product)
+	if err != nil {
+		return nil, err
+	}
+	var merged map[string]interface{}
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return nil, err
+	}
+	for field, value := range updates {
+		merged[field] = value
+	}
+	if data, err = json.Marshal(merged); err != nil {
+		return nil, err
+	}
+	var preview models.Product
+	if err := json.Unmarshal(data, &preview); err != nil {
+		return nil, err
+	}
+	return &preview, nil
 }
 
-func GetProductByID(c *gin.Context) {
+func (h *ProductHandler) GetProductByID(c *gin.Context) {
 	// --- AÑADIMOS LA VERIFICACIÓN AL INICIO ---
 	userSubdomain, userSubdomainExists := c.Get("subdomain")
 	if !userSubdomainExists {
@@ -187,39 +459,55 @@ func GetProductByID(c *gin.Context) {
 	}
 	// --- FIN DE LA VERIFICACIÓN INICIAL ---
 
+	currency, ok := displayCurrency(c)
+	if !ok {
+		return
+	}
+
 	productID := c.Param("id")
 	ctx := context.Background()
 
-	doc, err := firestore.GetDocument(ctx, "products", productID)
+	product, err := h.repo.Get(ctx, productID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		respondRepositoryError(c, err, "Failed to load product")
 		return
 	}
 
-	productSubdomain, productSubdomainOk := doc.Data["subdomain"].(string)
-
 	// Verificamos la pertenencia del producto al subdominio del usuario.
-	if !productSubdomainOk || userSubdomain.(string) != productSubdomain {
+	if product.Subdomain == "" || userSubdomain.(string) != product.Subdomain {
 		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to access this resource."})
 		return
 	}
 
-	// El resto de la función no cambia...
-	var product models.Product
-	jsonData, err := json.Marshal(doc.Data)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process product data (marshal)"})
+	// La tienda solo ve productos publicados; borradores y archivados no existen para ella.
+	if !product.IsPublished() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	// El stock de un bundle depende del de sus componentes en este momento.
+	if err := repository.ApplyBundleStock(ctx, h.repo, product); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute bundle stock", "details": err.Error()})
 		return
 	}
-	if err := json.Unmarshal(jsonData, &product); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse product data (unmarshal)"})
+
+	// El precio vigente se resuelve al leer: la ventana de oferta puede haberse
+	// abierto o cerrado desde la última escritura, y la sesión puede tener una
+	// lista de precios de su grupo de clientes.
+	priceList := priceListForRequest(c, h.priceLists, product.Subdomain)
+	pricing.ApplyPriceList(product, priceList, time.Now().UTC())
+	if currency != "" && !convertProducts(c, h.rates, currency, product) {
+		return
+	}
+	if !applyTaxes(c, h.taxes, product) {
 		return
 	}
 
+	setProductETag(c, product)
 	c.JSON(http.StatusOK, gin.H{"success": true, "data": product})
 }
 
-func ListProducts(c *gin.Context) {
+func (h *ProductHandler) ListProducts(c *gin.Context) {
 	var options firebase.QueryOptions
 	if err := c.ShouldBindJSON(&options); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body for filters", "details": err.Error()})
@@ -227,6 +515,10 @@ func ListProducts(c *gin.Context) {
 	}
 
 	subdomain, subdomainExists := c.Get("subdomain")
+	currency, ok := displayCurrency(c)
+	if !ok {
+		return
+	}
 
 	// --- INICIO DE LA CORRECCIÓN FINAL DE SEGURIDAD ---
 
@@ -243,9 +535,12 @@ func ListProducts(c *gin.Context) {
 	}
 
 	// Si llegamos aquí, es porque sí existe un subdominio y lo forzamos.
+	// La tienda solo ve productos publicados. Se filtra por active, que
+	// refleja status == published y que también tienen los documentos
+	// anteriores al ciclo de vida (igual que IsPublished en el detalle).
 	secureFilters := []firebase.QueryFilter{}
 	for _, filter := range options.Filters {
-		if filter.Field != "subdomain" {
+		if filter.Field != "subdomain" && filter.Field != "status" && filter.Field != "active" {
 			secureFilters = append(secureFilters, filter)
 		}
 	}
@@ -253,25 +548,57 @@ func ListProducts(c *gin.Context) {
 		Field:    "subdomain",
 		Operator: "==",
 		Value:    subdomain.(string),
+	}, firebase.QueryFilter{
+		Field:    "active",
+		Operator: "==",
+		Value:    true,
 	})
 	options.Filters = secureFilters
 
+	// ?category=<id> incluye los productos de toda la rama de la categoría.
+	if categoryID := c.Query("category"); categoryID != "" {
+		category, err := h.categories.GetCategory(context.Background(), categoryID)
+		if errors.Is(err, repository.ErrCategoryNotFound) || (err == nil && category.Subdomain != subdomain.(string)) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load category", "details": err.Error()})
+			return
+		}
+		options.Filters = append(options.Filters, firebase.QueryFilter{
+			Field:    "category_ids",
+			Operator: "array-contains",
+			Value:    category.ID,
+		})
+	}
+
 	// --- FIN DE LA CORRECCIÓN FINAL DE SEGURIDAD ---
 
 	// El resto de la función no cambia...
 	ctx := context.Background()
-	docs, err := firestore.QueryDocuments(ctx, "products", options)
+	products, err := h.repo.Query(ctx, options)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query products", "details": err.Error()})
 		return
 	}
 
-	var products []models.Product
-	for _, doc := range docs {
-		var product models.Product
-		jsonData, _ := json.Marshal(doc.Data)
-		json.Unmarshal(jsonData, &product)
-		products = append(products, product)
+	now := time.Now().UTC()
+	priceList := priceListForRequest(c, h.priceLists, subdomain.(string))
+	converted := make([]*models.Product, len(products))
+	for i := range products {
+		pricing.ApplyPriceList(&products[i], priceList, now)
+		converted[i] = &products[i]
+	}
+	if err := repository.ApplyBundleStock(ctx, h.repo, converted...); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute bundle stock", "details": err.Error()})
+		return
+	}
+	if currency != "" && !convertProducts(c, h.rates, currency, converted...) {
+		return
+	}
+	if !applyTaxes(c, h.taxes, converted...) {
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -282,17 +609,21 @@ func ListProducts(c *gin.Context) {
 	})
 }
 
-func DeleteProduct(c *gin.Context) {
+func (h *ProductHandler) DeleteProduct(c *gin.Context) {
 	productID := c.Param("id")
-	ctx := context.Background()
+	// El autor queda registrado en deletedBy.
+	ctx := stockContext(c, models.MovementAdjustment)
 
-	productDoc, err := firestore.GetDocument(ctx, "products", productID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+	expectedVersion, ok := expectedVersionFromRequest(c)
+	if !ok {
 		return
 	}
 
-	productSubdomain, _ := productDoc.Data["subdomain"].(string)
+	product, err := h.repo.Get(ctx, productID)
+	if err != nil {
+		respondRepositoryError(c, err, "Failed to load product")
+		return
+	}
 
 	allowedSubdomains, ok := getSubdomainsFromContext(c)
 	if !ok {
@@ -300,51 +631,46 @@ func DeleteProduct(c *gin.Context) {
 		return
 	}
 
-	if !isSubdomainAllowed(allowedSubdomains, productSubdomain) {
+	middleware.SetAuditSubdomain(c, product.Subdomain)
+	if !isSubdomainAllowed(allowedSubdomains, product.Subdomain) {
 		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to modify resources in this subdomain."})
 		return
 	}
 
-	updates := map[string]interface{}{
-		"active":    false,
-		"updatedAt": time.Now().UTC(),
-	}
-
-	if err := firestore.UpdateDocument(ctx, "products", productID, updates); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete product", "details": err.Error()})
+	updated, err := h.repo.Deactivate(ctx, productID, expectedVersion)
+	if err != nil {
+		respondRepositoryError(c, err, "Failed to delete product")
 		return
 	}
 
+	setProductETag(c, updated)
+
 	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Product deactivated successfully"})
 }
 
-func CreateVariation(c *gin.Context) {
+func (h *ProductHandler) CreateVariation(c *gin.Context) {
 	productID := c.Param("id")
-	ctx := context.Background()
+	ctx := stockContext(c, models.MovementInitial)
 
-	// 1. Obtener el producto principal
-	doc, err := firestore.GetDocument(ctx, "products", productID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+	expectedVersion, ok := expectedVersionFromRequest(c)
+	if !ok {
 		return
 	}
 
-	// --- INICIO DE LA CORRECCIÓN ---
-	var product models.Product
-	jsonData, err := json.Marshal(doc.Data)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process product data (marshal)", "details": err.Error()})
+	product, ok := authorizeProduct(c, h.repo, productID)
+	if !ok {
 		return
 	}
-	if err := json.Unmarshal(jsonData, &product); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse product data (unmarshal)", "details": err.Error()})
+
+	// 1. Obtener y validar la nueva variación del cuerpo de la petición. Los
+	// importes en formato antiguo se convierten con la moneda del producto.
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variation data", "details": err.Error()})
 		return
 	}
-	// --- FIN DE LA CORRECCIÓN ---
-
-	// 2. Obtener y validar la nueva variación del cuerpo de la petición
-	var newVariation models.Variation
-	if err := c.ShouldBindJSON(&newVariation); err != nil {
+	newVariation, err := models.DecodeVariation(body, product.Currency)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variation data", "details": err.Error()})
 		return
 	}
@@ -354,150 +680,142 @@ func CreateVariation(c *gin.Context) {
 		return
 	}
 
-	for _, v := range product.Variations {
-		if v.SKU == newVariation.SKU {
-			c.JSON(http.StatusConflict, gin.H{"error": "A variation with this SKU already exists for this product."})
+	if !checkVariationAttributes(c, h.categories, product, newVariation) {
+		return
+	}
+
+	if len(newVariation.Inventory) > 0 {
+		locationIDs := repository.InventoryLocations(&models.Product{Inventory: newVariation.Inventory})
+		if err := validateLocations(ctx, h.locations, product.ProjectID, locationIDs); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 	}
 
-	// 3. Asignar un nuevo ID y añadir la variación al producto
+	// 2. Asignar un nuevo ID
 	newVariation.ID = "var-" + uuid.New().String()
+	middleware.AddAuditTarget(c, "variationId", newVariation.ID)
 	newVariation.Active = true
-	product.Variations = append(product.Variations, newVariation)
-	product.UpdatedAt = time.Now().UTC()
 
-	// 4. Guardar el producto actualizado en Firestore
-	var data map[string]interface{}
-	jsonUpdateData, _ := json.Marshal(product)
-	json.Unmarshal(jsonUpdateData, &data)
-
-	if err := firestore.UpdateDocument(ctx, "products", productID, data); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add variation", "details": err.Error()})
+	// 3. Añadir la variación dentro de una transacción. El repositorio comprueba
+	// que el SKU no esté repetido con los datos más recientes del producto.
+	updated, err := h.repo.AddVariation(ctx, productID, expectedVersion, newVariation)
+	if err != nil {
+		respondRepositoryError(c, err, "Failed to add variation")
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "Variation created successfully", "data": newVariation})
+	setProductETag(c, updated)
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "Variation created successfully", "data": models.CurrencyVariation{Variation: newVariation, Currency: product.Currency}})
 }
 
-func UpdateVariation(c *gin.Context) {
+func (h *ProductHandler) UpdateVariation(c *gin.Context) {
 	productID := c.Param("id")
 	variationID := c.Param("variationId")
-	ctx := context.Background()
-
-	// 1. Obtener el producto principal
-	doc, err := firestore.GetDocument(ctx, "products", productID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
-		return
-	}
+	ctx := stockContext(c, models.MovementAdjustment)
 
-	// --- INICIO DE LA CORRECCIÓN ---
-	var product models.Product
-	jsonData, err := json.Marshal(doc.Data)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process product data (marshal)", "details": err.Error()})
-		return
-	}
-	if err := json.Unmarshal(jsonData, &product); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse product data (unmarshal)", "details": err.Error()})
+	expectedVersion, ok := expectedVersionFromRequest(c)
+	if !ok {
 		return
 	}
-	// --- FIN DE LA CORRECCIÓN ---
 
-	// 2. Obtener los datos a actualizar
+	// 1. Obtener los datos a actualizar
 	var updates map[string]interface{}
 	if err := c.ShouldBindJSON(&updates); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format", "details": err.Error()})
 		return
 	}
 
-	// 3. Encontrar y actualizar la variación
-	variationFound := false
-	for i, v := range product.Variations {
-		if v.ID == variationID {
-			variationFound = true
-			if price, ok := updates["price"].(float64); ok {
-				product.Variations[i].Price = price
-			}
-			if stock, ok := updates["stock"].(float64); ok {
-				product.Variations[i].Stock = int(stock)
-			}
-			if imageUrl, ok := updates["imageUrl"].(string); ok {
-				product.Variations[i].ImageURL = imageUrl
-			}
-			break
-		}
-	}
+	// La baja de la variación solo cambia con DELETE y su restauración.
+	delete(updates, "deletedAt")
+	delete(updates, "deletedBy")
 
-	if !variationFound {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Variation not found"})
+	product, ok := authorizeProduct(c, h.repo, productID)
+	if !ok {
 		return
 	}
 
-	product.UpdatedAt = time.Now().UTC()
-
-	// 4. Guardar el producto actualizado
-	var data map[string]interface{}
-	jsonUpdateData, _ := json.Marshal(product)
-	json.Unmarshal(jsonUpdateData, &data)
-
-	if err := firestore.UpdateDocument(ctx, "products", productID, data); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update variation", "details": err.Error()})
+	// 2. Encontrar, actualizar la variación y guardar el producto
+	updated, err := h.repo.UpdateVariation(ctx, productID, variationID, expectedVersion, func(v *models.Variation) error {
+		return applyVariationUpdates(v, updates, product.Currency)
+	})
+	if err != nil {
+		respondRepositoryError(c, err, "Failed to update variation")
 		return
 	}
 
+	setProductETag(c, updated)
 	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Variation updated successfully"})
 }
 
-func DeleteVariation(c *gin.Context) {
-	productID := c.Param("id")
-	variationID := c.Param("variationId")
-	ctx := context.Background()
+// variationUpdatableFields son los campos que se pueden cambiar con PATCH de
+// una variación.
+var variationUpdatableFields = []string{
+	"price", "stock", "imageUrl",
+	"salePrice", "compareAtPrice", "saleStartsAt", "saleEndsAt", "priceTiers", "currencyPrices",
+}
 
-	doc, err := firestore.GetDocument(ctx, "products", productID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
-		return
+// applyVariationUpdates aplica a la variación los campos permitidos presentes
+// en updates. Un valor null borra el campo. Los importes pueden venir en el
+// formato antiguo y se convierten con la moneda del producto.
+func applyVariationUpdates(v *models.Variation, updates map[string]interface{}, currency string) error {
+	// Con stock por ubicación el total se deriva; se usan ajustes por ubicación.
+	if _, ok := updates["stock"]; ok && len(v.Inventory) > 0 {
+		return repository.ErrLocationRequired
 	}
 
-	// --- INICIO DE LA CORRECCIÓN ---
-	var product models.Product
-	jsonData, err := json.Marshal(doc.Data)
+	current, err := json.Marshal(v)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process product data (marshal)", "details": err.Error()})
-		return
+		return err
 	}
-	if err := json.Unmarshal(jsonData, &product); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse product data (unmarshal)", "details": err.Error()})
-		return
+	var data map[string]interface{}
+	if err := json.Unmarshal(current, &data); err != nil {
+		return err
 	}
-	// --- FIN DE LA CORRECCIÓN ---
-
-	variationFound := false
-	for i, v := range product.Variations {
-		if v.ID == variationID {
-			product.Variations[i].Active = false
-			variationFound = true
-			break
+	for _, field := range variationUpdatableFields {
+		value, ok := updates[field]
+		if !ok {
+			continue
+		}
+		if value == nil {
+			delete(data, field)
+		} else {
+			data[field] = value
 		}
 	}
 
-	if !variationFound {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Variation not found"})
-		return
+	merged, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	decoded, err := models.DecodeVariation(merged, currency)
+	if err != nil {
+		return fmt.Errorf("%w: %v", repository.ErrInvalidUpdate, err)
 	}
+	*v = decoded
+	return nil
+}
 
-	product.UpdatedAt = time.Now().UTC()
+func (h *ProductHandler) DeleteVariation(c *gin.Context) {
+	productID := c.Param("id")
+	variationID := c.Param("variationId")
+	ctx := stockContext(c, models.MovementAdjustment)
 
-	var data map[string]interface{}
-	jsonUpdateData, _ := json.Marshal(product)
-	json.Unmarshal(jsonUpdateData, &data)
+	expectedVersion, ok := expectedVersionFromRequest(c)
+	if !ok {
+		return
+	}
 
-	if err := firestore.UpdateDocument(ctx, "products", productID, data); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deactivate variation"})
+	if _, ok := authorizeProduct(c, h.repo, productID); !ok {
+		return
+	}
+
+	updated, err := h.repo.DeactivateVariation(ctx, productID, variationID, expectedVersion)
+	if err != nil {
+		respondRepositoryError(c, err, "Failed to deactivate variation")
 		return
 	}
 
+	setProductETag(c, updated)
 	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Variation deactivated successfully"})
 }
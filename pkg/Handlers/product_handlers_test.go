@@ -0,0 +1,352 @@
+package Handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+func simpleProductBody(sku string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":       "Tee " + sku,
+		"sku":        sku,
+		"price":      map[string]interface{}{"amount": 1999},
+		"stock":      5,
+		"currency":   "USD",
+		"project_id": "proj",
+		"subdomain":  "s",
+		"status":     "published",
+	}
+}
+
+// createProduct crea un producto publicado por la API y lo devuelve.
+func createProduct(t *testing.T, api *testAPI, body map[string]interface{}) models.Product {
+	t.Helper()
+	var product models.Product
+	expect(t, api.do(http.MethodPost, "/api/v1/products/", body), http.StatusCreated, &product)
+	return product
+}
+
+func etag(version int64) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+func TestCreateProductValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(map[string]interface{})
+		status int
+	}{
+		{"valid", func(map[string]interface{}) {}, http.StatusCreated},
+		{"missing name", func(b map[string]interface{}) { delete(b, "name") }, http.StatusBadRequest},
+		{"missing price", func(b map[string]interface{}) { delete(b, "price") }, http.StatusBadRequest},
+		{"unknown currency", func(b map[string]interface{}) { b["currency"] = "XXX" }, http.StatusBadRequest},
+		{"archived on create", func(b map[string]interface{}) { b["status"] = "archived" }, http.StatusBadRequest},
+		{"other subdomain", func(b map[string]interface{}) { b["subdomain"] = "other" }, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newTestAPI(t)
+			body := simpleProductBody("A")
+			tt.change(body)
+			expect(t, api.do(http.MethodPost, "/api/v1/products/", body), tt.status, nil)
+		})
+	}
+}
+
+func TestProductCRUD(t *testing.T) {
+	api := newTestAPI(t)
+	created := createProduct(t, api, simpleProductBody("A"))
+	if created.ID == "" || created.Version != 1 || created.Price != 1999 || !created.Active {
+		t.Fatalf("created product = %+v", created)
+	}
+	path := "/api/v1/products/" + created.ID
+
+	var got models.Product
+	rec := api.do(http.MethodGet, path, nil)
+	expect(t, rec, http.StatusOK, &got)
+	if got.Name != "Tee A" || rec.Header().Get("ETag") != etag(1) {
+		t.Fatalf("GET = %+v, ETag %s", got, rec.Header().Get("ETag"))
+	}
+	expect(t, api.do(http.MethodGet, path, nil, "X-Test-Subdomain", "other"), http.StatusForbidden, nil)
+	expect(t, api.do(http.MethodGet, "/api/v1/products/missing", nil), http.StatusNotFound, nil)
+
+	// PATCH con la versión actual; la misma versión otra vez ya es vieja.
+	rec = api.do(http.MethodPatch, path, `{"name": "Renamed", "id": "hijack"}`, "If-Match", etag(1))
+	expect(t, rec, http.StatusOK, nil)
+	if rec.Header().Get("ETag") != etag(2) {
+		t.Fatalf("PATCH ETag = %s, want %s", rec.Header().Get("ETag"), etag(2))
+	}
+	expect(t, api.do(http.MethodPatch, path, `{"name": "Stale"}`, "If-Match", etag(1)), http.StatusPreconditionFailed, nil)
+	expect(t, api.do(http.MethodPatch, path, `{"name": "Other"}`, "X-Test-Subdomain", "other"), http.StatusForbidden, nil)
+	expect(t, api.do(http.MethodGet, path, nil), http.StatusOK, &got)
+	if got.Name != "Renamed" || got.ID != created.ID {
+		t.Fatalf("after PATCH = %+v", got)
+	}
+
+	var list []models.Product
+	expect(t, api.do(http.MethodPost, "/api/v1/products/search", `{}`), http.StatusOK, &list)
+	if len(list) != 1 || list[0].ID != created.ID {
+		t.Fatalf("search = %+v", list)
+	}
+
+	// DELETE archiva: deja de verse en la tienda.
+	expect(t, api.do(http.MethodDelete, path, nil, "X-Test-Subdomain", "other"), http.StatusForbidden, nil)
+	expect(t, api.do(http.MethodDelete, path, nil), http.StatusOK, nil)
+	expect(t, api.do(http.MethodGet, path, nil), http.StatusNotFound, nil)
+	expect(t, api.do(http.MethodPost, "/api/v1/products/search", `{}`), http.StatusOK, &list)
+	if len(list) != 0 {
+		t.Fatalf("search after delete = %+v", list)
+	}
+}
+
+func TestSearchOnlyListsOwnPublishedProducts(t *testing.T) {
+	api := newTestAPI(t)
+	published := createProduct(t, api, simpleProductBody("A"))
+	draft := simpleProductBody("B")
+	draft["status"] = "draft"
+	createProduct(t, api, draft)
+	other := simpleProductBody("C")
+	other["subdomain"] = "other"
+	expect(t, api.do(http.MethodPost, "/api/v1/products/", other, "X-Test-Subdomain", "other"), http.StatusCreated, nil)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"no filters", `{}`, 1},
+		{"status filter is ignored", `{"filters": [{"field": "status", "operator": "==", "value": "draft"}]}`, 1},
+		{"subdomain filter is ignored", `{"filters": [{"field": "subdomain", "operator": "==", "value": "other"}]}`, 1},
+		{"active filter is ignored", `{"filters": [{"field": "active", "operator": "==", "value": false}]}`, 1},
+		{"other filters apply", `{"filters": [{"field": "sku", "operator": "==", "value": "nope"}]}`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var list []models.Product
+			expect(t, api.do(http.MethodPost, "/api/v1/products/search", tt.body), http.StatusOK, &list)
+			if len(list) != tt.want {
+				t.Fatalf("search returned %d products, want %d", len(list), tt.want)
+			}
+			if tt.want == 1 && list[0].ID != published.ID {
+				t.Fatalf("search returned %s, want %s", list[0].ID, published.ID)
+			}
+		})
+	}
+}
+
+func TestVariationCRUD(t *testing.T) {
+	api := newTestAPI(t)
+	product := createProduct(t, api, map[string]interface{}{
+		"name": "Tee", "currency": "USD", "project_id": "proj", "subdomain": "s", "status": "published",
+		"variations": []interface{}{
+			map[string]interface{}{"sku": "T-S", "price": map[string]interface{}{"amount": 1000}, "stock": 2, "attributes": map[string]string{"size": "S"}},
+		},
+	})
+	base := "/api/v1/products/" + product.ID + "/variations"
+
+	var variation struct {
+		ID    string  `json:"id"`
+		Price float64 `json:"price"`
+	}
+	expect(t, api.do(http.MethodPost, base, `{"sku": "T-M", "price": {"amount": 1100}, "stock": 4, "attributes": {"size": "M"}}`), http.StatusCreated, &variation)
+	if variation.Price != 11 {
+		t.Fatalf("created variation price = %v, want the legacy shape", variation.Price)
+	}
+	expect(t, api.do(http.MethodPost, base, `{"sku": "T-M", "price": {"amount": 1100}, "attributes": {"size": "M"}}`), http.StatusConflict, nil)
+	expect(t, api.do(http.MethodPost, base, `{"sku": "T-L"}`), http.StatusBadRequest, nil)
+
+	expect(t, api.do(http.MethodPatch, base+"/"+variation.ID, `{"price": {"amount": 1200}}`), http.StatusOK, nil)
+	expect(t, api.do(http.MethodPatch, base+"/missing", `{"price": {"amount": 1200}}`), http.StatusNotFound, nil)
+	expect(t, api.do(http.MethodDelete, base+"/"+variation.ID, nil), http.StatusOK, nil)
+
+	var got models.Product
+	expect(t, api.do(http.MethodGet, "/api/v1/products/"+product.ID, nil), http.StatusOK, &got)
+	if len(got.Variations) != 2 {
+		t.Fatalf("variations = %+v", got.Variations)
+	}
+	v := got.Variations[1]
+	if v.SKU != "T-M" || v.Price != 1200 || v.Active || got.ActiveVariationCount != 1 || got.TotalStock != 2 {
+		t.Fatalf("after updates: product %+v, variation %+v", got, v)
+	}
+}
+
+func TestSearchFiltersPriceInMajorUnits(t *testing.T) {
+	api := newTestAPI(t)
+	cheap := simpleProductBody("A")
+	cheap["price"] = 9.5
+	createProduct(t, api, cheap)
+	expensive := createProduct(t, api, simpleProductBody("B"))
+
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"legacy filter", `{"filters": [{"field": "filter_price", "operator": ">=", "value": 10}]}`, []string{expensive.ID}},
+		{"exact filter", `{"filters": [{"field": "filter_price_minor.amount", "operator": ">", "value": 950}]}`, []string{expensive.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var list []models.Product
+			expect(t, api.do(http.MethodPost, "/api/v1/products/search", tt.body), http.StatusOK, &list)
+			if len(list) != len(tt.want) || list[0].ID != tt.want[0] {
+				t.Fatalf("search = %+v, want %v", list, tt.want)
+			}
+		})
+	}
+
+	rec := api.do(http.MethodGet, "/api/v1/products/"+expensive.ID, nil)
+	expect(t, rec, http.StatusOK, nil)
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Data["price"] != 19.99 || body.Data["filter_price"] != 19.99 {
+		t.Errorf("price = %v, filter_price = %v; want the legacy shape", body.Data["price"], body.Data["filter_price"])
+	}
+}
+
+func TestVariationWritesRequireProductSubdomain(t *testing.T) {
+	api := newTestAPI(t)
+	product := createProduct(t, api, map[string]interface{}{
+		"name": "Tee", "currency": "USD", "project_id": "proj", "subdomain": "s", "status": "published",
+		"variations": []interface{}{
+			map[string]interface{}{"id": "var-s", "sku": "T-S", "price": map[string]interface{}{"amount": 1000}, "stock": 2, "attributes": map[string]string{"size": "S"}},
+		},
+	})
+	base := "/api/v1/products/" + product.ID + "/variations"
+	variationID := product.Variations[0].ID
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{"create", http.MethodPost, base, `{"sku": "T-M", "price": {"amount": 1100}, "attributes": {"size": "M"}}`},
+		{"update", http.MethodPatch, base + "/" + variationID, `{"price": {"amount": 1}}`},
+		{"delete", http.MethodDelete, base + "/" + variationID, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expect(t, api.do(tt.method, tt.path, tt.body, "X-Test-Subdomain", "other"), http.StatusForbidden, nil)
+		})
+	}
+	expect(t, api.do(http.MethodPost, "/api/v1/products/missing/variations", tests[0].body), http.StatusNotFound, nil)
+
+	var got models.Product
+	expect(t, api.do(http.MethodGet, "/api/v1/products/"+product.ID, nil), http.StatusOK, &got)
+	if got.Version != product.Version || len(got.Variations) != 1 || got.Variations[0].Price != 1000 || !got.Variations[0].Active {
+		t.Fatalf("product changed by another subdomain: %+v", got)
+	}
+}
+
+func TestVariationWritesCheckIfMatch(t *testing.T) {
+	api := newTestAPI(t)
+	product := createProduct(t, api, map[string]interface{}{
+		"name": "Tee", "currency": "USD", "project_id": "proj", "subdomain": "s", "status": "published",
+		"variations": []interface{}{
+			map[string]interface{}{"sku": "T-S", "price": map[string]interface{}{"amount": 1000}, "stock": 2, "attributes": map[string]string{"size": "S"}},
+		},
+	})
+	path := "/api/v1/products/" + product.ID + "/variations/" + product.Variations[0].ID
+
+	rec := api.do(http.MethodPatch, path, `{"price": {"amount": 900}}`, "If-Match", etag(product.Version))
+	expect(t, rec, http.StatusOK, nil)
+	if rec.Header().Get("ETag") != etag(product.Version+1) {
+		t.Fatalf("ETag = %s, want %s", rec.Header().Get("ETag"), etag(product.Version+1))
+	}
+	expect(t, api.do(http.MethodPatch, path, `{"price": {"amount": 800}}`, "If-Match", etag(product.Version)), http.StatusPreconditionFailed, nil)
+	expect(t, api.do(http.MethodDelete, path, nil, "If-Match", etag(product.Version)), http.StatusPreconditionFailed, nil)
+	expect(t, api.do(http.MethodPatch, path, `{"price": {"amount": 800}}`, "If-Match", "not-a-version"), http.StatusBadRequest, nil)
+	expect(t, api.do(http.MethodDelete, path, nil, "If-Match", etag(product.Version+1)), http.StatusOK, nil)
+}
+
+func TestUpdateCannotRewriteTrash(t *testing.T) {
+	api := newTestAPI(t)
+	product := createProduct(t, api, map[string]interface{}{
+		"name": "Tee", "currency": "USD", "project_id": "proj", "subdomain": "s", "status": "published",
+		"variations": []interface{}{
+			map[string]interface{}{"sku": "T-S", "price": map[string]interface{}{"amount": 1000}, "stock": 2, "attributes": map[string]string{"size": "S"}},
+			map[string]interface{}{"sku": "T-M", "price": map[string]interface{}{"amount": 1000}, "stock": 2, "attributes": map[string]string{"size": "M"}},
+		},
+	})
+	path := "/api/v1/products/" + product.ID
+	variationPath := path + "/variations/" + product.Variations[0].ID
+	expect(t, api.do(http.MethodDelete, variationPath, nil), http.StatusOK, nil)
+	expect(t, api.do(http.MethodDelete, path, nil), http.StatusOK, nil)
+	trashed, err := api.store.Get(context.Background(), product.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		body string
+	}{
+		{"backdated product", path, `{"deletedAt": "2000-01-01T00:00:00Z", "deletedBy": {"uid": "someone"}, "description": "x"}`},
+		{"product taken out of the trash", path, `{"deletedAt": null, "deletedBy": null, "description": "y"}`},
+		{"backdated variations", path, `{"variation_deleted_at": "2000-01-01T00:00:00Z", "description": "z"}`},
+		{"backdated variation", variationPath, `{"deletedAt": "2000-01-01T00:00:00Z", "deletedBy": null, "stock": 1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expect(t, api.do(http.MethodPatch, tt.path, tt.body), http.StatusOK, nil)
+			current, err := api.store.Get(context.Background(), product.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if current.DeletedAt == nil || !current.DeletedAt.Equal(*trashed.DeletedAt) || current.DeletedBy == nil || current.DeletedBy.UID != "" {
+				t.Errorf("product deletion = %v by %+v, want %v", current.DeletedAt, current.DeletedBy, trashed.DeletedAt)
+			}
+			if current.VariationDeletedAt == nil || !current.VariationDeletedAt.Equal(*trashed.VariationDeletedAt) {
+				t.Errorf("variation_deleted_at = %v, want %v", current.VariationDeletedAt, trashed.VariationDeletedAt)
+			}
+			if v := current.Variations[0]; v.DeletedAt == nil || !v.DeletedAt.Equal(*trashed.Variations[0].DeletedAt) {
+				t.Errorf("variation deletion = %v, want %v", v.DeletedAt, trashed.Variations[0].DeletedAt)
+			}
+		})
+	}
+}
+
+func TestBundleCannotGetOptionsOrStock(t *testing.T) {
+	api := newTestAPI(t)
+	component := createProduct(t, api, simpleProductBody("MUG"))
+	bundle := createProduct(t, api, map[string]interface{}{
+		"name": "Gift", "sku": "GIFT", "type": "bundle", "price": map[string]interface{}{"amount": 2500},
+		"currency": "USD", "project_id": "proj", "subdomain": "s", "status": "published",
+		"components": []interface{}{map[string]interface{}{"productId": component.ID, "quantity": 2}},
+	})
+	path := "/api/v1/products/" + bundle.ID
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"options", `{"options": [{"name": "color", "values": ["red", "blue"]}]}`, http.StatusBadRequest},
+		{"stock", `{"stock": 3}`, http.StatusBadRequest},
+		{"other field", `{"description": "Two mugs"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expect(t, api.do(http.MethodPatch, path, tt.body), tt.want, nil)
+		})
+	}
+
+	stored, err := api.store.Get(context.Background(), bundle.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.Options) != 0 || stored.Stock != 0 {
+		t.Errorf("bundle options = %v, stock = %d; want none", stored.Options, stored.Stock)
+	}
+}
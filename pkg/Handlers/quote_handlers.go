@@ -0,0 +1,107 @@
+package Handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/pricing"
+	"github.com/andrescris/products/pkg/repository"
+	"github.com/gin-gonic/gin"
+)
+
+type quoteLineRequest struct {
+	ProductID   string `json:"productId"`
+	VariationID string `json:"variationId"`
+	SKU         string `json:"sku"`
+	Quantity    int    `json:"quantity"`
+}
+
+type quoteRequest struct {
+	Lines []quoteLineRequest `json:"lines"`
+}
+
+// QuoteProducts calcula precio unitario, tramo aplicado y total de cada línea
+// con los mismos precios que ve la sesión en GetProductByID, y el desglose de
+// impuestos de la región (parámetro region) si el proyecto tiene tabla.
+func (h *ProductHandler) QuoteProducts(c *gin.Context) {
+	userSubdomain, exists := c.Get("subdomain")
+	if !exists {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied. Subdomain context is required."})
+		return
+	}
+	subdomain := userSubdomain.(string)
+
+	var req quoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if len(req.Lines) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one line is required."})
+		return
+	}
+	for i, line := range req.Lines {
+		if line.ProductID == "" || (line.SKU == "" && line.VariationID == "") || line.Quantity <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Each line requires productId, sku or variationId, and a positive quantity.", "line": i})
+			return
+		}
+	}
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	priceList := priceListForRequest(c, h.priceLists, subdomain)
+	taxes := newTaxTables(c, h.taxes)
+	products := map[string]*models.Product{}
+
+	lines := make([]pricing.QuoteLine, 0, len(req.Lines))
+	for i, reqLine := range req.Lines {
+		product, ok := products[reqLine.ProductID]
+		if !ok {
+			loaded, err := h.repo.Get(ctx, reqLine.ProductID)
+			if errors.Is(err, repository.ErrNotFound) || (err == nil && loaded.Subdomain != subdomain) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Product not found", "line": i})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load product", "details": err.Error()})
+				return
+			}
+			product = loaded
+			products[reqLine.ProductID] = product
+		}
+
+		line, err := pricing.QuoteSKU(product, reqLine.VariationID, reqLine.SKU, reqLine.Quantity, priceList, now)
+		switch {
+		case errors.Is(err, pricing.ErrUnknownSKU):
+			c.JSON(http.StatusNotFound, gin.H{"error": "SKU not found in product", "line": i})
+			return
+		case errors.Is(err, pricing.ErrUnavailable):
+			c.JSON(http.StatusConflict, gin.H{"error": "The product or variation is not available.", "line": i})
+			return
+		}
+		if len(lines) > 0 && line.Currency != lines[0].Currency {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "All lines must use the same currency.", "line": i})
+			return
+		}
+
+		table, region, ok := taxes.forProject(c, product.ProjectID, product.Subdomain)
+		if !ok {
+			return
+		}
+		if table != nil {
+			pricing.ApplyLineTax(&line, product.TaxCategory, table, region)
+		}
+
+		lines = append(lines, line)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": gin.H{
+		"lines":    lines,
+		"currency": lines[0].Currency,
+		"subtotal": pricing.Subtotal(lines),
+		"tax":      pricing.TaxTotals(lines),
+	}})
+}
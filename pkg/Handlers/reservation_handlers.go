@@ -0,0 +1,175 @@
+package Handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/andrescris/products/pkg/middleware"
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/pricing"
+	"github.com/andrescris/products/pkg/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultReservationTTL = 15 * time.Minute
+	maxReservationTTL     = 24 * time.Hour
+)
+
+// ReservationHandler expone la reserva atómica de stock para el checkout.
+type ReservationHandler struct {
+	repo repository.ReservationRepository
+}
+
+// NewReservationHandler crea los handlers sobre el repositorio indicado.
+func NewReservationHandler(repo repository.ReservationRepository) *ReservationHandler {
+	return &ReservationHandler{repo: repo}
+}
+
+type createReservationRequest struct {
+	Subdomain  string                   `json:"subdomain"`
+	TTLSeconds int                      `json:"ttlSeconds"`
+	Lines      []models.ReservationLine `json:"lines"`
+}
+
+// respondReservationError traduce los errores de reservas a respuestas HTTP.
+func respondReservationError(c *gin.Context, err error, message string) {
+	var shortage *repository.InsufficientStockError
+	switch {
+	case errors.As(err, &shortage):
+		c.JSON(http.StatusConflict, gin.H{"error": "Insufficient stock for one or more lines.", "shortages": shortage.Shortages})
+	case errors.Is(err, repository.ErrReservationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reservation not found"})
+	case errors.Is(err, repository.ErrReservationClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": "Reservation is no longer active."})
+	case errors.Is(err, repository.ErrReservationExpired):
+		c.JSON(http.StatusConflict, gin.H{"error": "Reservation has expired. Its stock is being released."})
+	case errors.Is(err, repository.ErrNotFound), errors.Is(err, repository.ErrVariationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product or variation not found", "details": err.Error()})
+	case errors.Is(err, pricing.ErrUnavailable):
+		c.JSON(http.StatusConflict, gin.H{"error": "The product or variation is not available.", "details": err.Error()})
+	case errors.Is(err, repository.ErrBundleStock), errors.Is(err, repository.ErrBundleCycle):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message, "details": err.Error()})
+	}
+}
+
+func (h *ReservationHandler) CreateReservation(c *gin.Context) {
+	var req createReservationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	if len(req.Lines) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one line is required."})
+		return
+	}
+	for i, line := range req.Lines {
+		if line.ProductID == "" || (line.VariationID == "" && line.SKU == "") || line.Quantity <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Each line requires productId, variationId or sku, and a positive quantity.", "line": i})
+			return
+		}
+	}
+
+	ttl := defaultReservationTTL
+	if req.TTLSeconds > 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+	}
+	if ttl > maxReservationTTL {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ttlSeconds cannot exceed 86400."})
+		return
+	}
+
+	allowedSubdomains, ok := getSubdomainsFromContext(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Could not verify user permissions."})
+		return
+	}
+	middleware.SetAuditSubdomain(c, req.Subdomain)
+	if !isSubdomainAllowed(allowedSubdomains, req.Subdomain) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to reserve stock in this subdomain."})
+		return
+	}
+
+	now := time.Now().UTC()
+	reservation := models.Reservation{
+		ID:        "res-" + uuid.New().String(),
+		Subdomain: req.Subdomain,
+		Status:    models.ReservationActive,
+		Lines:     req.Lines,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	middleware.AddAuditTarget(c, "reservationId", reservation.ID)
+
+	if err := h.repo.Reserve(stockContext(c, models.MovementReservation), &reservation); err != nil {
+		respondReservationError(c, err, "Failed to reserve stock")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "Stock reserved successfully", "data": reservation})
+}
+
+func (h *ReservationHandler) GetReservation(c *gin.Context) {
+	reservation, ok := h.loadAuthorizedReservation(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": reservation})
+}
+
+func (h *ReservationHandler) CommitReservation(c *gin.Context) {
+	if _, ok := h.loadAuthorizedReservation(c); !ok {
+		return
+	}
+
+	reservation, err := h.repo.CommitReservation(context.Background(), c.Param("reservationId"), time.Now().UTC())
+	if err != nil {
+		respondReservationError(c, err, "Failed to commit reservation")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Reservation committed successfully", "data": reservation})
+}
+
+func (h *ReservationHandler) ReleaseReservation(c *gin.Context) {
+	if _, ok := h.loadAuthorizedReservation(c); !ok {
+		return
+	}
+
+	reservation, err := h.repo.ReleaseReservation(stockContext(c, models.MovementReservationRelease), c.Param("reservationId"))
+	if err != nil {
+		respondReservationError(c, err, "Failed to release reservation")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Reservation released successfully", "data": reservation})
+}
+
+// loadAuthorizedReservation obtiene la reserva y verifica que pertenezca a un
+// subdominio permitido para la API Key. Si no, responde y devuelve ok=false.
+func (h *ReservationHandler) loadAuthorizedReservation(c *gin.Context) (*models.Reservation, bool) {
+	reservation, err := h.repo.GetReservation(context.Background(), c.Param("reservationId"))
+	if err != nil {
+		respondReservationError(c, err, "Failed to load reservation")
+		return nil, false
+	}
+
+	allowedSubdomains, ok := getSubdomainsFromContext(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Could not verify user permissions."})
+		return nil, false
+	}
+	middleware.SetAuditSubdomain(c, reservation.Subdomain)
+	if !isSubdomainAllowed(allowedSubdomains, reservation.Subdomain) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to access this reservation."})
+		return nil, false
+	}
+	return reservation, true
+}
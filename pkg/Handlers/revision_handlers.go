@@ -0,0 +1,199 @@
+package Handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/pricing"
+	"github.com/andrescris/products/pkg/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// RevisionHandler expone el historial de revisiones de los productos.
+type RevisionHandler struct {
+	products   repository.ProductRepository
+	revisions  repository.RevisionRepository
+	categories repository.CategoryRepository
+}
+
+// NewRevisionHandler crea los handlers del historial.
+func NewRevisionHandler(products repository.ProductRepository, revisions repository.RevisionRepository, categories repository.CategoryRepository) *RevisionHandler {
+	return &RevisionHandler{products: products, revisions: revisions, categories: categories}
+}
+
+// ListRevisions lista las revisiones del producto, de la más reciente a la
+// más antigua, con quién, cuándo, desde qué endpoint y qué campos cambió.
+func (h *RevisionHandler) ListRevisions(c *gin.Context) {
+	productID := c.Param("id")
+
+	limit, offset, ok := pagination(c)
+	if !ok {
+		return
+	}
+	if _, ok := authorizeProduct(c, h.products, productID); !ok {
+		return
+	}
+
+	revisions, err := h.revisions.ListRevisions(context.Background(), productID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list revisions", "details": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"success": true,
+		"count":   len(revisions),
+		"limit":   limit,
+		"offset":  offset,
+		"data":    revisions,
+	}
+	if len(revisions) == limit {
+		response["nextOffset"] = offset + limit
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetRevision devuelve una revisión con la foto completa del producto.
+func (h *RevisionHandler) GetRevision(c *gin.Context) {
+	revision, ok := h.loadRevision(c, c.Param("rev"))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": revision})
+}
+
+// DiffRevisions compara la revisión :rev con la indicada en ?to= (por
+// defecto, la versión actual del producto).
+func (h *RevisionHandler) DiffRevisions(c *gin.Context) {
+	from, ok := h.loadRevision(c, c.Param("rev"))
+	if !ok {
+		return
+	}
+	to := c.Query("to")
+	if to == "" {
+		product, err := h.products.Get(context.Background(), from.ProductID)
+		if err != nil {
+			respondRepositoryError(c, err, "Failed to load product")
+			return
+		}
+		to = strconv.FormatInt(product.Version, 10)
+	}
+	target, ok := h.loadRevision(c, to)
+	if !ok {
+		return
+	}
+
+	changes := repository.DiffDocs(from.Snapshot, target.Snapshot)
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": gin.H{
+		"from":    from.Revision,
+		"to":      target.Revision,
+		"changes": changes,
+	}})
+}
+
+// RollbackRevision devuelve el contenido del producto al de la revisión :rev
+// como una revisión nueva. Respeta If-Match y conserva el stock, el estado,
+// la papelera y las variaciones creadas después (ver RestoreRevision). Si el
+// resultado ya no es válido (categoría desaparecida, bundle roto, atributos
+// fuera del esquema) responde 409 sin escribir.
+func (h *RevisionHandler) RollbackRevision(c *gin.Context) {
+	expectedVersion, ok := expectedVersionFromRequest(c)
+	if !ok {
+		return
+	}
+	revision, ok := h.loadRevision(c, c.Param("rev"))
+	if !ok {
+		return
+	}
+
+	ctx := stockContext(c, models.MovementAdjustment)
+	product, err := h.products.Get(ctx, revision.ProductID)
+	if err != nil {
+		respondRepositoryError(c, err, "Failed to load product")
+		return
+	}
+	if expectedVersion != repository.AnyVersion && product.Version != expectedVersion {
+		respondRepositoryError(c, repository.ErrVersionConflict, "Failed to roll back product")
+		return
+	}
+	restored, err := repository.RestoreRevision(product, revision)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore revision", "details": err.Error()})
+		return
+	}
+	if err := h.validateRollback(ctx, restored); err != nil {
+		err.respond(c)
+		return
+	}
+
+	updated, err := h.products.Mutate(ctx, revision.ProductID, expectedVersion, repository.Rollback(restored))
+	if err != nil {
+		respondRepositoryError(c, err, "Failed to roll back product")
+		return
+	}
+
+	setProductETag(c, updated)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Product rolled back to revision " + strconv.FormatInt(revision.Revision, 10),
+		"data":    updated,
+	})
+}
+
+// validateRollback repite sobre el producto restaurado las validaciones de
+// una actualización: la categoría se resuelve de nuevo (su ruta pudo
+// cambiar), el bundle y los atributos deben seguir siendo válidos.
+func (h *RevisionHandler) validateRollback(ctx context.Context, restored *models.Product) *requestError {
+	conflict := func(details interface{}) *requestError {
+		return &requestError{http.StatusConflict, gin.H{"error": "The revision can no longer be restored.", "details": details}}
+	}
+
+	restored.CategoryIDs = nil
+	if restored.CategoryID != "" {
+		category, err := resolveCategory(ctx, h.categories, restored.ProjectID, restored.CategoryID)
+		if err != nil {
+			return conflict(err.Error())
+		}
+		repository.SetProductCategory(restored, category)
+	}
+	if err := pricing.ValidateProduct(restored); err != nil {
+		return conflict(err.Error())
+	}
+	if err := repository.ValidateBundle(ctx, h.products, restored); err != nil {
+		if errors.Is(err, repository.ErrInvalidBundle) || errors.Is(err, repository.ErrBundleCycle) || errors.Is(err, repository.ErrBundleStock) {
+			return conflict(err.Error())
+		}
+		return repositoryError(err, "Failed to validate bundle")
+	}
+	if err := validateVariationAttributes(h.categories, restored, restored.Variations...); err != nil {
+		if err.status != http.StatusUnprocessableEntity {
+			return err
+		}
+		return conflict(err.body)
+	}
+	return nil
+}
+
+// loadRevision comprueba los permisos sobre el producto :id y carga la
+// revisión indicada.
+func (h *RevisionHandler) loadRevision(c *gin.Context, rev string) (*models.ProductRevision, bool) {
+	productID := c.Param("id")
+	number, err := strconv.ParseInt(rev, 10, 64)
+	if err != nil || number <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Revision must be a positive integer."})
+		return nil, false
+	}
+	if _, ok := authorizeProduct(c, h.products, productID); !ok {
+		return nil, false
+	}
+
+	revision, err := h.revisions.GetRevision(context.Background(), productID, number)
+	if err != nil {
+		respondRepositoryError(c, err, "Failed to load revision")
+		return nil, false
+	}
+	return revision, true
+}
@@ -0,0 +1,59 @@
+package Handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+func TestRevisionDiffAndRollback(t *testing.T) {
+	api := newTestAPI(t)
+	ctx := context.Background()
+	category := &models.Category{ID: "cat-1", ProjectID: "proj", Subdomain: "s", Name: "Mugs", Slug: "mugs"}
+	if err := api.store.CreateCategory(ctx, category); err != nil {
+		t.Fatal(err)
+	}
+	body := simpleProductBody("A")
+	body["categoryId"] = "cat-1"
+	product := createProduct(t, api, body)
+	path := "/api/v1/products/" + product.ID
+	expect(t, api.do(http.MethodPatch, path, `{"name": "Renamed"}`, "If-Match", etag(1)), http.StatusOK, nil)
+
+	var diff struct {
+		From    int64                `json:"from"`
+		To      int64                `json:"to"`
+		Changes []models.FieldChange `json:"changes"`
+	}
+	expect(t, api.do(http.MethodGet, path+"/revisions/1/diff", nil), http.StatusOK, &diff)
+	if diff.From != 1 || diff.To != 2 || len(diff.Changes) != 1 || diff.Changes[0].Field != "name" {
+		t.Fatalf("diff = %+v", diff)
+	}
+	expect(t, api.do(http.MethodGet, path+"/revisions/1/diff", nil, "X-Test-Subdomain", "other"), http.StatusForbidden, nil)
+	expect(t, api.do(http.MethodGet, path+"/revisions/9/diff", nil), http.StatusNotFound, nil)
+
+	expect(t, api.do(http.MethodPost, path+"/revisions/1/rollback", nil, "If-Match", etag(1)), http.StatusPreconditionFailed, nil)
+	expect(t, api.do(http.MethodPost, path+"/revisions/1/rollback", nil, "X-Test-Subdomain", "other"), http.StatusForbidden, nil)
+
+	var rolledBack models.Product
+	rec := api.do(http.MethodPost, path+"/revisions/1/rollback", nil, "If-Match", etag(2))
+	expect(t, rec, http.StatusOK, &rolledBack)
+	if rolledBack.Name != "Tee A" || rolledBack.Version != 3 || rec.Header().Get("ETag") != etag(3) {
+		t.Fatalf("rolled back product = %+v", rolledBack)
+	}
+	if rolledBack.Category != "mugs" || len(rolledBack.CategoryIDs) != 1 {
+		t.Errorf("category = %q %v, want it resolved again", rolledBack.Category, rolledBack.CategoryIDs)
+	}
+
+	// Sin la categoría la revisión ya no se puede restaurar.
+	if err := api.store.DeleteCategory(ctx, "cat-1"); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, api.do(http.MethodPost, path+"/revisions/2/rollback", nil), http.StatusConflict, nil)
+	var current models.Product
+	expect(t, api.do(http.MethodGet, path, nil), http.StatusOK, &current)
+	if current.Version != 3 || current.Name != "Tee A" {
+		t.Errorf("a rejected rollback wrote the product: %+v", current)
+	}
+}
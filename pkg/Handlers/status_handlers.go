@@ -0,0 +1,78 @@
+package Handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/repository"
+	"github.com/gin-gonic/gin"
+)
+
+type publishRequest struct {
+	// PublishAt programa la publicación; vacío o en el pasado publica ya.
+	PublishAt *time.Time `json:"publishAt"`
+}
+
+// PublishProduct publica el producto o, con publishAt futuro, programa la
+// publicación del borrador.
+func (h *ProductHandler) PublishProduct(c *gin.Context) {
+	var request publishRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+			return
+		}
+	}
+
+	if request.PublishAt != nil && request.PublishAt.After(time.Now()) {
+		h.changeStatus(c, "Product publishing scheduled", repository.SchedulePublish(*request.PublishAt))
+		return
+	}
+	h.changeStatus(c, "Product published successfully", repository.SetStatus(models.StatusPublished))
+}
+
+// UnpublishProduct devuelve el producto a borrador (también desde archivado)
+// y cancela una publicación programada.
+func (h *ProductHandler) UnpublishProduct(c *gin.Context) {
+	h.changeStatus(c, "Product moved to draft", func(product *models.Product) error {
+		if err := repository.SetStatus(models.StatusDraft)(product); err != nil {
+			return err
+		}
+		product.PublishAt = nil
+		return nil
+	})
+}
+
+// ArchiveProduct retira el producto de la tienda.
+func (h *ProductHandler) ArchiveProduct(c *gin.Context) {
+	h.changeStatus(c, "Product archived successfully", repository.SetStatus(models.StatusArchived))
+}
+
+// changeStatus aplica la transición sobre los datos más recientes del
+// producto, respetando If-Match.
+func (h *ProductHandler) changeStatus(c *gin.Context, message string, fn func(*models.Product) error) {
+	productID := c.Param("id")
+
+	expectedVersion, ok := expectedVersionFromRequest(c)
+	if !ok {
+		return
+	}
+	if _, ok := authorizeProduct(c, h.repo, productID); !ok {
+		return
+	}
+
+	updated, err := h.repo.Mutate(stockContext(c, models.MovementAdjustment), productID, expectedVersion, fn)
+	if err != nil {
+		respondRepositoryError(c, err, "Failed to change product status")
+		return
+	}
+
+	setProductETag(c, updated)
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": message, "data": gin.H{
+		"id":        updated.ID,
+		"status":    updated.Status,
+		"active":    updated.Active,
+		"publishAt": updated.PublishAt,
+	}})
+}
@@ -0,0 +1,191 @@
+package Handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/andrescris/products/pkg/middleware"
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/repository"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// StockHandler expone los ajustes manuales de stock y el libro de movimientos.
+type StockHandler struct {
+	products  repository.ProductRepository
+	movements repository.StockMovementRepository
+	locations repository.LocationRepository
+}
+
+// NewStockHandler crea los handlers de stock.
+func NewStockHandler(products repository.ProductRepository, movements repository.StockMovementRepository, locations repository.LocationRepository) *StockHandler {
+	return &StockHandler{products: products, movements: movements, locations: locations}
+}
+
+type stockAdjustmentRequest struct {
+	VariationID string                `json:"variationId"`
+	SKU         string                `json:"sku"`
+	LocationID  string                `json:"locationId"`
+	Delta       int                   `json:"delta"`
+	Reason      models.MovementReason `json:"reason"`
+	Note        string                `json:"note"`
+}
+
+// manualReasons son los motivos que se pueden registrar desde la API. Los
+// demás los genera el propio servicio (reservas, stock inicial).
+var manualReasons = map[models.MovementReason]bool{
+	models.MovementAdjustment: true,
+	models.MovementSale:       true,
+	models.MovementReturn:     true,
+	models.MovementImport:     true,
+}
+
+// stockContext prepara el context de una escritura para que los movimientos
+// de stock y la revisión que produzca queden atribuidos al autor y al
+// endpoint de la petición.
+func stockContext(c *gin.Context, reason models.MovementReason) context.Context {
+	return repository.WithStockChange(context.Background(), repository.StockChange{
+		Reason: reason,
+		Actor:  middleware.ActorFromContext(c),
+		Source: requestSource(c),
+	})
+}
+
+// requestSource identifica el endpoint de la petición, p. ej. "PATCH /api/v1/products/:id".
+func requestSource(c *gin.Context) string {
+	return c.Request.Method + " " + c.FullPath()
+}
+
+// pagination lee limit y offset de la query string.
+func pagination(c *gin.Context) (limit, offset int, ok bool) {
+	limit, offset = defaultPageSize, 0
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 || n > maxPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500."})
+			return 0, 0, false
+		}
+		limit = n
+	}
+	if value := c.Query("offset"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a non-negative integer."})
+			return 0, 0, false
+		}
+		offset = n
+	}
+	return limit, offset, true
+}
+
+// authorizeProduct carga el producto y verifica que la API Key tenga acceso a
+// su subdominio. Si no, responde y devuelve ok=false.
+func authorizeProduct(c *gin.Context, repo repository.ProductRepository, productID string) (*models.Product, bool) {
+	product, err := repo.Get(context.Background(), productID)
+	if err != nil {
+		respondRepositoryError(c, err, "Failed to load product")
+		return nil, false
+	}
+
+	allowedSubdomains, ok := getSubdomainsFromContext(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Could not verify user permissions."})
+		return nil, false
+	}
+	middleware.SetAuditSubdomain(c, product.Subdomain)
+	if !isSubdomainAllowed(allowedSubdomains, product.Subdomain) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to access resources in this subdomain."})
+		return nil, false
+	}
+	return product, true
+}
+
+func (h *StockHandler) AdjustStock(c *gin.Context) {
+	productID := c.Param("id")
+
+	expectedVersion, ok := expectedVersionFromRequest(c)
+	if !ok {
+		return
+	}
+
+	var req stockAdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if req.Reason == "" {
+		req.Reason = models.MovementAdjustment
+	}
+	if req.Delta == 0 || !manualReasons[req.Reason] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A non-zero delta is required and reason must be one of: adjustment, sale, return, import."})
+		return
+	}
+
+	product, ok := authorizeProduct(c, h.products, productID)
+	if !ok {
+		return
+	}
+	if req.LocationID != "" {
+		if err := validateLocations(context.Background(), h.locations, product.ProjectID, []string{req.LocationID}); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	ctx := repository.WithStockChange(context.Background(), repository.StockChange{
+		Reason: req.Reason,
+		Actor:  middleware.ActorFromContext(c),
+		Note:   req.Note,
+		Source: requestSource(c),
+	})
+	updated, err := repository.AdjustStock(ctx, h.products, productID, expectedVersion, req.VariationID, req.SKU, req.LocationID, req.Delta)
+	if err != nil {
+		var shortage *repository.InsufficientStockError
+		if errors.As(err, &shortage) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Stock cannot become negative.", "shortages": shortage.Shortages})
+			return
+		}
+		respondRepositoryError(c, err, "Failed to adjust stock")
+		return
+	}
+
+	setProductETag(c, updated)
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Stock adjusted successfully", "data": updated})
+}
+
+func (h *StockHandler) ListStockMovements(c *gin.Context) {
+	productID := c.Param("id")
+
+	limit, offset, ok := pagination(c)
+	if !ok {
+		return
+	}
+	if _, ok := authorizeProduct(c, h.products, productID); !ok {
+		return
+	}
+
+	movements, err := h.movements.ListStockMovements(context.Background(), productID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list stock movements", "details": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"success": true,
+		"count":   len(movements),
+		"limit":   limit,
+		"offset":  offset,
+		"data":    movements,
+	}
+	if len(movements) == limit {
+		response["nextOffset"] = offset + limit
+	}
+	c.JSON(http.StatusOK, response)
+}
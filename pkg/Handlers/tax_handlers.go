@@ -0,0 +1,158 @@
+package Handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/andrescris/products/pkg/middleware"
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/pricing"
+	"github.com/andrescris/products/pkg/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// TaxHandler gestiona la tabla de impuestos de cada proyecto.
+type TaxHandler struct {
+	taxes    repository.TaxTableRepository
+	products repository.ProductRepository
+}
+
+// NewTaxHandler crea los handlers de impuestos. products se usa para
+// comprobar a quién pertenece el proyecto.
+func NewTaxHandler(taxes repository.TaxTableRepository, products repository.ProductRepository) *TaxHandler {
+	return &TaxHandler{taxes: taxes, products: products}
+}
+
+func (h *TaxHandler) GetTaxTable(c *gin.Context) {
+	table, err := h.taxes.GetTaxTable(context.Background(), c.Param("projectId"))
+	if err != nil {
+		respondTaxTableError(c, err, "Failed to load tax table")
+		return
+	}
+
+	allowedSubdomains, ok := getSubdomainsFromContext(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Could not verify user permissions."})
+		return
+	}
+	middleware.SetAuditSubdomain(c, table.Subdomain)
+	if !isSubdomainAllowed(allowedSubdomains, table.Subdomain) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to access this resource."})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": table})
+}
+
+// PutTaxTable reemplaza la tabla completa del proyecto.
+func (h *TaxHandler) PutTaxTable(c *gin.Context) {
+	projectID := c.Param("projectId")
+	ctx := context.Background()
+
+	var table models.TaxTable
+	if err := c.ShouldBindJSON(&table); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	table.ProjectID = projectID
+	if err := pricing.ValidateTaxTable(&table); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	allowedSubdomains, ok := getSubdomainsFromContext(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Could not verify user permissions."})
+		return
+	}
+	middleware.SetAuditSubdomain(c, table.Subdomain)
+	if !isSubdomainAllowed(allowedSubdomains, table.Subdomain) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to modify resources in this subdomain."})
+		return
+	}
+
+	existing, err := h.taxes.GetTaxTable(ctx, projectID)
+	if err != nil && !errors.Is(err, repository.ErrTaxTableNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load tax table", "details": err.Error()})
+		return
+	}
+	if existing != nil && !isSubdomainAllowed(allowedSubdomains, existing.Subdomain) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to modify resources in this subdomain."})
+		return
+	}
+	if !authorizeProject(c, h.products, projectID, table.Subdomain, allowedSubdomains) {
+		return
+	}
+
+	table.UpdatedAt = time.Now().UTC()
+	if err := h.taxes.SaveTaxTable(ctx, &table); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save tax table", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Tax table saved successfully", "data": table})
+}
+
+func respondTaxTableError(c *gin.Context, err error, message string) {
+	if errors.Is(err, repository.ErrTaxTableNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tax table not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message, "details": err.Error()})
+}
+
+// taxTables carga las tablas de impuestos por proyecto durante una petición.
+type taxTables struct {
+	repo   repository.TaxTableRepository
+	region string
+	tables map[string]*models.TaxTable
+}
+
+func newTaxTables(c *gin.Context, repo repository.TaxTableRepository) *taxTables {
+	return &taxTables{repo: repo, region: c.Query("region"), tables: map[string]*models.TaxTable{}}
+}
+
+// forProject devuelve la tabla del proyecto y la región a usar para un
+// producto de subdomain. Una tabla nula significa que el proyecto no tiene
+// impuestos configurados; una tabla de otro subdominio no se usa. Si hay un
+// error ya se respondió y ok es false.
+func (t *taxTables) forProject(c *gin.Context, projectID, subdomain string) (table *models.TaxTable, region string, ok bool) {
+	table, cached := t.tables[projectID]
+	if !cached {
+		loaded, err := t.repo.GetTaxTable(context.Background(), projectID)
+		if err != nil && !errors.Is(err, repository.ErrTaxTableNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load tax table", "details": err.Error()})
+			return nil, "", false
+		}
+		table = loaded
+		t.tables[projectID] = table
+	}
+	if table == nil || table.Subdomain != subdomain {
+		return nil, "", true
+	}
+
+	region, err := pricing.TaxRegion(table, t.region)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, "", false
+	}
+	return table, region, true
+}
+
+// applyTaxes añade el desglose neto/impuesto/bruto a los productos cuyo
+// proyecto tiene tabla de impuestos.
+func applyTaxes(c *gin.Context, repo repository.TaxTableRepository, products ...*models.Product) bool {
+	tables := newTaxTables(c, repo)
+	for _, product := range products {
+		table, region, ok := tables.forProject(c, product.ProjectID, product.Subdomain)
+		if !ok {
+			return false
+		}
+		if table != nil {
+			pricing.ApplyTaxes(product, table, region)
+		}
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package Handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+func TestPutTaxTableChecksProjectOwner(t *testing.T) {
+	tests := []struct {
+		name      string
+		subdomain string
+		status    int
+	}{
+		{"project owner", "s", http.StatusOK},
+		{"another subdomain", "other", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newTestAPI(t)
+			createProduct(t, api, simpleProductBody("A"))
+			body := map[string]interface{}{"subdomain": tt.subdomain, "defaultRegion": "CO", "regions": map[string]interface{}{"CO": map[string]float64{"default": 19}}}
+			expect(t, api.do(http.MethodPut, "/api/v1/tax-rates/proj", body, "X-Test-Subdomain", tt.subdomain), tt.status, nil)
+			_, err := api.store.GetTaxTable(context.Background(), "proj")
+			if saved := err == nil; saved != (tt.status == http.StatusOK) {
+				t.Errorf("table saved = %v, want %v", saved, tt.status == http.StatusOK)
+			}
+		})
+	}
+}
+
+func TestTaxesIgnoreOtherSubdomainTable(t *testing.T) {
+	tests := []struct {
+		name      string
+		subdomain string
+		taxed     bool
+	}{
+		{"own table", "s", true},
+		{"table of another subdomain", "other", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newTestAPI(t)
+			product := createProduct(t, api, simpleProductBody("A"))
+			table := &models.TaxTable{ProjectID: "proj", Subdomain: tt.subdomain, DefaultRegion: "CO", Regions: map[string]map[string]float64{"CO": {"default": 19}}}
+			if err := api.store.SaveTaxTable(context.Background(), table); err != nil {
+				t.Fatal(err)
+			}
+			var got models.Product
+			expect(t, api.do(http.MethodGet, "/api/v1/products/"+product.ID, nil), http.StatusOK, &got)
+			if taxed := got.Tax != nil; taxed != tt.taxed {
+				t.Errorf("tax breakdown = %+v, want present %v", got.Tax, tt.taxed)
+			}
+		})
+	}
+}
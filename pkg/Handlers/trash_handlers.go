@@ -0,0 +1,99 @@
+package Handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/andrescris/firestore/lib/firebase"
+	"github.com/andrescris/products/pkg/middleware"
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// ListTrash lista los productos eliminados de un subdominio, del más
+// reciente al más antiguo, con la fecha y el autor de la baja.
+func (h *ProductHandler) ListTrash(c *gin.Context) {
+	subdomain := c.Query("subdomain")
+	if subdomain == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The subdomain query parameter is required."})
+		return
+	}
+	limit, offset, ok := pagination(c)
+	if !ok {
+		return
+	}
+
+	allowedSubdomains, ok := getSubdomainsFromContext(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Could not verify user permissions."})
+		return
+	}
+	middleware.SetAuditSubdomain(c, subdomain)
+	if !isSubdomainAllowed(allowedSubdomains, subdomain) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to access resources in this subdomain."})
+		return
+	}
+
+	// Ordenar por deletedAt deja fuera los productos que no están en la papelera.
+	products, err := h.repo.Query(context.Background(), firebase.QueryOptions{
+		Filters:  []firebase.QueryFilter{{Field: "subdomain", Operator: "==", Value: subdomain}},
+		OrderBy:  "deletedAt",
+		OrderDir: "desc",
+		Limit:    limit,
+		Offset:   offset,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list trash", "details": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"success": true,
+		"count":   len(products),
+		"limit":   limit,
+		"offset":  offset,
+		"data":    products,
+	}
+	if len(products) == limit {
+		response["nextOffset"] = offset + limit
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// RestoreProduct saca el producto de la papelera como borrador.
+func (h *ProductHandler) RestoreProduct(c *gin.Context) {
+	h.restore(c, "Product restored as draft", func(ctx context.Context, productID string, expectedVersion int64) (*models.Product, error) {
+		return h.repo.Mutate(ctx, productID, expectedVersion, repository.RestoreProduct)
+	})
+}
+
+// RestoreVariation reactiva una variación eliminada.
+func (h *ProductHandler) RestoreVariation(c *gin.Context) {
+	variationID := c.Param("variationId")
+	h.restore(c, "Variation restored successfully", func(ctx context.Context, productID string, expectedVersion int64) (*models.Product, error) {
+		return h.repo.UpdateVariation(ctx, productID, variationID, expectedVersion, repository.RestoreVariation)
+	})
+}
+
+// restore comprueba permisos y ejecuta la escritura de write, respetando If-Match.
+func (h *ProductHandler) restore(c *gin.Context, message string, write func(ctx context.Context, productID string, expectedVersion int64) (*models.Product, error)) {
+	productID := c.Param("id")
+
+	expectedVersion, ok := expectedVersionFromRequest(c)
+	if !ok {
+		return
+	}
+	if _, ok := authorizeProduct(c, h.repo, productID); !ok {
+		return
+	}
+
+	updated, err := write(stockContext(c, models.MovementAdjustment), productID, expectedVersion)
+	if err != nil {
+		respondRepositoryError(c, err, "Failed to restore")
+		return
+	}
+
+	setProductETag(c, updated)
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": message, "data": updated})
+}
@@ -0,0 +1,83 @@
+package Handlers
+
+import (
+	"net/http"
+
+	"github.com/andrescris/products/pkg/middleware"
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/repository"
+	"github.com/gin-gonic/gin"
+)
+
+type generateVariationsRequest struct {
+	repository.VariationTemplate
+	// DryRun solo devuelve las variaciones que se crearían.
+	DryRun bool `json:"dryRun"`
+}
+
+// GenerateVariations crea las variaciones que faltan para completar la matriz
+// de opciones del producto, con la plantilla de SKU, el precio y el stock
+// indicados.
+func (h *ProductHandler) GenerateVariations(c *gin.Context) {
+	productID := c.Param("id")
+	ctx := stockContext(c, models.MovementInitial)
+
+	expectedVersion, ok := expectedVersionFromRequest(c)
+	if !ok {
+		return
+	}
+
+	var request generateVariationsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if request.Price <= 0 || request.Stock < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "price must be positive and stock cannot be negative."})
+		return
+	}
+
+	product, err := h.repo.Get(ctx, productID)
+	if err != nil {
+		respondRepositoryError(c, err, "Failed to load product")
+		return
+	}
+
+	allowedSubdomains, ok := getSubdomainsFromContext(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Could not verify user permissions."})
+		return
+	}
+	middleware.SetAuditSubdomain(c, product.Subdomain)
+	if !isSubdomainAllowed(allowedSubdomains, product.Subdomain) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to modify resources in this subdomain."})
+		return
+	}
+
+	planned, err := repository.PlanVariations(product, request.VariationTemplate)
+	if err != nil {
+		respondRepositoryError(c, err, "Failed to plan variations")
+		return
+	}
+	if !checkVariationAttributes(c, h.categories, product, planned...) {
+		return
+	}
+
+	if request.DryRun {
+		c.JSON(http.StatusOK, gin.H{"success": true, "dryRun": true, "count": len(planned), "data": planned})
+		return
+	}
+	if len(planned) == 0 {
+		c.JSON(http.StatusOK, gin.H{"success": true, "message": "All option combinations already exist", "count": 0, "data": planned})
+		return
+	}
+
+	updated, created, err := repository.GenerateVariations(ctx, h.repo, productID, expectedVersion, request.VariationTemplate)
+	if err != nil {
+		respondRepositoryError(c, err, "Failed to generate variations")
+		return
+	}
+
+	setProductETag(c, updated)
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "Variations generated successfully", "count": len(created), "data": created})
+}
@@ -0,0 +1,294 @@
+package Handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/andrescris/products/pkg/middleware"
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// WebhookHandler gestiona las suscripciones de webhooks de cada subdominio y
+// el historial de sus envíos.
+type WebhookHandler struct {
+	webhooks repository.WebhookRepository
+}
+
+// NewWebhookHandler crea los handlers de webhooks.
+func NewWebhookHandler(webhooks repository.WebhookRepository) *WebhookHandler {
+	return &WebhookHandler{webhooks: webhooks}
+}
+
+// newWebhookSecret genera el secreto con el que se firman los envíos.
+func newWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var webhook models.Webhook
+	if err := c.ShouldBindJSON(&webhook); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if webhook.Subdomain == "" || webhook.URL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required fields: subdomain and url are required."})
+		return
+	}
+	if err := repository.ValidateWebhook(&webhook); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := repository.CheckWebhookHost(c.Request.Context(), webhook.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	allowedSubdomains, ok := getSubdomainsFromContext(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Could not verify user permissions."})
+		return
+	}
+	middleware.SetAuditSubdomain(c, webhook.Subdomain)
+	if !isSubdomainAllowed(allowedSubdomains, webhook.Subdomain) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to create resources in this subdomain."})
+		return
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate webhook secret", "details": err.Error()})
+		return
+	}
+	now := time.Now().UTC()
+	webhook.ID = "whk-" + uuid.New().String()
+	middleware.AddAuditTarget(c, "webhookId", webhook.ID)
+	webhook.Secret = secret
+	webhook.Active = true
+	webhook.CreatedAt = now
+	webhook.UpdatedAt = now
+
+	if err := h.webhooks.CreateWebhook(context.Background(), &webhook); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook", "details": err.Error()})
+		return
+	}
+
+	// El secreto solo se devuelve en esta respuesta.
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "Webhook created successfully. Store the secret: it will not be shown again.", "data": webhook})
+}
+
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	subdomain := c.Query("subdomain")
+	if subdomain == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The subdomain query parameter is required."})
+		return
+	}
+
+	allowedSubdomains, ok := getSubdomainsFromContext(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Could not verify user permissions."})
+		return
+	}
+	if !isSubdomainAllowed(allowedSubdomains, subdomain) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to access resources in this subdomain."})
+		return
+	}
+
+	webhooks, err := h.webhooks.ListWebhooks(context.Background(), subdomain)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list webhooks", "details": err.Error()})
+		return
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "count": len(webhooks), "data": webhooks})
+}
+
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	webhook, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+	webhook.Secret = ""
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": webhook})
+}
+
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	webhook, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	var updates struct {
+		URL    *string             `json:"url"`
+		Events *[]models.EventType `json:"events"`
+		Active *bool               `json:"active"`
+	}
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format", "details": err.Error()})
+		return
+	}
+
+	if updates.URL != nil && *updates.URL != webhook.URL {
+		if err := repository.CheckWebhookHost(c.Request.Context(), *updates.URL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	updated, err := h.webhooks.UpdateWebhook(context.Background(), webhook.ID, func(w *models.Webhook) error {
+		if updates.URL != nil {
+			w.URL = *updates.URL
+		}
+		if updates.Events != nil {
+			w.Events = *updates.Events
+		}
+		if updates.Active != nil {
+			w.Active = *updates.Active
+		}
+		return repository.ValidateWebhook(w)
+	})
+	if err != nil {
+		respondWebhookError(c, err, "Failed to update webhook")
+		return
+	}
+	updated.Secret = ""
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Webhook updated successfully", "data": updated})
+}
+
+// DeleteWebhook elimina la suscripción. Los envíos pendientes pasan a dead
+// letter en el siguiente intento.
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	webhook, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+	if err := h.webhooks.DeleteWebhook(context.Background(), webhook.ID); err != nil {
+		respondWebhookError(c, err, "Failed to delete webhook")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Webhook deleted successfully"})
+}
+
+// ListDeliveries devuelve los envíos del webhook, del más reciente al más
+// antiguo. ?status= filtra por pending, delivered o dead.
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	webhook, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+	status := models.DeliveryStatus(c.Query("status"))
+	switch status {
+	case "", models.DeliveryPending, models.DeliveryDelivered, models.DeliveryDead:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, delivered or dead."})
+		return
+	}
+	limit, offset, ok := pagination(c)
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.webhooks.ListDeliveries(context.Background(), webhook.ID, status, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list webhook deliveries", "details": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"success": true,
+		"count":   len(deliveries),
+		"limit":   limit,
+		"offset":  offset,
+		"data":    deliveries,
+	}
+	if len(deliveries) == limit {
+		response["nextOffset"] = offset + limit
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// ReplayDelivery vuelve a poner en cola un envío (normalmente uno en dead
+// letter) para que el worker lo intente de inmediato con los intentos a cero.
+func (h *WebhookHandler) ReplayDelivery(c *gin.Context) {
+	webhook, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+	deliveryID := c.Param("deliveryId")
+	middleware.AddAuditTarget(c, "deliveryId", deliveryID)
+
+	ctx := context.Background()
+	delivery, err := h.webhooks.GetDelivery(ctx, deliveryID)
+	if err == nil && delivery.WebhookID != webhook.ID {
+		err = repository.ErrDeliveryNotFound
+	}
+	if err != nil {
+		respondWebhookError(c, err, "Failed to load webhook delivery")
+		return
+	}
+
+	replayed, err := h.webhooks.UpdateDelivery(ctx, deliveryID, func(d *models.WebhookDelivery) error {
+		d.Status = models.DeliveryPending
+		d.Attempts = 0
+		d.NextAttemptAt = time.Now().UTC()
+		d.DeliveredAt = nil
+		d.LastError = ""
+		d.LastStatusCode = 0
+		return nil
+	})
+	if err != nil {
+		respondWebhookError(c, err, "Failed to replay webhook delivery")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"success": true, "message": "Delivery queued for replay", "data": replayed})
+}
+
+// loadWebhook carga el webhook de la ruta y comprueba que su subdominio esté
+// permitido. Si devuelve false ya respondió.
+func (h *WebhookHandler) loadWebhook(c *gin.Context) (*models.Webhook, bool) {
+	webhook, err := h.webhooks.GetWebhook(context.Background(), c.Param("webhookId"))
+	if err != nil {
+		respondWebhookError(c, err, "Failed to load webhook")
+		return nil, false
+	}
+
+	allowedSubdomains, ok := getSubdomainsFromContext(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Could not verify user permissions."})
+		return nil, false
+	}
+	middleware.SetAuditSubdomain(c, webhook.Subdomain)
+	if !isSubdomainAllowed(allowedSubdomains, webhook.Subdomain) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to access resources in this subdomain."})
+		return nil, false
+	}
+	return webhook, true
+}
+
+func respondWebhookError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, repository.ErrWebhookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+	case errors.Is(err, repository.ErrDeliveryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook delivery not found"})
+	case errors.Is(err, repository.ErrInvalidWebhook):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message, "details": err.Error()})
+	}
+}
@@ -0,0 +1,85 @@
+// Package catalogio importa y exporta el catálogo en archivos planos (CSV),
+// con una fila por SKU.
+package catalogio
+
+import (
+	"sort"
+	"strings"
+)
+
+// Campos de una fila. Los de producto se toman de la primera fila del grupo
+// que los tenga; los de SKU son del producto simple o de cada variación.
+const (
+	// FieldParent agrupa varias filas como variaciones de un mismo producto.
+	// Vacío, la fila es un producto simple.
+	FieldParent = "parent"
+
+	FieldName        = "name"
+	FieldDescription = "description"
+	FieldBrand       = "brand"
+	FieldCategory    = "category"
+	FieldCategoryID  = "categoryId"
+	FieldTaxCategory = "taxCategory"
+	FieldCurrency    = "currency"
+	FieldStatus      = "status"
+	FieldWeight      = "weight"
+
+	FieldSKU            = "sku"
+	FieldBarcode        = "barcode"
+	FieldPrice          = "price"
+	FieldCompareAtPrice = "compareAtPrice"
+	FieldSalePrice      = "salePrice"
+	FieldStock          = "stock"
+	FieldImageURL       = "imageUrl"
+
+	// AttributePrefix mapea una columna a un atributo de variación, p. ej.
+	// "attributes.color".
+	AttributePrefix = "attributes."
+)
+
+var productFields = []string{
+	FieldName, FieldDescription, FieldBrand, FieldCategory, FieldCategoryID,
+	FieldTaxCategory, FieldCurrency, FieldStatus, FieldWeight,
+}
+
+var skuFields = []string{
+	FieldSKU, FieldBarcode, FieldPrice, FieldCompareAtPrice, FieldSalePrice,
+	FieldStock, FieldImageURL,
+}
+
+// knownField devuelve el nombre canónico del campo (sin distinguir
+// mayúsculas) y si existe.
+func knownField(name string) (string, bool) {
+	if len(name) > len(AttributePrefix) && strings.EqualFold(name[:len(AttributePrefix)], AttributePrefix) {
+		return AttributePrefix + name[len(AttributePrefix):], true
+	}
+	if strings.EqualFold(name, FieldParent) {
+		return FieldParent, true
+	}
+	for _, fields := range [][]string{productFields, skuFields} {
+		for _, field := range fields {
+			if strings.EqualFold(name, field) {
+				return field, true
+			}
+		}
+	}
+	return "", false
+}
+
+func isProductField(field string) bool {
+	for _, f := range productFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,191 @@
+package catalogio
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/andrescris/firestore/lib/firebase"
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/repository"
+)
+
+// exportBatchSize es el tamaño de página al recorrer el catálogo.
+const exportBatchSize = 200
+
+// Formatos de exportación.
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// ValidFormat indica si format es un formato de exportación conocido.
+func ValidFormat(format string) bool {
+	return format == FormatCSV || format == FormatJSONL
+}
+
+// ExportOptions configura una exportación.
+type ExportOptions struct {
+	Subdomain string
+	Format    string
+	// Status limita la exportación a un estado; vacío exporta todos.
+	Status models.ProductStatus
+}
+
+// ExportResult cuenta lo exportado.
+type ExportResult struct {
+	Products int `json:"products"`
+	// Rows son las líneas de datos escritas: una por SKU en CSV y una por
+	// producto en JSON Lines.
+	Rows int `json:"rows"`
+}
+
+// Export escribe en w los productos del subdominio, recorridos por ID en
+// páginas, sin cargar el catálogo en memoria. Los productos en la papelera y
+// las variaciones eliminadas no se exportan.
+//
+// En JSON Lines cada línea es un producto completo. En CSV cada línea es un
+// SKU con las columnas de la importación: las variaciones llevan el ID del
+// producto en parent, así que el archivo se puede volver a importar. Como la
+// cabecera necesita los nombres de todos los atributos, el CSV recorre el
+// catálogo dos veces.
+func Export(ctx context.Context, products repository.ProductRepository, w io.Writer, opts ExportOptions) (ExportResult, error) {
+	options := firebase.QueryOptions{
+		Filters: []firebase.QueryFilter{{Field: "subdomain", Operator: "==", Value: opts.Subdomain}},
+	}
+	if opts.Status != "" {
+		options.Filters = append(options.Filters, firebase.QueryFilter{Field: "status", Operator: "==", Value: string(opts.Status)})
+	}
+	each := func(fn func(*models.Product) error) error {
+		return repository.EachProduct(ctx, products, options, exportBatchSize, func(product models.Product) error {
+			if product.DeletedAt != nil {
+				return nil
+			}
+			return fn(&product)
+		})
+	}
+
+	var result ExportResult
+	switch opts.Format {
+	case FormatJSONL:
+		encoder := json.NewEncoder(w)
+		err := each(func(product *models.Product) error {
+			product.Variations = liveVariations(product.Variations)
+			result.Products++
+			result.Rows++
+			return encoder.Encode(product)
+		})
+		return result, err
+
+	case FormatCSV:
+		attributes := make(map[string]bool)
+		err := each(func(product *models.Product) error {
+			for _, v := range liveVariations(product.Variations) {
+				for name := range v.Attributes {
+					attributes[name] = true
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return result, err
+		}
+		names := make([]string, 0, len(attributes))
+		for name := range attributes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		writer := csv.NewWriter(w)
+		if err := writer.Write(csvHeader(names)); err != nil {
+			return result, err
+		}
+		err = each(func(product *models.Product) error {
+			result.Products++
+			for _, record := range csvRecords(product, names) {
+				if err := writer.Write(record); err != nil {
+					return err
+				}
+				result.Rows++
+			}
+			// Se vacía por producto para que la respuesta vaya saliendo.
+			writer.Flush()
+			return writer.Error()
+		})
+		if err != nil {
+			return result, err
+		}
+		writer.Flush()
+		return result, writer.Error()
+
+	default:
+		return result, fmt.Errorf("unknown export format %q", opts.Format)
+	}
+}
+
+// liveVariations descarta las variaciones eliminadas.
+func liveVariations(variations []models.Variation) []models.Variation {
+	live := make([]models.Variation, 0, len(variations))
+	for _, v := range variations {
+		if v.DeletedAt == nil {
+			live = append(live, v)
+		}
+	}
+	return live
+}
+
+func csvHeader(attributes []string) []string {
+	header := []string{FieldParent}
+	header = append(header, productFields...)
+	header = append(header, skuFields...)
+	for _, name := range attributes {
+		header = append(header, AttributePrefix+name)
+	}
+	return header
+}
+
+// csvRecords devuelve una fila por SKU: la del producto simple o una por
+// variación. Un producto con variaciones pero sin ninguna viva no tiene filas.
+func csvRecords(product *models.Product, attributes []string) [][]string {
+	exponent, _ := models.CurrencyExponent(product.Currency)
+	weight := ""
+	if product.Weight != 0 {
+		weight = strconv.FormatFloat(product.Weight, 'f', -1, 64)
+	}
+	base := []string{
+		product.Name, product.Description, product.Brand, product.Category, product.CategoryID,
+		product.TaxCategory, product.Currency, string(product.Status), weight,
+	}
+
+	if len(product.Variations) == 0 {
+		record := append([]string{""}, base...)
+		record = append(record, skuRecord(product.SKU, product.Barcode, product.Price, product.SalePricing, product.Stock, product.ImageURL, exponent)...)
+		return [][]string{append(record, make([]string, len(attributes))...)}
+	}
+
+	records := [][]string{}
+	for _, v := range liveVariations(product.Variations) {
+		record := append([]string{product.ID}, base...)
+		record = append(record, skuRecord(v.SKU, v.Barcode, v.Price, v.SalePricing, v.Stock, v.ImageURL, exponent)...)
+		for _, name := range attributes {
+			record = append(record, v.Attributes[name])
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// skuRecord son las columnas de SKU, en el orden de skuFields.
+func skuRecord(sku, barcode string, price models.Money, sale models.SalePricing, stock int, imageURL string, exponent int) []string {
+	amount := func(m models.Money) string {
+		if m == 0 {
+			return ""
+		}
+		return m.Major(exponent)
+	}
+	return []string{sku, barcode, amount(price), amount(sale.CompareAtPrice), amount(sale.SalePrice), strconv.Itoa(stock), imageURL}
+}
@@ -0,0 +1,137 @@
+package catalogio
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/repository"
+)
+
+// exportRepository tiene un producto simple publicado, uno archivado, uno con
+// variaciones (una en la papelera) en borrador, uno en la papelera y uno de
+// otro subdominio.
+func exportRepository(t *testing.T) *repository.MemoryRepository {
+	t.Helper()
+	deleted := time.Now().UTC()
+	products := []*models.Product{
+		{ID: "mug", Name: "Mug", SKU: "MUG", Price: 550, Stock: 10, Status: models.StatusPublished},
+		{ID: "tee", Name: "Tee", Status: models.StatusDraft, Variations: []models.Variation{
+			{ID: "tee-s", SKU: "TEE-S", Price: 1999, Stock: 3, Active: true, Attributes: map[string]string{"size": "S"}},
+			{ID: "tee-m", SKU: "TEE-M", Price: 1999, Stock: 4, Active: true, Attributes: map[string]string{"size": "M"}},
+			{ID: "tee-l", SKU: "TEE-L", Price: 1999, Attributes: map[string]string{"fit": "loose"}, DeletedAt: &deleted},
+		}},
+		{ID: "hat", Name: "Hat", SKU: "HAT", Price: 900, Status: models.StatusArchived},
+		{ID: "cap", Name: "Cap", SKU: "CAP", Price: 1200, Status: models.StatusArchived, DeletedAt: &deleted},
+		{ID: "other", Name: "Other", SKU: "OTHER", Price: 100, Status: models.StatusPublished, Subdomain: "other"},
+	}
+	repo := repository.NewMemoryRepository()
+	for _, product := range products {
+		product.Currency, product.ProjectID = "USD", "proj"
+		if product.Subdomain == "" {
+			product.Subdomain = "s"
+		}
+		if err := repo.Create(context.Background(), product); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo
+}
+
+func TestExportCSV(t *testing.T) {
+	tests := []struct {
+		name   string
+		status models.ProductStatus
+		skus   []string
+		// headerEnd son las últimas columnas de la cabecera.
+		headerEnd string
+	}{
+		{"all statuses", "", []string{"HAT", "MUG", "TEE-S", "TEE-M"}, ",imageUrl,attributes.size"},
+		{"published only", models.StatusPublished, []string{"MUG"}, ",stock,imageUrl"},
+		{"draft only", models.StatusDraft, []string{"TEE-S", "TEE-M"}, ",imageUrl,attributes.size"},
+		{"archived only", models.StatusArchived, []string{"HAT"}, ",stock,imageUrl"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			result, err := Export(context.Background(), exportRepository(t), &out, ExportOptions{Subdomain: "s", Format: FormatCSV, Status: tt.status})
+			if err != nil {
+				t.Fatal(err)
+			}
+			records, err := csv.NewReader(&out).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			header, rows := records[0], records[1:]
+			// Solo los atributos de variaciones exportadas forman columnas.
+			if got := strings.Join(header, ","); !strings.HasSuffix(got, tt.headerEnd) {
+				t.Errorf("header = %s", got)
+			}
+			if result.Rows != len(tt.skus) || len(rows) != len(tt.skus) {
+				t.Fatalf("rows = %d (result %+v), want %v", len(rows), result, tt.skus)
+			}
+			column := map[string]int{}
+			for i, name := range header {
+				column[name] = i
+			}
+			for i, row := range rows {
+				if row[column[FieldSKU]] == "MUG" && row[column[FieldPrice]] != "5.50" {
+					t.Errorf("MUG price = %q, want 5.50", row[column[FieldPrice]])
+				}
+				if row[column[FieldSKU]] != tt.skus[i] {
+					t.Errorf("row %d SKU = %s, want %s", i, row[column[FieldSKU]], tt.skus[i])
+				}
+				if parent := row[column[FieldParent]]; (parent == "tee") != strings.HasPrefix(tt.skus[i], "TEE") {
+					t.Errorf("row %d parent = %q", i, parent)
+				}
+			}
+		})
+	}
+}
+
+func TestExportJSONL(t *testing.T) {
+	var out bytes.Buffer
+	result, err := Export(context.Background(), exportRepository(t), &out, ExportOptions{Subdomain: "s", Format: FormatJSONL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if result.Products != 3 || result.Rows != 3 || len(lines) != 3 {
+		t.Fatalf("result = %+v, lines = %d", result, len(lines))
+	}
+	for _, line := range lines {
+		var product models.Product
+		if err := json.Unmarshal([]byte(line), &product); err != nil {
+			t.Fatal(err)
+		}
+		if product.ID == "tee" && len(product.Variations) != 2 {
+			t.Errorf("tee variations = %d, want the 2 not in the trash", len(product.Variations))
+		}
+	}
+}
+
+func TestExportUnknownFormat(t *testing.T) {
+	if _, err := Export(context.Background(), exportRepository(t), &bytes.Buffer{}, ExportOptions{Subdomain: "s", Format: "xml"}); err == nil {
+		t.Fatal("Export() with an unknown format succeeded")
+	}
+}
+
+// Un CSV exportado se vuelve a importar sin cambios.
+func TestExportImportRoundTrip(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	runImport(t, repo, catalogCSV, importOptions())
+
+	var out bytes.Buffer
+	if _, err := Export(context.Background(), repo, &out, ExportOptions{Subdomain: "s", Format: FormatCSV}); err != nil {
+		t.Fatal(err)
+	}
+	report := runImport(t, repo, out.String(), importOptions())
+	if report.Rows != 3 || report.Unchanged != 2 || report.Created != 0 || report.Updated != 0 || len(report.Errors) != 0 {
+		t.Fatalf("re-import report = %+v\n%s", report, out.String())
+	}
+}
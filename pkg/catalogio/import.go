@@ -0,0 +1,789 @@
+package catalogio
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/andrescris/firestore/lib/firebase"
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/pricing"
+	"github.com/andrescris/products/pkg/repository"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidFile se devuelve cuando el archivo no es un CSV legible.
+var ErrInvalidFile = errors.New("invalid CSV file")
+
+const (
+	// indexBatchSize es el tamaño de página al indexar los SKUs existentes.
+	indexBatchSize = 200
+	// writeBatchSize es el número de productos por transacción al aplicar.
+	writeBatchSize = 100
+)
+
+// ImportOptions configura una importación.
+type ImportOptions struct {
+	Subdomain string
+	ProjectID string
+	// Currency es la moneda de los productos nuevos que no traen columna
+	// currency.
+	Currency string
+	// Mapping asigna columnas del archivo (por su cabecera) a campos. Las
+	// columnas que no aparecen se mapean por nombre; un campo vacío ignora
+	// la columna.
+	Mapping map[string]string
+	// DryRun valida y devuelve el informe sin escribir nada.
+	DryRun bool
+}
+
+// ImportAction es lo que la importación hace con un producto.
+type ImportAction string
+
+const (
+	ActionCreate    ImportAction = "create"
+	ActionUpdate    ImportAction = "update"
+	ActionUnchanged ImportAction = "unchanged"
+)
+
+// RowError es un problema de una fila. Row es la línea del archivo (la
+// cabecera es la 1).
+type RowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	SKU     string `json:"sku,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportedProduct es el resultado de un producto del archivo.
+type ImportedProduct struct {
+	Action    ImportAction `json:"action"`
+	ProductID string       `json:"productId"`
+	Parent    string       `json:"parent,omitempty"`
+	SKUs      []string     `json:"skus"`
+	Rows      []int        `json:"rows"`
+	// Fields son los campos de primer nivel que cambia una actualización.
+	Fields []string `json:"fields,omitempty"`
+	// Written indica que el producto quedó guardado. Es false en un dry run,
+	// en los productos sin cambios y en los de lotes que no se aplicaron.
+	Written bool `json:"written"`
+}
+
+// ImportReport es el resultado de ImportCSV. Applied indica si se escribió
+// algo: con errores de validación no se escribe nada, pero si falla un lote
+// al aplicar, los lotes anteriores ya quedaron guardados. En ese caso Partial
+// es true, Written cuenta los productos guardados y cada uno lleva Written en
+// Products.
+type ImportReport struct {
+	DryRun         bool              `json:"dryRun"`
+	Applied        bool              `json:"applied"`
+	Partial        bool              `json:"partial"`
+	Written        int               `json:"written"`
+	Rows           int               `json:"rows"`
+	Created        int               `json:"created"`
+	Updated        int               `json:"updated"`
+	Unchanged      int               `json:"unchanged"`
+	IgnoredColumns []string          `json:"ignoredColumns"`
+	Products       []ImportedProduct `json:"products"`
+	Errors         []RowError        `json:"errors"`
+}
+
+func (r *ImportReport) addError(row int, column, sku, format string, args ...interface{}) {
+	r.Errors = append(r.Errors, RowError{Row: row, Column: column, SKU: sku, Message: fmt.Sprintf(format, args...)})
+}
+
+// row es una fila con sus valores por campo, sin espacios alrededor.
+type row struct {
+	line       int
+	values     map[string]string
+	attributes map[string]string
+}
+
+func (r row) sku() string {
+	return r.values[FieldSKU]
+}
+
+// group son las filas de un mismo producto: las de un parent o una sola
+// fila sin parent.
+type group struct {
+	parent string
+	rows   []row
+}
+
+// skuOwner es el producto (y la variación) que ya tiene un SKU.
+type skuOwner struct {
+	product     *models.Product
+	variationID string
+}
+
+// ImportCSV lee un CSV con una fila por SKU y crea o actualiza los productos
+// del subdominio, identificados por SKU. Primero valida todo el archivo; si
+// hay errores, o en DryRun, no escribe nada. Las escrituras se aplican en
+// lotes atómicos con control de versión, así que un producto modificado
+// durante la importación hace fallar su lote; los lotes anteriores quedan
+// guardados y el informe marca cuáles productos se escribieron. Si falla el
+// repositorio, devuelve el error junto con el informe de lo ya escrito. Los
+// movimientos de stock toman el motivo del context.
+func ImportCSV(ctx context.Context, products repository.ProductRepository, categories repository.CategoryRepository, bulk repository.BulkRepository, input io.Reader, opts ImportOptions) (*ImportReport, error) {
+	report := &ImportReport{
+		DryRun:         opts.DryRun,
+		IgnoredColumns: []string{},
+		Products:       []ImportedProduct{},
+		Errors:         []RowError{},
+	}
+
+	rows, err := readRows(input, opts.Mapping, report)
+	if err != nil {
+		return nil, err
+	}
+	report.Rows = len(rows)
+	if len(report.Errors) > 0 {
+		return report, nil
+	}
+
+	owners, err := indexSKUs(ctx, products, opts.Subdomain)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &planner{
+		ctx:        ctx,
+		categories: categories,
+		opts:       opts,
+		owners:     owners,
+		report:     report,
+		now:        time.Now().UTC(),
+		schemas:    make(map[string][]models.AttributeDefinition),
+	}
+	writes := []repository.BulkWrite{}
+	written := []int{}
+	for _, g := range groupRows(rows, report) {
+		write, result, err := p.plan(g)
+		if err != nil {
+			return nil, err
+		}
+		if result == nil {
+			continue
+		}
+		switch result.Action {
+		case ActionCreate:
+			report.Created++
+		case ActionUpdate:
+			report.Updated++
+		default:
+			report.Unchanged++
+		}
+		report.Products = append(report.Products, *result)
+		if write != nil {
+			writes = append(writes, *write)
+			written = append(written, len(report.Products)-1)
+		}
+	}
+
+	sort.SliceStable(report.Errors, func(i, j int) bool {
+		return report.Errors[i].Row < report.Errors[j].Row
+	})
+	if len(report.Errors) > 0 || opts.DryRun {
+		return report, nil
+	}
+	for start := 0; start < len(writes); start += writeBatchSize {
+		end := start + writeBatchSize
+		if end > len(writes) {
+			end = len(writes)
+		}
+		if _, err := bulk.ApplyBulk(ctx, writes[start:end]); err != nil {
+			// Los lotes anteriores ya se guardaron.
+			report.Applied = start > 0
+			report.Partial = start > 0
+			var bulkErr *repository.BulkError
+			if !errors.As(err, &bulkErr) {
+				return report, err
+			}
+			// El informe señala el producto que hizo fallar este lote.
+			failed := report.Products[written[start+bulkErr.Index]]
+			report.addError(failed.Rows[0], "", failed.SKUs[0], "%v", bulkErr.Err)
+			return report, nil
+		}
+		for _, i := range written[start:end] {
+			report.Products[i].Written = true
+		}
+		report.Written += end - start
+	}
+	report.Applied = true
+	return report, nil
+}
+
+// readRows lee la cabecera, resuelve el mapeo de columnas y devuelve las
+// filas no vacías. Los problemas de cabecera se anotan en la fila 1.
+func readRows(input io.Reader, mapping map[string]string, report *ImportReport) ([]row, error) {
+	reader := csv.NewReader(input)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: the file is empty", ErrInvalidFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+
+	fields := make([]string, len(header))
+	columns := make(map[string]string)
+	mapped := make(map[string]bool)
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		header[i] = name
+		target, explicit := mapping[name]
+		if !explicit {
+			target = name
+		}
+		mapped[name] = explicit
+		if target == "" {
+			report.IgnoredColumns = append(report.IgnoredColumns, name)
+			continue
+		}
+		field, ok := knownField(target)
+		if !ok {
+			if explicit {
+				report.addError(1, name, "", "unknown field %q in the column mapping", target)
+			} else {
+				report.IgnoredColumns = append(report.IgnoredColumns, name)
+			}
+			continue
+		}
+		if previous, dup := columns[field]; dup {
+			report.addError(1, name, "", "column maps to field %s, already mapped from column %s", field, previous)
+			continue
+		}
+		columns[field] = name
+		fields[i] = field
+	}
+	for _, name := range sortedKeys(mapping) {
+		if _, ok := mapped[name]; !ok {
+			report.addError(1, name, "", "the column mapping refers to a column that is not in the file")
+		}
+	}
+	if _, ok := columns[FieldSKU]; !ok {
+		report.addError(1, "", "", "the file has no column for the sku field")
+	}
+
+	rows := []row{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		line, _ := reader.FieldPos(0)
+		r := row{line: line, values: make(map[string]string), attributes: make(map[string]string)}
+		for i, value := range record {
+			if i >= len(fields) || fields[i] == "" {
+				continue
+			}
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+			if strings.HasPrefix(fields[i], AttributePrefix) {
+				r.attributes[strings.TrimPrefix(fields[i], AttributePrefix)] = value
+			} else {
+				r.values[fields[i]] = value
+			}
+		}
+		if len(r.values) == 0 && len(r.attributes) == 0 {
+			continue
+		}
+		rows = append(rows, r)
+	}
+}
+
+// groupRows agrupa las filas por parent en el orden del archivo y descarta
+// las filas sin SKU o con un SKU repetido.
+func groupRows(rows []row, report *ImportReport) []*group {
+	groups := []*group{}
+	byParent := make(map[string]*group)
+	seen := make(map[string]int)
+	for _, r := range rows {
+		sku := r.sku()
+		if sku == "" {
+			report.addError(r.line, FieldSKU, "", "sku is required")
+			continue
+		}
+		if first, dup := seen[sku]; dup {
+			report.addError(r.line, FieldSKU, sku, "sku is repeated (first seen in row %d)", first)
+			continue
+		}
+		seen[sku] = r.line
+
+		parent := r.values[FieldParent]
+		if parent == "" {
+			groups = append(groups, &group{rows: []row{r}})
+			continue
+		}
+		g, ok := byParent[parent]
+		if !ok {
+			g = &group{parent: parent}
+			byParent[parent] = g
+			groups = append(groups, g)
+		}
+		g.rows = append(g.rows, r)
+	}
+	return groups
+}
+
+// indexSKUs asocia cada SKU del subdominio con el producto que lo tiene.
+func indexSKUs(ctx context.Context, products repository.ProductRepository, subdomain string) (map[string]skuOwner, error) {
+	owners := make(map[string]skuOwner)
+	options := firebase.QueryOptions{
+		Filters: []firebase.QueryFilter{{Field: "subdomain", Operator: "==", Value: subdomain}},
+	}
+	err := repository.EachProduct(ctx, products, options, indexBatchSize, func(product models.Product) error {
+		p := product
+		if len(p.Variations) == 0 {
+			if p.SKU != "" {
+				owners[p.SKU] = skuOwner{product: &p}
+			}
+			return nil
+		}
+		for _, v := range p.Variations {
+			owners[v.SKU] = skuOwner{product: &p, variationID: v.ID}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("indexing existing SKUs: %w", err)
+	}
+	return owners, nil
+}
+
+// planner convierte cada grupo de filas en una escritura.
+type planner struct {
+	ctx        context.Context
+	categories repository.CategoryRepository
+	opts       ImportOptions
+	owners     map[string]skuOwner
+	report     *ImportReport
+	now        time.Time
+	// schemas guarda el esquema de atributos de cada categoría ya cargada.
+	schemas map[string][]models.AttributeDefinition
+}
+
+// plan valida un grupo y devuelve su escritura (nil si no cambia nada) y su
+// resultado (nil si tiene errores).
+func (p *planner) plan(g *group) (*repository.BulkWrite, *ImportedProduct, error) {
+	errorsBefore := len(p.report.Errors)
+	first := g.rows[0]
+
+	values := p.productValues(g)
+	existing := p.target(g)
+	if len(p.report.Errors) > errorsBefore {
+		return nil, nil, nil
+	}
+
+	product := &models.Product{}
+	if existing != nil {
+		// Se trabaja sobre una copia para comparar después con el original.
+		if err := copyProduct(existing, product); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		product.ProjectID = p.opts.ProjectID
+		product.Subdomain = p.opts.Subdomain
+	}
+
+	if err := p.applyProductValues(product, values, existing == nil, first); err != nil {
+		return nil, nil, err
+	}
+	exponent, _ := models.CurrencyExponent(product.Currency)
+	for _, r := range g.rows {
+		p.applyRow(product, g, r, exponent)
+	}
+	if len(p.report.Errors) > errorsBefore {
+		return nil, nil, nil
+	}
+
+	if err := pricing.ValidateProduct(product); err != nil {
+		p.report.addError(first.line, "", first.sku(), "%v", err)
+		return nil, nil, nil
+	}
+	if err := p.validateAttributes(product, g); err != nil {
+		return nil, nil, err
+	}
+	if len(p.report.Errors) > errorsBefore {
+		return nil, nil, nil
+	}
+
+	result := &ImportedProduct{Parent: g.parent, SKUs: []string{}, Rows: []int{}}
+	for _, r := range g.rows {
+		result.SKUs = append(result.SKUs, r.sku())
+		result.Rows = append(result.Rows, r.line)
+	}
+
+	if existing == nil {
+		prepareNew(product, p.now)
+		result.Action = ActionCreate
+		result.ProductID = product.ID
+		return &repository.BulkWrite{Create: product}, result, nil
+	}
+
+	result.ProductID = existing.ID
+	updates, err := changedFields(existing, product)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(updates) == 0 {
+		result.Action = ActionUnchanged
+		return nil, result, nil
+	}
+	result.Action = ActionUpdate
+	result.Fields = sortedKeys(updates)
+	return &repository.BulkWrite{ProductID: existing.ID, ExpectedVersion: existing.Version, Updates: updates}, result, nil
+}
+
+// productValues junta los campos de producto del grupo: vale el primero no
+// vacío y cualquier otro valor distinto es un error.
+func (p *planner) productValues(g *group) map[string]string {
+	values := make(map[string]string)
+	origin := make(map[string]int)
+	for _, r := range g.rows {
+		for _, field := range productFields {
+			value, ok := r.values[field]
+			if !ok {
+				continue
+			}
+			current, set := values[field]
+			if !set {
+				values[field] = value
+				origin[field] = r.line
+				continue
+			}
+			if current != value {
+				p.report.addError(r.line, field, r.sku(), "value differs from row %d of parent %s", origin[field], g.parent)
+			}
+		}
+	}
+	return values
+}
+
+// target busca el producto existente del grupo: todos sus SKUs ya
+// existentes deben ser del mismo producto y del mismo tipo.
+func (p *planner) target(g *group) *models.Product {
+	var target *models.Product
+	for _, r := range g.rows {
+		owner, ok := p.owners[r.sku()]
+		if !ok {
+			continue
+		}
+		switch {
+		case owner.product.ProjectID != p.opts.ProjectID:
+			p.report.addError(r.line, FieldSKU, r.sku(), "sku belongs to product %s of another project", owner.product.ID)
+		case g.parent == "" && owner.variationID != "":
+			p.report.addError(r.line, FieldParent, r.sku(), "sku is a variation of product %s; set its parent to update it", owner.product.ID)
+		case g.parent != "" && owner.variationID == "":
+			p.report.addError(r.line, FieldParent, r.sku(), "sku is the simple product %s; leave parent empty to update it", owner.product.ID)
+		case target != nil && target.ID != owner.product.ID:
+			p.report.addError(r.line, FieldSKU, r.sku(), "sku belongs to product %s, but other SKUs of parent %s belong to product %s", owner.product.ID, g.parent, target.ID)
+		default:
+			target = owner.product
+		}
+	}
+	return target
+}
+
+// applyProductValues aplica los campos de producto. En los productos
+// existentes las celdas vacías dejan el valor como está.
+func (p *planner) applyProductValues(product *models.Product, values map[string]string, create bool, first row) error {
+	sku := first.sku()
+	if value, ok := values[FieldName]; ok {
+		product.Name = value
+	}
+	if value, ok := values[FieldDescription]; ok {
+		product.Description = value
+	}
+	if value, ok := values[FieldBrand]; ok {
+		product.Brand = value
+	}
+	if value, ok := values[FieldTaxCategory]; ok {
+		product.TaxCategory = value
+	}
+	if value, ok := values[FieldWeight]; ok {
+		weight, err := strconv.ParseFloat(value, 64)
+		if err != nil || weight < 0 {
+			p.report.addError(first.line, FieldWeight, sku, "weight must be a non-negative number")
+		}
+		product.Weight = weight
+	}
+
+	currency := strings.ToUpper(values[FieldCurrency])
+	switch {
+	case create && currency == "":
+		currency = strings.ToUpper(p.opts.Currency)
+		if currency == "" {
+			p.report.addError(first.line, FieldCurrency, sku, "currency is required for new products")
+		}
+	case !create && currency != "" && currency != product.Currency:
+		p.report.addError(first.line, FieldCurrency, sku, "currency cannot be changed from %s", product.Currency)
+	}
+	if create {
+		if currency != "" && !models.ValidCurrency(currency) {
+			p.report.addError(first.line, FieldCurrency, sku, "currency must be a valid ISO 4217 code")
+		}
+		product.Currency = currency
+	}
+
+	if value, ok := values[FieldStatus]; ok {
+		status := models.ProductStatus(value)
+		switch {
+		case create && status != models.StatusDraft && status != models.StatusPublished:
+			p.report.addError(first.line, FieldStatus, sku, "status must be draft or published when creating a product")
+		case create:
+			product.Status = status
+		case status != product.Status:
+			p.report.addError(first.line, FieldStatus, sku, "status cannot be changed by an import; use the publishing endpoints")
+		}
+	}
+
+	if id, ok := values[FieldCategoryID]; ok && id != product.CategoryID {
+		category, err := p.categories.GetCategory(p.ctx, id)
+		switch {
+		case errors.Is(err, repository.ErrCategoryNotFound):
+			p.report.addError(first.line, FieldCategoryID, sku, "category %s does not exist", id)
+		case err != nil:
+			return err
+		case category.ProjectID != product.ProjectID:
+			p.report.addError(first.line, FieldCategoryID, sku, "category %s does not belong to project %s", id, product.ProjectID)
+		default:
+			repository.SetProductCategory(product, category)
+		}
+	}
+
+	// Con categoryId la ruta la fija el árbol; una columna category que la
+	// repite (como en las exportaciones) no es un cambio.
+	if value, ok := values[FieldCategory]; ok && value != product.Category {
+		if product.CategoryID != "" {
+			p.report.addError(first.line, FieldCategory, sku, "category is derived from categoryId")
+		} else {
+			product.Category = value
+		}
+	}
+
+	if create && product.Name == "" {
+		p.report.addError(first.line, FieldName, sku, "name is required for new products")
+	}
+	return nil
+}
+
+// applyRow aplica los campos de SKU de una fila al producto simple o a su
+// variación, que se añade si el SKU es nuevo.
+func (p *planner) applyRow(product *models.Product, g *group, r row, exponent int) {
+	if g.parent == "" {
+		if len(r.attributes) > 0 {
+			p.report.addError(r.line, "", r.sku(), "attributes only apply to variations; set a parent")
+		}
+		product.SKU = r.sku()
+		p.applySKU(r, exponent, &product.Barcode, &product.ImageURL, &product.Price, &product.SalePricing, &product.Stock, len(product.Inventory) > 0, product.IsBundle())
+		return
+	}
+
+	index := -1
+	for i, v := range product.Variations {
+		if v.SKU == r.sku() {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		product.Variations = append(product.Variations, models.Variation{
+			ID:         "var-" + uuid.New().String(),
+			SKU:        r.sku(),
+			Active:     true,
+			Attributes: map[string]string{},
+		})
+		index = len(product.Variations) - 1
+	}
+	v := &product.Variations[index]
+	if v.Attributes == nil {
+		v.Attributes = map[string]string{}
+	}
+	for name, value := range r.attributes {
+		v.Attributes[name] = value
+	}
+	p.applySKU(r, exponent, &v.Barcode, &v.ImageURL, &v.Price, &v.SalePricing, &v.Stock, len(v.Inventory) > 0, false)
+}
+
+// applySKU aplica los campos de SKU de una fila. Los importes vienen en
+// unidades mayores de la moneda del producto. El stock de un bundle se deriva
+// de sus componentes, así que solo acepta 0.
+func (p *planner) applySKU(r row, exponent int, barcode, imageURL *string, price *models.Money, sale *models.SalePricing, stock *int, perLocation, bundle bool) {
+	sku := r.sku()
+	if value, ok := r.values[FieldBarcode]; ok {
+		*barcode = value
+	}
+	if value, ok := r.values[FieldImageURL]; ok {
+		*imageURL = value
+	}
+	amounts := []struct {
+		field  string
+		target *models.Money
+	}{
+		{FieldPrice, price},
+		{FieldCompareAtPrice, &sale.CompareAtPrice},
+		{FieldSalePrice, &sale.SalePrice},
+	}
+	for _, amount := range amounts {
+		value, ok := r.values[amount.field]
+		if !ok {
+			continue
+		}
+		money, err := models.MoneyFromMajor(value, exponent)
+		if err != nil || money < 0 {
+			p.report.addError(r.line, amount.field, sku, "%s must be a non-negative amount", amount.field)
+			continue
+		}
+		*amount.target = money
+	}
+	if *price <= 0 {
+		p.report.addError(r.line, FieldPrice, sku, "price must be greater than zero")
+	}
+	if value, ok := r.values[FieldStock]; ok {
+		quantity, err := strconv.Atoi(value)
+		switch {
+		case err != nil || quantity < 0:
+			p.report.addError(r.line, FieldStock, sku, "stock must be a non-negative integer")
+		case perLocation && quantity != *stock:
+			p.report.addError(r.line, FieldStock, sku, "%v", repository.ErrLocationRequired)
+		case bundle && quantity != 0:
+			p.report.addError(r.line, FieldStock, sku, "%v", repository.ErrBundleStock)
+		default:
+			*stock = quantity
+		}
+	}
+}
+
+// validateAttributes valida los atributos de las variaciones del archivo
+// contra el esquema de la categoría.
+func (p *planner) validateAttributes(product *models.Product, g *group) error {
+	if product.CategoryID == "" || g.parent == "" {
+		return nil
+	}
+	schema, ok := p.schemas[product.CategoryID]
+	if !ok {
+		category, err := p.categories.GetCategory(p.ctx, product.CategoryID)
+		if err != nil {
+			return fmt.Errorf("loading category %s: %w", product.CategoryID, err)
+		}
+		schema, err = repository.CategorySchema(p.ctx, p.categories, category)
+		if err != nil {
+			return fmt.Errorf("loading schema of category %s: %w", product.CategoryID, err)
+		}
+		p.schemas[product.CategoryID] = schema
+	}
+
+	lines := make(map[string]int, len(g.rows))
+	for _, r := range g.rows {
+		lines[r.sku()] = r.line
+	}
+	for _, v := range product.Variations {
+		line, ok := lines[v.SKU]
+		if !ok {
+			continue
+		}
+		for _, violation := range models.ValidateAttributes(schema, v.SKU, v.Attributes) {
+			p.report.addError(line, AttributePrefix+violation.Attribute, v.SKU, "%s", violation.Message)
+		}
+	}
+	return nil
+}
+
+// prepareNew completa ID, estado y fechas de un producto nuevo.
+func prepareNew(product *models.Product, now time.Time) {
+	product.ID = "prod-" + uuid.New().String()
+	if product.Variations == nil {
+		product.Variations = []models.Variation{}
+	}
+	if product.Status == "" {
+		product.Status = models.StatusDraft
+	}
+	product.CreatedAt = now
+	product.UpdatedAt = now
+}
+
+// copyProduct copia un producto pasando por JSON, como los guarda el
+// repositorio.
+func copyProduct(src, dst *models.Product) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+// changedFields devuelve los campos de primer nivel cuyo valor JSON difiere
+// entre el producto guardado y el importado.
+func changedFields(before, after *models.Product) (map[string]interface{}, error) {
+	old, err := productMap(before)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := productMap(after)
+	if err != nil {
+		return nil, err
+	}
+	changes := make(map[string]interface{})
+	for key, value := range updated {
+		if !reflect.DeepEqual(old[key], value) {
+			changes[key] = value
+		}
+	}
+	return changes, nil
+}
+
+func productMap(product *models.Product) (map[string]interface{}, error) {
+	data, err := json.Marshal(product)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	err = json.Unmarshal(data, &m)
+	return m, err
+}
+
+// WriteImportReport escribe un resumen legible del informe y sus errores.
+func WriteImportReport(w io.Writer, report *ImportReport) error {
+	fmt.Fprintf(w, "Rows: %d, create: %d, update: %d, unchanged: %d, errors: %d, applied: %t, written: %d\n",
+		report.Rows, report.Created, report.Updated, report.Unchanged, len(report.Errors), report.Applied, report.Written)
+	if len(report.IgnoredColumns) > 0 {
+		fmt.Fprintf(w, "Ignored columns: %s\n", strings.Join(report.IgnoredColumns, ", "))
+	}
+	if report.Partial {
+		fmt.Fprintf(w, "WARNING: the import was partially applied; only these %d products were saved:\n", report.Written)
+		for _, product := range report.Products {
+			if product.Written {
+				fmt.Fprintf(w, "  %s %s (%s)\n", product.Action, product.ProductID, strings.Join(product.SKUs, ", "))
+			}
+		}
+	}
+	if len(report.Errors) == 0 {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ROW\tCOLUMN\tSKU\tERROR")
+	for _, e := range report.Errors {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", e.Row, e.Column, e.SKU, e.Message)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,289 @@
+package catalogio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/andrescris/firestore/lib/firebase"
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/repository"
+)
+
+const catalogCSV = `sku,parent,name,price,stock,attributes.size
+MUG,,Mug,5.50,10,
+TEE-S,tee,Tee,19.99,3,S
+TEE-M,tee,Tee,19.99,4,M
+`
+
+func importOptions() ImportOptions {
+	return ImportOptions{Subdomain: "s", ProjectID: "proj", Currency: "USD"}
+}
+
+func runImport(t *testing.T, repo *repository.MemoryRepository, input string, opts ImportOptions) *ImportReport {
+	t.Helper()
+	report, err := ImportCSV(context.Background(), repo, repo, repo, strings.NewReader(input), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+// catalog devuelve los productos del subdominio "s" por SKU (el del producto
+// simple o el de cada variación).
+func catalog(t *testing.T, repo *repository.MemoryRepository) map[string]models.Product {
+	t.Helper()
+	products, err := repo.Query(context.Background(), firebase.QueryOptions{
+		Filters: []firebase.QueryFilter{{Field: "subdomain", Operator: "==", Value: "s"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bySKU := map[string]models.Product{}
+	for _, product := range products {
+		if product.SKU != "" {
+			bySKU[product.SKU] = product
+		}
+		for _, v := range product.Variations {
+			bySKU[v.SKU] = product
+		}
+	}
+	return bySKU
+}
+
+func TestImportCreatesAndUpdates(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+
+	opts := importOptions()
+	opts.DryRun = true
+	report := runImport(t, repo, catalogCSV, opts)
+	if report.Created != 2 || report.Applied || report.Written != 0 || len(report.Errors) != 0 {
+		t.Fatalf("dry run report = %+v", report)
+	}
+	if got := catalog(t, repo); len(got) != 0 {
+		t.Fatalf("dry run wrote %d products", len(got))
+	}
+
+	report = runImport(t, repo, catalogCSV, importOptions())
+	if report.Rows != 3 || report.Created != 2 || !report.Applied || report.Partial || report.Written != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+	for _, product := range report.Products {
+		if !product.Written || product.Action != ActionCreate {
+			t.Errorf("product %+v not reported as created and written", product)
+		}
+	}
+	products := catalog(t, repo)
+	mug, tee := products["MUG"], products["TEE-M"]
+	if mug.Price != 550 || mug.Stock != 10 || mug.Status != models.StatusDraft || mug.Currency != "USD" {
+		t.Errorf("MUG = %+v", mug)
+	}
+	if len(tee.Variations) != 2 || tee.Variations[1].Attributes["size"] != "M" || tee.Variations[1].Price != 1999 || tee.TotalStock != 7 {
+		t.Errorf("TEE = %+v", tee)
+	}
+
+	// Volver a importar el mismo archivo no cambia nada.
+	report = runImport(t, repo, catalogCSV, importOptions())
+	if report.Unchanged != 2 || report.Written != 0 {
+		t.Fatalf("re-import report = %+v", report)
+	}
+
+	// Las celdas vacías no cambian el campo. Una variación se actualiza con
+	// el ID de su producto en parent.
+	report = runImport(t, repo, "sku,parent,price,stock\nMUG,,,12\nTEE-S,"+tee.ID+",18,\n", importOptions())
+	if report.Updated != 2 || report.Written != 2 {
+		t.Fatalf("update report = %+v", report)
+	}
+	products = catalog(t, repo)
+	if products["MUG"].Price != 550 || products["MUG"].Stock != 12 {
+		t.Errorf("updated MUG = %+v", products["MUG"])
+	}
+	if v := products["TEE-S"].Variations[0]; v.Price != 1800 || v.Stock != 3 {
+		t.Errorf("updated TEE-S = %+v", v)
+	}
+}
+
+func TestImportMapping(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	opts := importOptions()
+	opts.Mapping = map[string]string{"Código": "sku", "Nombre": "name", "Precio": "price", "Notas": ""}
+	report := runImport(t, repo, "Código,Nombre,Precio,Notas,Extra\nCAP,Cap,12,call supplier,x\n", opts)
+	if report.Created != 1 || len(report.Errors) != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+	if len(report.IgnoredColumns) != 2 {
+		t.Errorf("ignored columns = %v, want Notas and Extra", report.IgnoredColumns)
+	}
+	if cap := catalog(t, repo)["CAP"]; cap.Name != "Cap" || cap.Price != 1200 {
+		t.Errorf("CAP = %+v", cap)
+	}
+}
+
+func TestImportValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		row    int
+		column string
+	}{
+		{"missing sku column", "name,price\nMug,5\n", 1, ""},
+		{"missing name for a new product", "sku,price\nMUG,5\n", 2, "name"},
+		{"invalid price", "sku,name,price\nMUG,Mug,cheap\n", 2, "price"},
+		{"negative stock", "sku,name,price,stock\nMUG,Mug,5,-1\n", 2, "stock"},
+		{"duplicated sku", "sku,name,price\nMUG,Mug,5\nMUG,Mug,6\n", 3, "sku"},
+		{"invalid status", "sku,name,price,status\nMUG,Mug,5,sold\n", 2, "status"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewMemoryRepository()
+			report := runImport(t, repo, tt.input, importOptions())
+			if len(report.Errors) == 0 {
+				t.Fatalf("no errors in report %+v", report)
+			}
+			first := report.Errors[0]
+			if first.Row != tt.row || (tt.column != "" && first.Column != tt.column) {
+				t.Errorf("first error = %+v, want row %d column %q", first, tt.row, tt.column)
+			}
+			if report.Applied || len(catalog(t, repo)) != 0 {
+				t.Errorf("a file with errors was applied")
+			}
+		})
+	}
+
+	_, err := ImportCSV(context.Background(), repository.NewMemoryRepository(), nil, nil, strings.NewReader(""), importOptions())
+	if !errors.Is(err, ErrInvalidFile) {
+		t.Errorf("empty file error = %v, want %v", err, ErrInvalidFile)
+	}
+}
+
+// failingBulk aplica los lotes con el repositorio hasta el lote failAt, que
+// falla como si su producto index hubiera cambiado durante la importación.
+type failingBulk struct {
+	repo   *repository.MemoryRepository
+	failAt int
+	index  int
+	err    error
+	calls  int
+}
+
+func (b *failingBulk) ApplyBulk(ctx context.Context, writes []repository.BulkWrite) ([]*models.Product, error) {
+	b.calls++
+	if b.calls == b.failAt {
+		if b.err != nil {
+			return nil, b.err
+		}
+		return nil, &repository.BulkError{Index: b.index, Err: repository.ErrVersionConflict}
+	}
+	return b.repo.ApplyBulk(ctx, writes)
+}
+
+func TestImportReportsPartialApplication(t *testing.T) {
+	var input strings.Builder
+	input.WriteString("sku,name,price\n")
+	for i := 0; i < writeBatchSize+50; i++ {
+		fmt.Fprintf(&input, "SKU-%03d,Product %d,1\n", i, i)
+	}
+
+	tests := []struct {
+		name    string
+		bulk    *failingBulk
+		wantErr bool
+	}{
+		{"conflict in the second batch", &failingBulk{failAt: 2, index: 3}, false},
+		{"repository failure in the second batch", &failingBulk{failAt: 2, err: errors.New("unavailable")}, true},
+		{"conflict in the first batch", &failingBulk{failAt: 1, index: 0}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewMemoryRepository()
+			tt.bulk.repo = repo
+			report, err := ImportCSV(context.Background(), repo, repo, tt.bulk, strings.NewReader(input.String()), importOptions())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ImportCSV() error = %v, want error %t", err, tt.wantErr)
+			}
+			if report == nil {
+				t.Fatal("no report")
+			}
+
+			wantWritten := 0
+			if tt.bulk.failAt == 2 {
+				wantWritten = writeBatchSize
+			}
+			saved := catalog(t, repo)
+			if report.Written != wantWritten || len(saved) != wantWritten {
+				t.Fatalf("written = %d, saved = %d, want %d", report.Written, len(saved), wantWritten)
+			}
+			if report.Partial != (wantWritten > 0) || report.Applied != (wantWritten > 0) {
+				t.Errorf("partial = %t, applied = %t", report.Partial, report.Applied)
+			}
+			for _, product := range report.Products {
+				_, exists := saved[product.SKUs[0]]
+				if product.Written != exists {
+					t.Errorf("%s: written = %t but saved = %t", product.SKUs[0], product.Written, exists)
+				}
+			}
+			if !tt.wantErr {
+				want := fmt.Sprintf("SKU-%03d", (tt.bulk.failAt-1)*writeBatchSize+tt.bulk.index)
+				if len(report.Errors) != 1 || report.Errors[0].SKU != want {
+					t.Errorf("errors = %+v, want one for %s", report.Errors, want)
+				}
+			}
+
+			var out strings.Builder
+			if err := WriteImportReport(&out, report); err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(out.String(), "partially applied") != report.Partial {
+				t.Errorf("WriteImportReport output:\n%s", out.String())
+			}
+		})
+	}
+}
+
+// El stock de un bundle se deriva de sus componentes: la importación solo
+// acepta 0.
+func TestImportRejectsBundleStock(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		column string
+	}{
+		{"stock", "sku,stock\nGIFT,3\n", "stock"},
+		{"zero stock", "sku,stock,price\nGIFT,0,30\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewMemoryRepository()
+			component := &models.Product{ID: "mug", Name: "Mug", SKU: "MUG", Currency: "USD", Subdomain: "s", ProjectID: "proj", Price: 500, Stock: 4}
+			bundle := &models.Product{
+				ID: "gift", Name: "Gift", SKU: "GIFT", Type: models.ProductTypeBundle, Currency: "USD", Subdomain: "s", ProjectID: "proj", Price: 2500,
+				Components: []models.BundleComponent{{ProductID: "mug", Quantity: 2}},
+			}
+			for _, product := range []*models.Product{component, bundle} {
+				if err := repo.Create(context.Background(), product); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			report := runImport(t, repo, tt.input, importOptions())
+			stored, err := repo.Get(context.Background(), "gift")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.column == "" {
+				if len(report.Errors) != 0 || stored.Price != 3000 {
+					t.Fatalf("report = %+v, price = %d", report, stored.Price)
+				}
+				return
+			}
+			if len(report.Errors) != 1 || report.Errors[0].Column != tt.column || report.Applied {
+				t.Fatalf("report = %+v, want one %s error", report, tt.column)
+			}
+			if stored.Stock != 0 {
+				t.Errorf("bundle stock = %d, want 0", stored.Stock)
+			}
+		})
+	}
+}
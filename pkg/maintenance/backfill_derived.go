@@ -0,0 +1,81 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/andrescris/firestore/lib/firebase"
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/repository"
+)
+
+// BackfillResult resume una ejecución de BackfillDerivedFields.
+type BackfillResult struct {
+	ProductsChecked int      `json:"productsChecked"`
+	ProductsFixed   []string `json:"productsFixed"`
+}
+
+// BackfillDerivedFields recalcula los campos desnormalizados de todos los
+// productos y reescribe los que estén desactualizados. Con dryRun solo informa
+// qué productos cambiarían.
+func BackfillDerivedFields(ctx context.Context, repo repository.ProductRepository, dryRun bool, log io.Writer) (*BackfillResult, error) {
+	result := &BackfillResult{ProductsFixed: []string{}}
+
+	err := repository.EachProduct(ctx, repo, firebase.QueryOptions{}, batchSize, func(product models.Product) error {
+		result.ProductsChecked++
+
+		derived := product
+		derived.Variations = append([]models.Variation(nil), product.Variations...)
+		repository.DeriveFields(&derived)
+		if sameDerivedFields(product, derived) {
+			return nil
+		}
+
+		fmt.Fprintf(log, "%s: status %q→%q, filter_price %v→%v, max_price %v→%v, total_stock %d→%d, in_stock %v→%v, active_variation_count %d→%d\n",
+			product.ID,
+			product.Status, derived.Status,
+			product.FilterPrice, derived.FilterPrice,
+			product.MaxPrice, derived.MaxPrice,
+			product.TotalStock, derived.TotalStock,
+			product.InStock, derived.InStock,
+			product.ActiveVariationCount, derived.ActiveVariationCount)
+		result.ProductsFixed = append(result.ProductsFixed, product.ID)
+		if dryRun {
+			return nil
+		}
+
+		// Una mutación vacía basta: el repositorio deriva los campos al guardar.
+		_, err := repo.Mutate(ctx, product.ID, repository.AnyVersion, func(*models.Product) error { return nil })
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func sameDerivedFields(a, b models.Product) bool {
+	return a.Status == b.Status &&
+		a.Active == b.Active &&
+		a.FilterPrice == b.FilterPrice &&
+		a.MaxPrice == b.MaxPrice &&
+		a.TotalStock == b.TotalStock &&
+		a.InStock == b.InStock &&
+		a.ActiveVariationCount == b.ActiveVariationCount &&
+		a.Stock == b.Stock &&
+		sameVariationStock(a.Variations, b.Variations)
+}
+
+func sameVariationStock(a, b []models.Variation) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Stock != b[i].Stock || !reflect.DeepEqual(a[i].Inventory, b[i].Inventory) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/andrescris/firestore/lib/firebase"
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/repository"
+)
+
+// MoneyMigrationResult resume una ejecución de MigrateMoney.
+type MoneyMigrationResult struct {
+	ProductsChecked  int      `json:"productsChecked"`
+	ProductsMigrated []string `json:"productsMigrated"`
+}
+
+// MigrateMoney reescribe los productos cuyos importes no tienen el valor exacto
+// en los campos *Minor: los que guardan decimales (float64) y los que se
+// guardaron solo con {"amount": ...}. La conversión la hace el modelo al leer
+// con el exponente de la moneda; aquí solo se vuelve a guardar. Con dryRun
+// solo informa qué productos cambiarían.
+func MigrateMoney(ctx context.Context, repo repository.ProductRepository, dryRun bool, log io.Writer) (*MoneyMigrationResult, error) {
+	result := &MoneyMigrationResult{ProductsMigrated: []string{}}
+
+	err := repository.EachProduct(ctx, repo, firebase.QueryOptions{}, batchSize, func(product models.Product) error {
+		result.ProductsChecked++
+		if !product.HasLegacyMoney() {
+			return nil
+		}
+
+		if _, known := models.CurrencyExponent(product.Currency); !known {
+			fmt.Fprintf(log, "%s: unknown currency %q, converting with %d decimals\n", product.ID, product.Currency, models.DefaultCurrencyExponent)
+		}
+		fmt.Fprintf(log, "%s: price %d, filter_price %d (%s minor units)\n", product.ID, product.Price, product.FilterPrice, product.Currency)
+		result.ProductsMigrated = append(result.ProductsMigrated, product.ID)
+		if dryRun {
+			return nil
+		}
+
+		_, err := repo.Mutate(ctx, product.ID, repository.AnyVersion, func(*models.Product) error { return nil })
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,105 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/andrescris/firestore/lib/firebase"
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/repository"
+)
+
+// PurgeResult resume una ejecución de PurgeTrash.
+type PurgeResult struct {
+	Cutoff         time.Time `json:"cutoff"`
+	ProductsPurged []string  `json:"productsPurged"`
+	// VariationsPurged son los SKUs eliminados de productos que siguen existiendo.
+	VariationsPurged map[string][]string `json:"variationsPurged"`
+}
+
+// PurgeTrash elimina definitivamente los productos y las variaciones que
+// están en la papelera desde cutoff o antes. Con dryRun solo informa qué se
+// eliminaría.
+func PurgeTrash(ctx context.Context, repo repository.ProductRepository, cutoff time.Time, dryRun bool, log io.Writer) (*PurgeResult, error) {
+	result := &PurgeResult{Cutoff: cutoff, ProductsPurged: []string{}, VariationsPurged: map[string][]string{}}
+	limit := cutoff.UTC().Format(time.RFC3339)
+
+	err := eachDue(ctx, repo, "deletedAt", limit, dryRun, func(product models.Product) error {
+		fmt.Fprintf(log, "%s: product deleted at %s by %s\n", product.ID, product.DeletedAt.Format(time.RFC3339), describeActor(product.DeletedBy))
+		result.ProductsPurged = append(result.ProductsPurged, product.ID)
+		if dryRun {
+			return nil
+		}
+		return repo.Delete(ctx, product.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = eachDue(ctx, repo, "variation_deleted_at", limit, dryRun, func(product models.Product) error {
+		if dryRun && product.DeletedAt != nil && !product.DeletedAt.After(cutoff) {
+			// Ya se cuenta como producto eliminado.
+			return nil
+		}
+		removed := []string{}
+		purge := repository.PurgeVariations(cutoff, &removed)
+		if dryRun {
+			if err := purge(&product); err != nil {
+				return err
+			}
+		} else if _, err := repo.Mutate(ctx, product.ID, repository.AnyVersion, purge); err != nil {
+			return err
+		}
+		if len(removed) > 0 {
+			fmt.Fprintf(log, "%s: variations %v\n", product.ID, removed)
+			result.VariationsPurged[product.ID] = removed
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// eachDue recorre los productos con field <= limit. Fuera de dryRun cada
+// producto procesado deja de cumplir el filtro, así que siempre se vuelve a
+// leer la primera página.
+func eachDue(ctx context.Context, repo repository.ProductRepository, field, limit string, dryRun bool, fn func(models.Product) error) error {
+	options := firebase.QueryOptions{
+		Filters: []firebase.QueryFilter{{Field: field, Operator: "<=", Value: limit}},
+		Limit:   batchSize,
+	}
+	for {
+		due, err := repo.Query(ctx, options)
+		if err != nil {
+			return err
+		}
+		for _, product := range due {
+			if err := fn(product); err != nil {
+				return err
+			}
+		}
+		if len(due) < batchSize {
+			return nil
+		}
+		if dryRun {
+			options.Offset += len(due)
+		}
+	}
+}
+
+func describeActor(actor *models.Actor) string {
+	switch {
+	case actor == nil || (actor.UID == "" && actor.APIKey == ""):
+		return "unknown"
+	case actor.UID == "":
+		return "api key " + actor.APIKey
+	case actor.APIKey == "":
+		return "user " + actor.UID
+	default:
+		return fmt.Sprintf("user %s (api key %s)", actor.UID, actor.APIKey)
+	}
+}
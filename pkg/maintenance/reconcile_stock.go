@@ -0,0 +1,109 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/andrescris/firestore/lib/firebase"
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/repository"
+)
+
+// batchSize es el tamaño de página al recorrer todo el catálogo.
+const batchSize = 200
+
+// StockDrift es un SKU cuyo stock no coincide con el saldo del libro, o cuyo
+// libro tiene saldos registrados que no cuadran con la suma de los deltas.
+type StockDrift struct {
+	ProductID     string `json:"productId"`
+	VariationID   string `json:"variationId,omitempty"`
+	SKU           string `json:"sku"`
+	CurrentStock  int    `json:"currentStock"`
+	LedgerBalance int    `json:"ledgerBalance"`
+	// BrokenEntries cuenta movimientos cuyo Balance registrado difiere del
+	// saldo recalculado hasta ese punto.
+	BrokenEntries int `json:"brokenEntries"`
+}
+
+// Drift es la diferencia entre el stock actual y el saldo del libro.
+func (d StockDrift) Drift() int {
+	return d.CurrentStock - d.LedgerBalance
+}
+
+// StockReconciliation es el resultado de ReconcileStock.
+type StockReconciliation struct {
+	ProductsChecked int          `json:"productsChecked"`
+	SKUsChecked     int          `json:"skusChecked"`
+	Drifts          []StockDrift `json:"drifts"`
+}
+
+// ReconcileStock recalcula el saldo de cada SKU a partir del libro de
+// movimientos y lo compara con el stock guardado en el producto. No modifica
+// nada: solo informa las diferencias.
+func ReconcileStock(ctx context.Context, products repository.ProductRepository, movements repository.StockMovementRepository) (*StockReconciliation, error) {
+	report := &StockReconciliation{Drifts: []StockDrift{}}
+
+	err := repository.EachProduct(ctx, products, firebase.QueryOptions{}, batchSize, func(product models.Product) error {
+		report.ProductsChecked++
+
+		entries, err := movements.ListStockMovements(ctx, product.ID, 0, 0)
+		if err != nil {
+			return fmt.Errorf("listing movements of %s: %w", product.ID, err)
+		}
+
+		// Los movimientos llegan del más reciente al más antiguo.
+		balances := make(map[string]int)
+		broken := make(map[string]int)
+		for i := len(entries) - 1; i >= 0; i-- {
+			m := entries[i]
+			balances[m.VariationID] += m.Delta
+			if balances[m.VariationID] != m.Balance {
+				broken[m.VariationID]++
+			}
+		}
+
+		check := func(variationID, sku string, stock int) {
+			report.SKUsChecked++
+			if stock != balances[variationID] || broken[variationID] > 0 {
+				report.Drifts = append(report.Drifts, StockDrift{
+					ProductID:     product.ID,
+					VariationID:   variationID,
+					SKU:           sku,
+					CurrentStock:  stock,
+					LedgerBalance: balances[variationID],
+					BrokenEntries: broken[variationID],
+				})
+			}
+		}
+		if len(product.Variations) == 0 {
+			check("", product.SKU, product.Stock)
+		}
+		for _, v := range product.Variations {
+			check(v.ID, v.SKU, v.Stock)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// WriteStockReconciliation imprime el informe en formato de tabla.
+func WriteStockReconciliation(w io.Writer, report *StockReconciliation) error {
+	fmt.Fprintf(w, "Products checked: %d, SKUs checked: %d, SKUs with drift: %d\n",
+		report.ProductsChecked, report.SKUsChecked, len(report.Drifts))
+	if len(report.Drifts) == 0 {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PRODUCT\tVARIATION\tSKU\tSTOCK\tLEDGER\tDRIFT\tBROKEN ENTRIES")
+	for _, d := range report.Drifts {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%+d\t%d\n",
+			d.ProductID, d.VariationID, d.SKU, d.CurrentStock, d.LedgerBalance, d.Drift(), d.BrokenEntries)
+	}
+	return tw.Flush()
+}
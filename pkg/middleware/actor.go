@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/andrescris/products/pkg/models"
+	"github.com/gin-gonic/gin"
+)
+
+// ActorFromContext identifica al autor de la petición con lo que dejaron los
+// middlewares de autenticación: el uid de SessionAuthMiddleware y la API Key.
+// Si el servicio de API Keys no expone un ID, se usa una huella de la clave.
+func ActorFromContext(c *gin.Context) models.Actor {
+	actor := models.Actor{UID: c.GetString("uid")}
+	if keyID := c.GetString("api_key_id"); keyID != "" {
+		actor.APIKey = keyID
+	} else if key := c.GetHeader("X-API-KEY"); key != "" {
+		actor.APIKey = APIKeyFingerprint(key)
+	}
+	return actor
+}
+
+// APIKeyFingerprint devuelve una huella estable de la clave que permite
+// distinguir claves sin guardarlas en claro.
+func APIKeyFingerprint(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
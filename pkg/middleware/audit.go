@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/andrescris/firestore/lib/firebase"
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	auditSubdomainKey = "audit_subdomain"
+	auditTargetsKey   = "audit_targets"
+)
+
+// AuditMiddleware registra cada petición en el registro de auditoría cuando
+// termina. Debe ir antes de los middlewares de autenticación para que también
+// queden registrados los intentos rechazados. Un fallo al guardar la entrada
+// solo se registra en el log: no cambia la respuesta.
+func AuditMiddleware(repo repository.AuditRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		entry := models.AuditEntry{
+			ID:         "aud-" + uuid.New().String(),
+			Actor:      ActorFromContext(c),
+			Subdomain:  auditSubdomain(c),
+			Method:     c.Request.Method,
+			Route:      c.FullPath(),
+			Path:       c.Request.URL.Path,
+			Targets:    auditTargets(c),
+			Status:     c.Writer.Status(),
+			Outcome:    auditOutcome(c.Writer.Status()),
+			DurationMs: time.Since(start).Milliseconds(),
+			ClientIP:   c.ClientIP(),
+			CreatedAt:  start.UTC(),
+		}
+		if err := repo.RecordAudit(context.Background(), &entry); err != nil {
+			log.Printf("AUDIT ERROR: recording %s %s: %v", entry.Method, entry.Path, err)
+		}
+	}
+}
+
+// SetAuditSubdomain indica el subdominio del recurso afectado. Los handlers
+// lo llaman al comprobar permisos; si no lo hacen queda el de la sesión, que
+// fija SessionAuthMiddleware al validarla.
+func SetAuditSubdomain(c *gin.Context, subdomain string) {
+	c.Set(auditSubdomainKey, subdomain)
+}
+
+// AuditQuerySubdomain atribuye las peticiones a la consulta genérica
+// (/collections/:collection/query) al subdominio que consultan. Va después
+// de la autenticación, que deja los subdominios permitidos: usa el filtro
+// subdomain == ... del cuerpo si es uno de ellos o, si no, el único
+// subdominio permitido. El cuerpo queda intacto para el handler.
+func AuditQuerySubdomain() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get("allowed_subdomains")
+		allowed, _ := value.([]interface{})
+		subdomain := querySubdomain(c)
+		if subdomain == "" && len(allowed) == 1 {
+			subdomain, _ = allowed[0].(string)
+		}
+		for _, candidate := range allowed {
+			if s, ok := candidate.(string); ok && subdomain != "" && s == subdomain {
+				SetAuditSubdomain(c, subdomain)
+				break
+			}
+		}
+		c.Next()
+	}
+}
+
+// querySubdomain devuelve el valor del filtro subdomain == ... de la consulta,
+// o "" si no lo tiene.
+func querySubdomain(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	var query firebase.QueryOptions
+	if json.Unmarshal(body, &query) != nil {
+		return ""
+	}
+	for _, filter := range query.Filters {
+		if value, ok := filter.Value.(string); ok && filter.Field == "subdomain" && filter.Operator == "==" {
+			return value
+		}
+	}
+	return ""
+}
+
+// AddAuditTarget agrega un ID afectado que no está en la ruta, p. ej. el del
+// producto recién creado.
+func AddAuditTarget(c *gin.Context, name, id string) {
+	targets, _ := c.Get(auditTargetsKey)
+	m, ok := targets.(map[string]string)
+	if !ok {
+		m = map[string]string{}
+		c.Set(auditTargetsKey, m)
+	}
+	m[name] = id
+}
+
+// auditSubdomain devuelve solo el subdominio indicado con SetAuditSubdomain,
+// por un handler, por SessionAuthMiddleware tras validar la sesión o por
+// AuditQuerySubdomain. La
+// cabecera X-Client-Subdomain no se usa: en una petición rechazada no está
+// verificada y permitiría escribir en el registro de otro subdominio, así que
+// esas entradas quedan sin subdominio.
+func auditSubdomain(c *gin.Context) string {
+	return c.GetString(auditSubdomainKey)
+}
+
+func auditTargets(c *gin.Context) map[string]string {
+	targets := map[string]string{}
+	for _, param := range c.Params {
+		targets[param.Key] = param.Value
+	}
+	if extra, ok := c.Get(auditTargetsKey); ok {
+		for name, id := range extra.(map[string]string) {
+			targets[name] = id
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+	return targets
+}
+
+func auditOutcome(status int) models.AuditOutcome {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return models.AuditDenied
+	case status >= http.StatusBadRequest:
+		return models.AuditFailure
+	default:
+		return models.AuditSuccess
+	}
+}
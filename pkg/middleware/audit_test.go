@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/repository"
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuditSubdomain(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name    string
+		handler gin.HandlerFunc
+		want    string
+		outcome models.AuditOutcome
+	}{
+		{"rejected before the handler", func(c *gin.Context) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing API Key."})
+		}, "", models.AuditDenied},
+		{"header is not trusted", func(c *gin.Context) {
+			c.Set("subdomain", c.GetHeader("X-Client-Subdomain"))
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		}, "", models.AuditDenied},
+		{"resource subdomain", func(c *gin.Context) {
+			SetAuditSubdomain(c, "shop")
+			c.JSON(http.StatusOK, gin.H{"success": true})
+		}, "shop", models.AuditSuccess},
+		{"denied on the resource subdomain", func(c *gin.Context) {
+			SetAuditSubdomain(c, "shop")
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		}, "shop", models.AuditDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewMemoryRepository()
+			r := gin.New()
+			r.Use(AuditMiddleware(repo))
+			r.POST("/products/:id", tt.handler)
+
+			req := httptest.NewRequest(http.MethodPost, "/products/p1", nil)
+			req.Header.Set("X-Client-Subdomain", "victim")
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			entries, err := repo.ListAuditEntries(context.Background(), repository.AuditFilter{}, 10, 0)
+			if err != nil || len(entries) != 1 {
+				t.Fatalf("ListAuditEntries() = %v, %v", entries, err)
+			}
+			entry := entries[0]
+			if entry.Subdomain != tt.want || entry.Outcome != tt.outcome {
+				t.Errorf("subdomain = %q, outcome = %s; want %q, %s", entry.Subdomain, entry.Outcome, tt.want, tt.outcome)
+			}
+			if entry.Route != "/products/:id" || entry.Targets["id"] != "p1" {
+				t.Errorf("route = %s, targets = %v", entry.Route, entry.Targets)
+			}
+		})
+	}
+}
+
+func TestAuditQuerySubdomain(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const filtered = `{"filters": [{"field": "subdomain", "operator": "==", "value": "shop"}]}`
+	tests := []struct {
+		name    string
+		allowed []interface{}
+		body    string
+		want    string
+	}{
+		{"filter on an allowed subdomain", []interface{}{"shop", "outlet"}, filtered, "shop"},
+		{"filter on another subdomain", []interface{}{"outlet", "other"}, filtered, ""},
+		{"single allowed subdomain", []interface{}{"outlet"}, `{"filters": []}`, "outlet"},
+		{"several allowed subdomains", []interface{}{"shop", "outlet"}, `{}`, ""},
+		{"rejected by authentication", nil, filtered, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewMemoryRepository()
+			r := gin.New()
+			r.Use(AuditMiddleware(repo))
+			var handlerBody []byte
+			r.POST("/collections/:collection/query", func(c *gin.Context) {
+				if tt.allowed == nil {
+					c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing API Key."})
+					return
+				}
+				c.Set("allowed_subdomains", tt.allowed)
+			}, AuditQuerySubdomain(), func(c *gin.Context) {
+				handlerBody, _ = io.ReadAll(c.Request.Body)
+				c.JSON(http.StatusOK, gin.H{"success": true})
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/collections/products/query", strings.NewReader(tt.body))
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			entries, err := repo.ListAuditEntries(context.Background(), repository.AuditFilter{}, 10, 0)
+			if err != nil || len(entries) != 1 {
+				t.Fatalf("ListAuditEntries() = %v, %v", entries, err)
+			}
+			if entries[0].Subdomain != tt.want {
+				t.Errorf("subdomain = %q, want %q", entries[0].Subdomain, tt.want)
+			}
+			if tt.allowed != nil && string(handlerBody) != tt.body {
+				t.Errorf("handler read %q, want the original body", handlerBody)
+			}
+		})
+	}
+}
@@ -62,6 +62,7 @@ func SessionAuthMiddleware() gin.HandlerFunc {
 		c.Set("uid", sessionInfo.UID)
 		c.Set("claims", sessionInfo.Claims)
 		c.Set("subdomain", clientSubdomain) // Usamos el subdomain que el cliente envía
+		SetAuditSubdomain(c, clientSubdomain)
 
 		c.Next()
 	}
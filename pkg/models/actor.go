@@ -0,0 +1,9 @@
+package models
+
+// Actor identifica quién originó una escritura: el usuario de la sesión
+// (UID) y/o la API Key usada. APIKey nunca es la clave en claro, sino su ID
+// o una huella.
+type Actor struct {
+	UID    string `json:"uid,omitempty" firestore:"uid,omitempty"`
+	APIKey string `json:"apiKey,omitempty" firestore:"apiKey,omitempty"`
+}
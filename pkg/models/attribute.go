@@ -0,0 +1,144 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Tipos de atributo que puede declarar una categoría.
+const (
+	AttributeText    = "text"
+	AttributeNumber  = "number"
+	AttributeBoolean = "boolean"
+)
+
+// AttributeDefinition declara un atributo de las variaciones de una categoría.
+// Si Values no está vacío, solo se aceptan esos valores.
+type AttributeDefinition struct {
+	Name     string   `json:"name" firestore:"name"`
+	Type     string   `json:"type" firestore:"type"`
+	Values   []string `json:"values,omitempty" firestore:"values,omitempty"`
+	Required bool     `json:"required" firestore:"required"`
+}
+
+// AttributeViolation describe un atributo de una variación que no cumple el
+// esquema de su categoría.
+type AttributeViolation struct {
+	SKU       string `json:"sku,omitempty"`
+	Attribute string `json:"attribute"`
+	Message   string `json:"message"`
+}
+
+// ValidateDefinitions comprueba que un esquema de atributos esté bien
+// formado: nombres únicos, tipos conocidos y valores permitidos de ese tipo.
+func ValidateDefinitions(definitions []AttributeDefinition) error {
+	seen := map[string]bool{}
+	for i, definition := range definitions {
+		if definition.Name == "" {
+			return fmt.Errorf("attributes[%d]: name is required", i)
+		}
+		if seen[definition.Name] {
+			return fmt.Errorf("attribute %q is defined more than once", definition.Name)
+		}
+		seen[definition.Name] = true
+
+		switch definition.Type {
+		case AttributeText, AttributeNumber, AttributeBoolean:
+		default:
+			return fmt.Errorf("attribute %q: type must be %s, %s or %s", definition.Name, AttributeText, AttributeNumber, AttributeBoolean)
+		}
+		for _, value := range definition.Values {
+			if msg := checkAttributeType(definition.Type, value); msg != "" {
+				return fmt.Errorf("attribute %q: allowed value %q: %s", definition.Name, value, msg)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateAttributes compara los atributos de una variación con el esquema y
+// devuelve todas las infracciones (vacío si cumple). Con un esquema vacío
+// cualquier atributo es válido.
+func ValidateAttributes(definitions []AttributeDefinition, sku string, attributes map[string]string) []AttributeViolation {
+	if len(definitions) == 0 {
+		return nil
+	}
+
+	var violations []AttributeViolation
+	add := func(attribute, message string) {
+		violations = append(violations, AttributeViolation{SKU: sku, Attribute: attribute, Message: message})
+	}
+
+	defined := map[string]bool{}
+	for _, definition := range definitions {
+		defined[definition.Name] = true
+		value, ok := attributes[definition.Name]
+		if !ok || value == "" {
+			if definition.Required {
+				add(definition.Name, "is required")
+			}
+			continue
+		}
+		if msg := checkAttributeType(definition.Type, value); msg != "" {
+			add(definition.Name, msg)
+			continue
+		}
+		if len(definition.Values) > 0 && !containsString(definition.Values, value) {
+			add(definition.Name, fmt.Sprintf("value %q is not allowed; expected one of %v", value, definition.Values))
+		}
+	}
+
+	unknown := []string{}
+	for name := range attributes {
+		if !defined[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		add(name, "is not defined for this category")
+	}
+	return violations
+}
+
+// MergeDefinitions combina los esquemas de la raíz a la hoja: una definición
+// con el mismo nombre en una subcategoría reemplaza la del ancestro.
+func MergeDefinitions(schemas ...[]AttributeDefinition) []AttributeDefinition {
+	var merged []AttributeDefinition
+	index := map[string]int{}
+	for _, schema := range schemas {
+		for _, definition := range schema {
+			if i, ok := index[definition.Name]; ok {
+				merged[i] = definition
+				continue
+			}
+			index[definition.Name] = len(merged)
+			merged = append(merged, definition)
+		}
+	}
+	return merged
+}
+
+func checkAttributeType(attributeType, value string) string {
+	switch attributeType {
+	case AttributeNumber:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Sprintf("value %q is not a number", value)
+		}
+	case AttributeBoolean:
+		if value != "true" && value != "false" {
+			return fmt.Sprintf("value %q must be true or false", value)
+		}
+	}
+	return ""
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
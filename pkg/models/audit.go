@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// AuditOutcome resume cómo terminó una petición auditada.
+type AuditOutcome string
+
+const (
+	AuditSuccess AuditOutcome = "success"
+	// AuditDenied cubre las peticiones rechazadas por autenticación o permisos (401/403).
+	AuditDenied  AuditOutcome = "denied"
+	AuditFailure AuditOutcome = "failure"
+)
+
+// AuditEntry registra una petición a una ruta de escritura o al endpoint
+// genérico de consultas. Es append-only.
+type AuditEntry struct {
+	ID    string `json:"id" firestore:"id"`
+	Actor Actor  `json:"actor" firestore:"actor"`
+	// Subdomain queda vacío (y se guarda vacío, para poder consultarlo) en
+	// las peticiones rechazadas antes de identificar el recurso.
+	Subdomain string `json:"subdomain,omitempty" firestore:"subdomain"`
+	Method    string `json:"method" firestore:"method"`
+	// Route es la ruta registrada en el router, p. ej. "/api/v1/products/:id";
+	// Path es la ruta real de la petición.
+	Route string `json:"route" firestore:"route"`
+	Path  string `json:"path" firestore:"path"`
+	// Targets son los IDs afectados: los parámetros de la ruta (id,
+	// variationId, collection...) más los que asigne el handler al crear.
+	Targets    map[string]string `json:"targets,omitempty" firestore:"targets,omitempty"`
+	Status     int               `json:"status" firestore:"status"`
+	Outcome    AuditOutcome      `json:"outcome" firestore:"outcome"`
+	DurationMs int64             `json:"durationMs" firestore:"durationMs"`
+	ClientIP   string            `json:"clientIp,omitempty" firestore:"clientIp,omitempty"`
+	CreatedAt  time.Time         `json:"createdAt" firestore:"createdAt"`
+}
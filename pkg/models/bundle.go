@@ -0,0 +1,19 @@
+package models
+
+// ProductTypeBundle marca un producto formado por otros productos
+// (caja de regalo, kit). No tiene stock propio.
+const ProductTypeBundle = "bundle"
+
+// BundleComponent es un SKU que forma parte de un bundle: una variación
+// (VariationID o SKU) o un producto simple, con las unidades por bundle.
+type BundleComponent struct {
+	ProductID   string `json:"productId" firestore:"productId"`
+	VariationID string `json:"variationId,omitempty" firestore:"variationId,omitempty"`
+	SKU         string `json:"sku,omitempty" firestore:"sku,omitempty"`
+	Quantity    int    `json:"quantity" firestore:"quantity"`
+}
+
+// IsBundle indica si el producto es un bundle.
+func (p *Product) IsBundle() bool {
+	return p.Type == ProductTypeBundle
+}
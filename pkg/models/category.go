@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Category es un nodo del árbol de categorías de un proyecto. Path y
+// Ancestors se derivan de la posición en el árbol y los mantiene el
+// repositorio al crear, renombrar o mover categorías.
+type Category struct {
+	ID        string `json:"id" firestore:"id"`
+	ProjectID string `json:"project_id" firestore:"project_id"`
+	Subdomain string `json:"subdomain" firestore:"subdomain"`
+	Name      string `json:"name" firestore:"name"`
+	// Slug identifica la categoría entre sus hermanas; Path une los slugs
+	// desde la raíz ("ropa/hombre/pantalones").
+	Slug     string `json:"slug" firestore:"slug"`
+	ParentID string `json:"parentId,omitempty" firestore:"parentId"`
+	Path     string `json:"path" firestore:"path"`
+	// Ancestors son los IDs desde la raíz hasta el padre.
+	Ancestors []string `json:"ancestors" firestore:"ancestors"`
+	Depth     int      `json:"depth" firestore:"depth"`
+	// Attributes es el esquema de atributos de las variaciones; las
+	// subcategorías heredan el de sus ancestros.
+	Attributes []AttributeDefinition `json:"attributes,omitempty" firestore:"attributes,omitempty"`
+	CreatedAt  time.Time             `json:"createdAt" firestore:"createdAt"`
+	UpdatedAt  time.Time             `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// Lineage devuelve los IDs de los ancestros y de la propia categoría; es lo
+// que se guarda en los productos para filtrar por subárbol.
+func (c *Category) Lineage() []string {
+	return append(append([]string{}, c.Ancestors...), c.ID)
+}
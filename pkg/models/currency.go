@@ -0,0 +1,52 @@
+package models
+
+import "strings"
+
+// DefaultCurrencyExponent se usa para convertir importes antiguos de
+// productos sin moneda o con un código desconocido.
+const DefaultCurrencyExponent = 2
+
+// currencyExponents son los decimales (exponente ISO 4217) de cada moneda
+// aceptada.
+var currencyExponents = map[string]int{
+	// Américas
+	"ARS": 2, "BOB": 2, "BRL": 2, "CAD": 2, "CLP": 0, "COP": 2, "CRC": 2,
+	"DOP": 2, "GTQ": 2, "HNL": 2, "MXN": 2, "NIO": 2, "PAB": 2, "PEN": 2,
+	"PYG": 0, "USD": 2, "UYU": 2, "VES": 2, "JMD": 2, "TTD": 2, "BSD": 2,
+	"BZD": 2, "HTG": 2, "CUP": 2, "GYD": 2, "SRD": 2, "CLF": 4, "UYI": 0,
+	// Europa
+	"EUR": 2, "GBP": 2, "CHF": 2, "SEK": 2, "NOK": 2, "DKK": 2, "ISK": 0,
+	"PLN": 2, "CZK": 2, "HUF": 2, "RON": 2, "BGN": 2, "RSD": 2, "UAH": 2,
+	"TRY": 2, "RUB": 2,
+	// Asia y Oceanía
+	"JPY": 0, "KRW": 0, "CNY": 2, "HKD": 2, "TWD": 2, "SGD": 2, "INR": 2,
+	"IDR": 2, "MYR": 2, "PHP": 2, "THB": 2, "VND": 0, "AUD": 2, "NZD": 2,
+	"PKR": 2, "BDT": 2, "LKR": 2, "ILS": 2, "AED": 2, "SAR": 2, "QAR": 2,
+	"BHD": 3, "KWD": 3, "OMR": 3, "JOD": 3, "IQD": 3,
+	// África
+	"ZAR": 2, "EGP": 2, "NGN": 2, "KES": 2, "MAD": 2, "GHS": 2, "TND": 3,
+	"LYD": 3, "XOF": 0, "XAF": 0, "UGX": 0, "RWF": 0, "BIF": 0, "DJF": 0,
+	"GNF": 0, "KMF": 0,
+}
+
+// CurrencyExponent devuelve los decimales de la moneda y si el código es
+// válido. El código no distingue mayúsculas.
+func CurrencyExponent(code string) (int, bool) {
+	exponent, ok := currencyExponents[strings.ToUpper(code)]
+	return exponent, ok
+}
+
+// ValidCurrency indica si el código es una moneda ISO 4217 aceptada.
+func ValidCurrency(code string) bool {
+	_, ok := CurrencyExponent(code)
+	return ok
+}
+
+// exponentOrDefault devuelve los decimales de la moneda o el valor por
+// defecto si el código no es válido.
+func exponentOrDefault(code string) int {
+	if exponent, ok := CurrencyExponent(code); ok {
+		return exponent
+	}
+	return DefaultCurrencyExponent
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// EventType es el tipo de un evento de catálogo.
+type EventType string
+
+const (
+	EventProductCreated       EventType = "product.created"
+	EventProductUpdated       EventType = "product.updated"
+	EventProductDeactivated   EventType = "product.deactivated"
+	EventVariationCreated     EventType = "variation.created"
+	EventVariationUpdated     EventType = "variation.updated"
+	EventVariationDeactivated EventType = "variation.deactivated"
+	EventStockChanged         EventType = "stock.changed"
+)
+
+// EventTypes son todos los tipos de evento, en el orden en que se documentan.
+var EventTypes = []EventType{
+	EventProductCreated, EventProductUpdated, EventProductDeactivated,
+	EventVariationCreated, EventVariationUpdated, EventVariationDeactivated,
+	EventStockChanged,
+}
+
+// ProductEvent es un cambio de catálogo guardado en el outbox en la misma
+// transacción que la escritura que lo produjo. Los IDs ordenan los eventos
+// por fecha de creación.
+type ProductEvent struct {
+	ID             string    `json:"id" firestore:"id"`
+	Type           EventType `json:"type" firestore:"type"`
+	Subdomain      string    `json:"subdomain" firestore:"subdomain"`
+	ProductID      string    `json:"productId" firestore:"productId"`
+	VariationID    string    `json:"variationId,omitempty" firestore:"variationId,omitempty"`
+	ProductVersion int64     `json:"productVersion" firestore:"productVersion"`
+	Actor          Actor     `json:"actor" firestore:"actor"`
+	// ProductStatus y CategoryIDs copian el estado y la rama de categorías del
+	// producto tras la escritura, para filtrar eventos sin leer el producto.
+	ProductStatus ProductStatus `json:"productStatus,omitempty" firestore:"productStatus,omitempty"`
+	CategoryIDs   []string      `json:"categoryIds,omitempty" firestore:"categoryIds,omitempty"`
+	// Data depende del tipo: "product" (el documento completo), "variation",
+	// "changes" (campos modificados) o "movements" (stock.changed).
+	Data map[string]interface{} `json:"data" firestore:"data"`
+	// Dispatched indica que el evento ya se repartió entre los webhooks.
+	Dispatched bool      `json:"-" firestore:"dispatched"`
+	CreatedAt  time.Time `json:"createdAt" firestore:"createdAt"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// ExchangeRateTable son los tipos de cambio de un proyecto. Rates expresa
+// cuántas unidades de cada moneda equivalen a una unidad de BaseCurrency; la
+// moneda base vale siempre 1. Hay una tabla por proyecto (ID = project_id).
+type ExchangeRateTable struct {
+	ProjectID    string                  `json:"project_id" firestore:"project_id"`
+	Subdomain    string                  `json:"subdomain" firestore:"subdomain"`
+	BaseCurrency string                  `json:"baseCurrency" firestore:"baseCurrency"`
+	Rates        map[string]float64      `json:"rates" firestore:"rates"`
+	Rounding     map[string]RoundingRule `json:"rounding,omitempty" firestore:"rounding,omitempty"`
+	UpdatedAt    time.Time               `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// Modos de redondeo de los precios convertidos.
+const (
+	RoundNearest = "nearest"
+	RoundUp      = "up"
+	RoundDown    = "down"
+)
+
+// RoundingRule redondea los precios convertidos a una moneda a múltiplos de
+// Increment terminados en Ending (p. ej. increment 1000 COP y ending 900 COP
+// da precios como 12.900 COP). Los importes están en unidades menores de esa
+// moneda.
+type RoundingRule struct {
+	Increment Money  `json:"increment" firestore:"increment"`
+	Ending    Money  `json:"ending,omitzero" firestore:"ending,omitempty"`
+	Mode      string `json:"mode,omitempty" firestore:"mode,omitempty"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Location es una ubicación física con inventario propio (tienda, bodega)
+// dentro de un proyecto.
+type Location struct {
+	ID        string    `json:"id" firestore:"id"`
+	ProjectID string    `json:"project_id" firestore:"project_id"`
+	Subdomain string    `json:"subdomain" firestore:"subdomain"`
+	Name      string    `json:"name" firestore:"name"`
+	Code      string    `json:"code,omitempty" firestore:"code,omitempty"`
+	Active    bool      `json:"active" firestore:"active"`
+	CreatedAt time.Time `json:"createdAt" firestore:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// StockTransferLine es un SKU y la cantidad que se mueve.
+type StockTransferLine struct {
+	ProductID   string `json:"productId" firestore:"productId"`
+	VariationID string `json:"variationId,omitempty" firestore:"variationId,omitempty"`
+	SKU         string `json:"sku,omitempty" firestore:"sku,omitempty"`
+	Quantity    int    `json:"quantity" firestore:"quantity"`
+}
+
+// StockTransfer mueve cantidades entre dos ubicaciones. Todas las líneas se
+// aplican en una sola transacción; el stock total de cada SKU no cambia.
+type StockTransfer struct {
+	ID             string              `json:"id" firestore:"id"`
+	Subdomain      string              `json:"subdomain" firestore:"subdomain"`
+	FromLocationID string              `json:"fromLocationId" firestore:"fromLocationId"`
+	ToLocationID   string              `json:"toLocationId" firestore:"toLocationId"`
+	Lines          []StockTransferLine `json:"lines" firestore:"lines"`
+	Note           string              `json:"note,omitempty" firestore:"note,omitempty"`
+	Actor          Actor               `json:"actor" firestore:"actor"`
+	CreatedAt      time.Time           `json:"createdAt" firestore:"createdAt"`
+}
@@ -0,0 +1,359 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// Money es un importe exacto en unidades menores de la moneda del producto
+// (centavos en USD y COP, yenes en JPY). En JSON es {"amount": 1999}.
+//
+// Los productos se guardan con el importe exacto en el campo *Minor
+// ({"amount": 1999}); el campo principal lleva el mismo importe como decimal
+// en unidades mayores (19.99) solo por compatibilidad con los clientes y los
+// filtros por precio que aún no leen *Minor (ver withLegacyMoney). Al leer,
+// el importe sale de *Minor; el decimal solo se convierte, con el exponente
+// ISO 4217 de la moneda del producto, cuando falta *Minor.
+type Money int64
+
+var errMoneyFormat = errors.New(`money must be an object like {"amount": 1999} in minor units`)
+
+type moneyJSON struct {
+	Amount *json.Number `json:"amount"`
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`{"amount":%d}`, int64(m))), nil
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value moneyJSON
+	if err := decoder.Decode(&value); err != nil || value.Amount == nil {
+		return errMoneyFormat
+	}
+	amount, err := value.Amount.Int64()
+	if err != nil {
+		return errMoneyFormat
+	}
+	*m = Money(amount)
+	return nil
+}
+
+// MoneyFromMajor convierte un decimal en unidades mayores ("19.99") a unidades
+// menores con el exponente indicado, redondeando a la unidad menor más
+// cercana. La conversión es exacta: no pasa por float64.
+func MoneyFromMajor(decimal string, exponent int) (Money, error) {
+	value, ok := new(big.Rat).SetString(decimal)
+	if !ok {
+		return 0, fmt.Errorf("invalid amount %q", decimal)
+	}
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil))
+	value.Mul(value, scale)
+
+	// Redondeo half away from zero.
+	num, den := value.Num(), value.Denom()
+	quotient, remainder := new(big.Int).QuoRem(num, den, new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2)).Cmp(den) >= 0 {
+		if num.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+	if !quotient.IsInt64() {
+		return 0, fmt.Errorf("amount %q is out of range", decimal)
+	}
+	return Money(quotient.Int64()), nil
+}
+
+// Major devuelve el importe en unidades mayores con el exponente indicado
+// ("19.99"), el formato que acepta MoneyFromMajor.
+func (m Money) Major(exponent int) string {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil)
+	return new(big.Rat).SetFrac(big.NewInt(int64(m)), scale).FloatString(exponent)
+}
+
+// Scale multiplica el importe por factor y redondea a la unidad menor más
+// cercana.
+func (m Money) Scale(factor float64) Money {
+	return Money(math.Round(float64(m) * factor))
+}
+
+// Times devuelve el importe de quantity unidades.
+func (m Money) Times(quantity int) Money {
+	return m * Money(quantity)
+}
+
+// moneyFields son los campos de importe de productos y variaciones que pueden
+// venir en el formato antiguo.
+var moneyFields = []string{"price", "salePrice", "compareAtPrice", "effectivePrice", "filter_price", "max_price"}
+
+// MinorField es el campo que acompaña a un importe en formato antiguo con su
+// valor exacto: priceMinor, filter_price_minor, currencyPricesMinor...
+func MinorField(field string) string {
+	if strings.Contains(field, "_") {
+		return field + "_minor"
+	}
+	return field + "Minor"
+}
+
+// withLegacyMoney reescribe en data los importes {"amount": ...} como números
+// en unidades mayores (el formato antiguo, que siguen leyendo los clientes y
+// los filtros por precio) y guarda el objeto en el campo *Minor. data debe
+// venir de un decoder con UseNumber.
+func withLegacyMoney(data map[string]interface{}, exponent int) {
+	major := func(value interface{}, exponent int) (json.Number, bool) {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		number, ok := object["amount"].(json.Number)
+		if !ok {
+			return "", false
+		}
+		amount, err := number.Int64()
+		if err != nil {
+			return "", false
+		}
+		return json.Number(Money(amount).Major(exponent)), true
+	}
+	legacy := func(container map[string]interface{}, field string) {
+		if number, ok := major(container[field], exponent); ok {
+			container[MinorField(field)] = container[field]
+			container[field] = number
+		}
+	}
+
+	for _, field := range moneyFields {
+		legacy(data, field)
+	}
+	if prices, ok := data["currencyPrices"].(map[string]interface{}); ok {
+		legacyPrices := make(map[string]interface{}, len(prices))
+		for code, value := range prices {
+			if number, ok := major(value, exponentOrDefault(code)); ok {
+				legacyPrices[code] = number
+			}
+		}
+		data[MinorField("currencyPrices")] = prices
+		data["currencyPrices"] = legacyPrices
+	}
+	if tiers, ok := data["priceTiers"].([]interface{}); ok {
+		for _, tier := range tiers {
+			if tierData, ok := tier.(map[string]interface{}); ok {
+				legacy(tierData, "unitPrice")
+			}
+		}
+	}
+}
+
+// encodeWithLegacyMoney serializa value y le aplica withLegacyMoney (a él y a
+// sus variaciones).
+func encodeWithLegacyMoney(value interface{}, currency string) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var raw map[string]interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, err
+	}
+	exponent := exponentOrDefault(currency)
+	withLegacyMoney(raw, exponent)
+	if variations, ok := raw["variations"].([]interface{}); ok {
+		for _, variation := range variations {
+			if variationData, ok := variation.(map[string]interface{}); ok {
+				withLegacyMoney(variationData, exponent)
+			}
+		}
+	}
+	return json.Marshal(raw)
+}
+
+// convertLegacyMoney deja en data los importes en el formato {"amount": ...}
+// y quita los campos *Minor. Un decimal en el campo principal se reemplaza por
+// el valor exacto de su *Minor y solo se convierte si este falta; un
+// {"amount": ...} en el campo principal ya es exacto y se respeta. Devuelve si
+// algún importe venía sin su campo *Minor: un documento así se guardó antes de
+// la migración (número) o con el formato intermedio que solo tenía
+// {"amount": ...}, y sus filtros por precio no funcionan hasta volver a
+// guardarlo. data debe venir de un decoder con UseNumber para que la
+// conversión sea exacta.
+func convertLegacyMoney(data map[string]interface{}, exponent int) (bool, error) {
+	converted := false
+	convert := func(container map[string]interface{}, field string) error {
+		value, present := container[field]
+		minor, migrated := container[MinorField(field)]
+		delete(container, MinorField(field))
+		if present && value != nil && !migrated {
+			converted = true
+		}
+		number, ok := value.(json.Number)
+		if ok && migrated {
+			container[field] = minor
+			return nil
+		}
+		if !ok {
+			return nil
+		}
+		amount, err := MoneyFromMajor(number.String(), exponent)
+		if err != nil {
+			return fmt.Errorf("%s: %w", field, err)
+		}
+		container[field] = map[string]interface{}{"amount": int64(amount)}
+		return nil
+	}
+
+	for _, field := range moneyFields {
+		if err := convert(data, field); err != nil {
+			return false, err
+		}
+	}
+	// Los precios por moneda usan los decimales de su propia moneda.
+	minorPrices, migratedPrices := data[MinorField("currencyPrices")].(map[string]interface{})
+	delete(data, MinorField("currencyPrices"))
+	if prices, ok := data["currencyPrices"].(map[string]interface{}); ok {
+		if len(prices) > 0 && !migratedPrices {
+			converted = true
+		}
+		for code, value := range prices {
+			if exact, ok := minorPrices[code]; ok {
+				if _, legacy := value.(json.Number); legacy {
+					prices[code] = exact
+					continue
+				}
+			}
+			number, ok := value.(json.Number)
+			if !ok {
+				continue
+			}
+			amount, err := MoneyFromMajor(number.String(), exponentOrDefault(code))
+			if err != nil {
+				return false, fmt.Errorf("currencyPrices.%s: %w", code, err)
+			}
+			prices[code] = map[string]interface{}{"amount": int64(amount)}
+		}
+	}
+	if tiers, ok := data["priceTiers"].([]interface{}); ok {
+		for _, tier := range tiers {
+			if tierData, ok := tier.(map[string]interface{}); ok {
+				if err := convert(tierData, "unitPrice"); err != nil {
+					return false, err
+				}
+			}
+		}
+	}
+	return converted, nil
+}
+
+// decodeWithLegacyMoney decodifica data en un mapa con los importes ya
+// convertidos al formato nuevo.
+func decodeWithLegacyMoney(data []byte, currency func(map[string]interface{}) string) (map[string]interface{}, bool, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var raw map[string]interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, false, err
+	}
+	exponent := exponentOrDefault(currency(raw))
+
+	converted, err := convertLegacyMoney(raw, exponent)
+	if err != nil {
+		return nil, false, err
+	}
+	if variations, ok := raw["variations"].([]interface{}); ok {
+		for _, variation := range variations {
+			variationData, ok := variation.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			variationConverted, err := convertLegacyMoney(variationData, exponent)
+			if err != nil {
+				return nil, false, err
+			}
+			converted = converted || variationConverted
+		}
+	}
+	return raw, converted, nil
+}
+
+// MarshalJSON emite los importes en el formato antiguo con el valor exacto al
+// lado (ver withLegacyMoney). También es el formato en que se guardan: el
+// valor que cuenta es el de *Minor.
+func (p Product) MarshalJSON() ([]byte, error) {
+	type plain Product
+	return encodeWithLegacyMoney(plain(p), p.Currency)
+}
+
+// UnmarshalJSON toma los importes de los campos *Minor y, si faltan, acepta
+// el formato antiguo y lo convierte con la moneda del producto.
+func (p *Product) UnmarshalJSON(data []byte) error {
+	raw, converted, err := decodeWithLegacyMoney(data, func(raw map[string]interface{}) string {
+		currency, _ := raw["currency"].(string)
+		return currency
+	})
+	if err != nil {
+		return err
+	}
+	if raw == nil {
+		return nil
+	}
+	normalized, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+
+	type plain Product
+	var decoded plain
+	if err := json.Unmarshal(normalized, &decoded); err != nil {
+		return err
+	}
+	*p = Product(decoded)
+	p.legacyMoney = converted
+	return nil
+}
+
+// HasLegacyMoney indica si el producto se leyó con importes sin su campo
+// *Minor (formato antiguo o intermedio) y hay que volver a guardarlo.
+func (p *Product) HasLegacyMoney() bool {
+	return p.legacyMoney
+}
+
+// CurrencyVariation es una variación suelta (p. ej. la respuesta de
+// CreateVariation) con la moneda de su producto, para emitirla en el mismo
+// formato que las variaciones dentro del producto.
+type CurrencyVariation struct {
+	Variation
+	Currency string
+}
+
+func (v CurrencyVariation) MarshalJSON() ([]byte, error) {
+	return encodeWithLegacyMoney(v.Variation, v.Currency)
+}
+
+// DecodeVariation decodifica una variación suelta (p. ej. el cuerpo de
+// CreateVariation) aceptando importes antiguos en la moneda indicada.
+func DecodeVariation(data []byte, currency string) (Variation, error) {
+	var variation Variation
+	raw, _, err := decodeWithLegacyMoney(data, func(map[string]interface{}) string { return currency })
+	if err != nil {
+		return variation, err
+	}
+	normalized, err := json.Marshal(raw)
+	if err != nil {
+		return variation, err
+	}
+	err = json.Unmarshal(normalized, &variation)
+	return variation, err
+}
@@ -0,0 +1,222 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMoneyFromMajor(t *testing.T) {
+	tests := []struct {
+		decimal  string
+		exponent int
+		want     Money
+		wantErr  bool
+	}{
+		{"19.99", 2, 1999, false},
+		{"0.1", 2, 10, false},
+		{"1.005", 2, 101, false},
+		{"1.004", 2, 100, false},
+		{"-1.005", 2, -101, false},
+		{"1500", 0, 1500, false},
+		{"1500.5", 0, 1501, false},
+		{"2.5", 3, 2500, false},
+		{"1e2", 2, 10000, false},
+		{"abc", 2, 0, true},
+		{"100000000000000000000", 2, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := MoneyFromMajor(tt.decimal, tt.exponent)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("MoneyFromMajor(%q, %d) = %d, %v; want %d, error %t", tt.decimal, tt.exponent, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestMoneyMajor(t *testing.T) {
+	tests := []struct {
+		amount   Money
+		exponent int
+		want     string
+	}{
+		{1999, 2, "19.99"},
+		{5, 2, "0.05"},
+		{-150, 2, "-1.50"},
+		{1500, 0, "1500"},
+		{2500, 3, "2.500"},
+	}
+	for _, tt := range tests {
+		if got := tt.amount.Major(tt.exponent); got != tt.want {
+			t.Errorf("Money(%d).Major(%d) = %q, want %q", tt.amount, tt.exponent, got, tt.want)
+		}
+		back, err := MoneyFromMajor(tt.amount.Major(tt.exponent), tt.exponent)
+		if err != nil || back != tt.amount {
+			t.Errorf("round trip of %d = %d, %v", tt.amount, back, err)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	data, err := json.Marshal(Money(1999))
+	if err != nil || string(data) != `{"amount":1999}` {
+		t.Fatalf("Marshal = %s, %v", data, err)
+	}
+	tests := []struct {
+		input   string
+		want    Money
+		wantErr bool
+	}{
+		{`{"amount": 1999}`, 1999, false},
+		{`{"amount": -5}`, -5, false},
+		{`null`, 0, false},
+		{`19.99`, 0, true},
+		{`{"amount": 19.99}`, 0, true},
+		{`{}`, 0, true},
+		{`"1999"`, 0, true},
+	}
+	for _, tt := range tests {
+		var m Money
+		err := json.Unmarshal([]byte(tt.input), &m)
+		if (err != nil) != tt.wantErr || m != tt.want {
+			t.Errorf("Unmarshal(%s) = %d, %v; want %d, error %t", tt.input, m, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// decodeMap decodifica data como lo leería un cliente.
+func decodeMap(t *testing.T, data []byte) map[string]interface{} {
+	t.Helper()
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestProductJSONKeepsLegacyShape(t *testing.T) {
+	product := Product{
+		ID:          "p1",
+		Currency:    "USD",
+		FilterPrice: 1000,
+		MaxPrice:    1999,
+		Variations: []Variation{{
+			ID:             "v1",
+			Price:          1999,
+			PriceTiers:     []PriceTier{{MinQuantity: 10, UnitPrice: 1500}},
+			CurrencyPrices: map[string]Money{"JPY": 3000, "COP": 8000000},
+			SalePricing:    SalePricing{SalePrice: 1000, EffectivePrice: 1000},
+		}},
+	}
+	data, err := json.Marshal(product)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := decodeMap(t, data)
+	if m["filter_price"] != 10.0 || m["max_price"] != 19.99 {
+		t.Errorf("filter_price = %v, max_price = %v", m["filter_price"], m["max_price"])
+	}
+	if !reflect.DeepEqual(m["filter_price_minor"], map[string]interface{}{"amount": 1000.0}) {
+		t.Errorf("filter_price_minor = %v", m["filter_price_minor"])
+	}
+	v := m["variations"].([]interface{})[0].(map[string]interface{})
+	if v["price"] != 19.99 || v["salePrice"] != 10.0 || v["effectivePrice"] != 10.0 {
+		t.Errorf("variation amounts = %v", v)
+	}
+	if !reflect.DeepEqual(v["priceMinor"], map[string]interface{}{"amount": 1999.0}) {
+		t.Errorf("priceMinor = %v", v["priceMinor"])
+	}
+	tier := v["priceTiers"].([]interface{})[0].(map[string]interface{})
+	if tier["unitPrice"] != 15.0 || !reflect.DeepEqual(tier["unitPriceMinor"], map[string]interface{}{"amount": 1500.0}) {
+		t.Errorf("tier = %v", tier)
+	}
+	// Cada precio por moneda usa sus propios decimales.
+	wantPrices := map[string]interface{}{"JPY": 3000.0, "COP": 80000.0}
+	if !reflect.DeepEqual(v["currencyPrices"], wantPrices) {
+		t.Errorf("currencyPrices = %v, want %v", v["currencyPrices"], wantPrices)
+	}
+
+	var decoded Product
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.HasLegacyMoney() {
+		t.Error("a product written with the *Minor fields is reported as legacy")
+	}
+	decoded.legacyMoney = false
+	if !reflect.DeepEqual(decoded, product) {
+		t.Errorf("round trip = %+v, want %+v", decoded, product)
+	}
+}
+
+func TestProductUnmarshalFormats(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		wantPrice  Money
+		wantLegacy bool
+	}{
+		{"legacy float", `{"currency": "USD", "price": 19.99}`, 1999, true},
+		{"legacy float without decimals", `{"currency": "JPY", "price": 1500}`, 1500, true},
+		{"legacy with unknown currency", `{"currency": "", "price": 1.5}`, 150, true},
+		{"new format without minor field", `{"currency": "USD", "price": {"amount": 1999}}`, 1999, true},
+		{"legacy with minor field", `{"currency": "USD", "price": 19.99, "priceMinor": {"amount": 1999}}`, 1999, false},
+		{"price wins over stale minor field", `{"currency": "USD", "price": {"amount": 1200}, "priceMinor": {"amount": 1999}}`, 1200, false},
+		{"minor field wins over the decimal", `{"currency": "USD", "price": 19.98, "priceMinor": {"amount": 1999}}`, 1999, false},
+		{"minor field beyond float precision", `{"currency": "USD", "price": 90071992547409.92, "priceMinor": {"amount": 9007199254740993}}`, 9007199254740993, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var product Product
+			if err := json.Unmarshal([]byte(tt.input), &product); err != nil {
+				t.Fatal(err)
+			}
+			if product.Price != tt.wantPrice || product.HasLegacyMoney() != tt.wantLegacy {
+				t.Errorf("price = %d, legacy = %t; want %d, %t", product.Price, product.HasLegacyMoney(), tt.wantPrice, tt.wantLegacy)
+			}
+		})
+	}
+
+	var product Product
+	if err := json.Unmarshal([]byte(`{"currency": "USD", "price": "19.99"}`), &product); err == nil {
+		t.Error("a string price was accepted")
+	}
+}
+
+func TestUnmarshalPrefersMinorFields(t *testing.T) {
+	input := `{"currency": "USD", "variations": [{
+		"price": 10, "priceMinor": {"amount": 1001},
+		"priceTiers": [{"minQuantity": 5, "unitPrice": 9, "unitPriceMinor": {"amount": 901}}],
+		"currencyPrices": {"COP": 40000, "JPY": 1500}, "currencyPricesMinor": {"COP": {"amount": 4000050}}
+	}]}`
+	var product Product
+	if err := json.Unmarshal([]byte(input), &product); err != nil {
+		t.Fatal(err)
+	}
+	v := product.Variations[0]
+	if v.Price != 1001 || v.PriceTiers[0].UnitPrice != 901 {
+		t.Errorf("price = %d, tier = %d; want the *Minor amounts", v.Price, v.PriceTiers[0].UnitPrice)
+	}
+	// JPY no tiene su *Minor y se convierte desde el decimal.
+	if v.CurrencyPrices["COP"] != 4000050 || v.CurrencyPrices["JPY"] != 1500 {
+		t.Errorf("currencyPrices = %v", v.CurrencyPrices)
+	}
+}
+
+func TestDecodeVariation(t *testing.T) {
+	v, err := DecodeVariation([]byte(`{"sku": "A", "price": 25, "salePrice": {"amount": 2000}, "priceTiers": [{"minQuantity": 5, "unitPrice": 22.5}]}`), "COP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Price != 2500 || v.SalePrice != 2000 || v.PriceTiers[0].UnitPrice != 2250 {
+		t.Errorf("variation = %+v", v)
+	}
+
+	data, err := json.Marshal(CurrencyVariation{Variation: v, Currency: "COP"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := decodeMap(t, data)
+	if m["price"] != 25.0 || m["salePrice"] != 20.0 {
+		t.Errorf("CurrencyVariation JSON = %s", data)
+	}
+}
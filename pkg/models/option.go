@@ -0,0 +1,64 @@
+package models
+
+import "fmt"
+
+// ProductOption es un eje de variación del producto, p. ej. talla: S, M, L.
+// Cada variación generada guarda el valor de cada opción en Attributes.
+type ProductOption struct {
+	Name   string   `json:"name" firestore:"name"`
+	Values []string `json:"values" firestore:"values"`
+}
+
+// ValidateOptions comprueba que las opciones tengan nombre y valores, sin
+// nombres ni valores repetidos.
+func ValidateOptions(options []ProductOption) error {
+	names := map[string]bool{}
+	for i, option := range options {
+		if option.Name == "" {
+			return fmt.Errorf("options[%d]: name is required", i)
+		}
+		if names[option.Name] {
+			return fmt.Errorf("option %q is defined more than once", option.Name)
+		}
+		names[option.Name] = true
+
+		if len(option.Values) == 0 {
+			return fmt.Errorf("option %q must have at least one value", option.Name)
+		}
+		values := map[string]bool{}
+		for _, value := range option.Values {
+			if value == "" {
+				return fmt.Errorf("option %q has an empty value", option.Name)
+			}
+			if values[value] {
+				return fmt.Errorf("option %q repeats the value %q", option.Name, value)
+			}
+			values[value] = true
+		}
+	}
+	return nil
+}
+
+// OptionCombinations devuelve el producto cartesiano de las opciones, en el
+// orden en que están declaradas (la última opción varía más rápido).
+func OptionCombinations(options []ProductOption) []map[string]string {
+	if len(options) == 0 {
+		return nil
+	}
+	combinations := []map[string]string{{}}
+	for _, option := range options {
+		next := make([]map[string]string, 0, len(combinations)*len(option.Values))
+		for _, combination := range combinations {
+			for _, value := range option.Values {
+				attributes := make(map[string]string, len(combination)+1)
+				for k, v := range combination {
+					attributes[k] = v
+				}
+				attributes[option.Name] = value
+				next = append(next, attributes)
+			}
+		}
+		combinations = next
+	}
+	return combinations
+}
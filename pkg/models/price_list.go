@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// PriceList define precios especiales para un grupo de clientes (p. ej.
+// mayoristas) dentro de un subdominio. Los SKUs con override usan ese precio;
+// el resto usa la primera regla porcentual que coincida o el precio normal.
+type PriceList struct {
+	ID            string    `json:"id" firestore:"id"`
+	ProjectID     string    `json:"project_id" firestore:"project_id"`
+	Subdomain     string    `json:"subdomain" firestore:"subdomain"`
+	Name          string    `json:"name" firestore:"name"`
+	CustomerGroup string    `json:"customerGroup" firestore:"customerGroup"`
+	Active        bool      `json:"active" firestore:"active"`
+	CreatedAt     time.Time `json:"createdAt" firestore:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt" firestore:"updatedAt"`
+
+	// Overrides fija el precio de SKUs concretos (SKU → precio).
+	Overrides map[string]Money `json:"overrides,omitempty" firestore:"overrides,omitempty"`
+	// Rules se evalúan en orden para los SKUs sin override.
+	Rules []PriceRule `json:"rules,omitempty" firestore:"rules,omitempty"`
+}
+
+// PriceRule ajusta el precio normal en Percent por ciento (negativo para un
+// descuento). Category y Brand vacíos coinciden con cualquier producto.
+type PriceRule struct {
+	Category string  `json:"category,omitempty" firestore:"category,omitempty"`
+	Brand    string  `json:"brand,omitempty" firestore:"brand,omitempty"`
+	Percent  float64 `json:"percent" firestore:"percent"`
+}
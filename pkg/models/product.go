@@ -9,41 +9,133 @@ type Variation struct {
 	ID         string            `json:"id" firestore:"id"`
 	SKU        string            `json:"sku" firestore:"sku"`
 	Barcode    string            `json:"barcode,omitempty" firestore:"barcode,omitempty"`
-	Price      float64           `json:"price" firestore:"price"`
+	Price      Money             `json:"price" firestore:"price"`
 	ImageURL   string            `json:"imageUrl,omitempty" firestore:"imageUrl,omitempty"`
-	Stock      int               `json:"stock" firestore:"stock"` 
+	Stock      int               `json:"stock" firestore:"stock"`
 	Attributes map[string]string `json:"attributes" firestore:"attributes"`
 	Active     bool              `json:"active" firestore:"active"`
+	// Inventory es el stock por ubicación (ID de ubicación → cantidad). Si
+	// tiene datos, Stock es siempre su suma.
+	Inventory map[string]int `json:"inventory,omitempty" firestore:"inventory,omitempty"`
+	// PriceTiers son precios unitarios por volumen, de menor a mayor cantidad.
+	PriceTiers []PriceTier `json:"priceTiers,omitempty" firestore:"priceTiers,omitempty"`
+	// CurrencyPrices fija el precio en otras monedas (código → importe en
+	// unidades menores de esa moneda); tiene prioridad sobre la conversión.
+	CurrencyPrices map[string]Money `json:"currencyPrices,omitempty" firestore:"currencyPrices,omitempty"`
+	// DeletedAt y DeletedBy registran la baja de la variación (ver Product).
+	DeletedAt *time.Time `json:"deletedAt,omitempty" firestore:"deletedAt,omitempty"`
+	DeletedBy *Actor     `json:"deletedBy,omitempty" firestore:"deletedBy,omitempty"`
+
+	SalePricing
+}
+
+// PriceTier es el precio unitario a partir de MinQuantity unidades.
+type PriceTier struct {
+	MinQuantity int   `json:"minQuantity" firestore:"minQuantity"`
+	UnitPrice   Money `json:"unitPrice" firestore:"unitPrice"`
+}
+
+// SalePricing es un precio de oferta programado. Fuera de la ventana
+// [SaleStartsAt, SaleEndsAt) rige el precio normal. Una fecha nula deja la
+// ventana abierta por ese lado.
+type SalePricing struct {
+	CompareAtPrice Money      `json:"compareAtPrice,omitzero" firestore:"compareAtPrice,omitempty"`
+	SalePrice      Money      `json:"salePrice,omitzero" firestore:"salePrice,omitempty"`
+	SaleStartsAt   *time.Time `json:"saleStartsAt,omitempty" firestore:"saleStartsAt,omitempty"`
+	SaleEndsAt     *time.Time `json:"saleEndsAt,omitempty" firestore:"saleEndsAt,omitempty"`
+
+	// EffectivePrice y OnSale se resuelven en cada escritura y de nuevo al leer.
+	EffectivePrice Money `json:"effectivePrice" firestore:"effectivePrice"`
+	OnSale         bool  `json:"onSale" firestore:"onSale"`
+	// Tax desglosa el precio normal y el vigente en neto, impuesto y bruto
+	// según la tabla de impuestos del proyecto. Solo existe en las respuestas.
+	Tax *PriceTax `json:"tax,omitempty" firestore:"-"`
 }
 
 // Product ahora puede ser simple O tener variaciones.
 type Product struct {
-	ID          string    `json:"id" firestore:"id"`
-	Name        string    `json:"name" firestore:"name"`
-	Description string    `json:"description" firestore:"description"`
-	Brand       string    `json:"brand,omitempty" firestore:"brand,omitempty"`
-	Category    string    `json:"category" firestore:"category"`
-	Currency    string    `json:"currency" firestore:"currency"`
+	ID          string `json:"id" firestore:"id"`
+	Name        string `json:"name" firestore:"name"`
+	Description string `json:"description" firestore:"description"`
+	Brand       string `json:"brand,omitempty" firestore:"brand,omitempty"`
+	Category    string `json:"category" firestore:"category"`
+	// CategoryID referencia el árbol de categorías. Si está, Category es la
+	// ruta de la categoría y CategoryIDs sus ancestros más ella misma.
+	CategoryID  string   `json:"categoryId,omitempty" firestore:"categoryId,omitempty"`
+	CategoryIDs []string `json:"category_ids,omitempty" firestore:"category_ids,omitempty"`
+	// TaxCategory elige la tasa en la tabla de impuestos del proyecto; vacío
+	// equivale a "standard".
+	TaxCategory string    `json:"taxCategory,omitempty" firestore:"taxCategory,omitempty"`
+	Currency    string    `json:"currency" firestore:"currency"` // ISO 4217; los importes están en sus unidades menores
 	Active      bool      `json:"active" firestore:"active"`
 	ProjectID   string    `json:"project_id" firestore:"project_id"`
 	Subdomain   string    `json:"subdomain" firestore:"subdomain"`
 	CreatedAt   time.Time `json:"createdAt" firestore:"createdAt"`
 	UpdatedAt   time.Time `json:"updatedAt" firestore:"updatedAt"`
-	FilterPrice float64 `json:"filter_price" firestore:"filter_price"`
+	// Status es el estado del ciclo de vida (draft → published → archived).
+	// Active se mantiene por compatibilidad y equivale a Status == published.
+	Status ProductStatus `json:"status" firestore:"status"`
+	// PublishAt programa la publicación de un borrador.
+	PublishAt *time.Time `json:"publishAt,omitempty" firestore:"publishAt,omitempty"`
+	// DeletedAt y DeletedBy registran el soft delete: el producto queda en la
+	// papelera hasta que se restaura o la retención lo elimina.
+	DeletedAt *time.Time `json:"deletedAt,omitempty" firestore:"deletedAt,omitempty"`
+	DeletedBy *Actor     `json:"deletedBy,omitempty" firestore:"deletedBy,omitempty"`
+	// Campos derivados: los recalcula el repositorio en cada escritura.
+	FilterPrice          Money `json:"filter_price" firestore:"filter_price"`
+	MaxPrice             Money `json:"max_price" firestore:"max_price"`
+	TotalStock           int   `json:"total_stock" firestore:"total_stock"`
+	InStock              bool  `json:"in_stock" firestore:"in_stock"`
+	ActiveVariationCount int   `json:"active_variation_count" firestore:"active_variation_count"`
+	// NextPriceChangeAt es el próximo inicio o fin de una oferta; el
+	// programador de ofertas vuelve a derivar el producto en ese momento.
+	NextPriceChangeAt *time.Time `json:"next_price_change_at,omitempty" firestore:"next_price_change_at,omitempty"`
+	// VariationDeletedAt es la baja más antigua entre las variaciones; la
+	// retención de la papelera la usa para encontrar variaciones a eliminar.
+	VariationDeletedAt *time.Time `json:"variation_deleted_at,omitempty" firestore:"variation_deleted_at,omitempty"`
+	// PriceListID es la lista de precios aplicada en la respuesta; no se
+	// guarda.
+	PriceListID string `json:"priceListId,omitempty" firestore:"-"`
+	// ConvertedFrom es la moneda original cuando la respuesta se convirtió a
+	// otra con el parámetro currency; no se guarda.
+	ConvertedFrom string `json:"convertedFrom,omitempty" firestore:"-"`
+	// Version se incrementa en cada escritura y se expone como ETag.
+	Version int64 `json:"version" firestore:"version"`
 
 	// --- CAMPOS PARA PRODUCTO SIMPLE ---
 	// Estos campos se usan si el array 'variations' está vacío.
-	SKU      string  `json:"sku,omitempty" firestore:"sku,omitempty"`
-	Price    float64 `json:"price,omitempty" firestore:"price,omitempty"`
-	Stock    int     `json:"stock,omitempty" firestore:"stock,omitempty"`
-	Barcode  string  `json:"barcode,omitempty" firestore:"barcode,omitempty"`
-	ImageURL string  `json:"imageUrl,omitempty" firestore:"imageUrl,omitempty"`
+	SKU      string `json:"sku,omitempty" firestore:"sku,omitempty"`
+	Price    Money  `json:"price,omitzero" firestore:"price,omitempty"`
+	Stock    int    `json:"stock,omitempty" firestore:"stock,omitempty"`
+	Barcode  string `json:"barcode,omitempty" firestore:"barcode,omitempty"`
+	ImageURL string `json:"imageUrl,omitempty" firestore:"imageUrl,omitempty"`
+	// Inventory es el stock por ubicación del producto simple (ver Variation).
+	Inventory map[string]int `json:"inventory,omitempty" firestore:"inventory,omitempty"`
+	// PriceTiers son los precios por volumen del producto simple.
+	PriceTiers []PriceTier `json:"priceTiers,omitempty" firestore:"priceTiers,omitempty"`
+	// CurrencyPrices son los precios fijos en otras monedas del producto simple.
+	CurrencyPrices map[string]Money `json:"currencyPrices,omitempty" firestore:"currencyPrices,omitempty"`
+	// Oferta del producto simple. En productos con variaciones cada variación
+	// tiene la suya; aquí EffectivePrice es el mínimo y OnSale indica si
+	// alguna variación activa está en oferta.
+	SalePricing
 
 	// --- CAMPO PARA VARIACIONES ---
 	Variations []Variation `json:"variations,omitempty" firestore:"variations,omitempty"`
+	// Type es vacío para los productos normales o ProductTypeBundle.
+	Type string `json:"type,omitempty" firestore:"type,omitempty"`
+	// Components son los SKUs que forman un bundle; su stock se calcula a
+	// partir del de los componentes.
+	Components []BundleComponent `json:"components,omitempty" firestore:"components,omitempty"`
+	// Options declara los ejes de las variaciones (talla, color...) para
+	// generar la matriz de combinaciones.
+	Options []ProductOption `json:"options,omitempty" firestore:"options,omitempty"`
 
 	// Otros campos que ya tenías
 	Weight     float64                `json:"weight,omitempty" firestore:"weight,omitempty"`
 	Dimensions map[string]float64     `json:"dimensions,omitempty" firestore:"dimensions,omitempty"`
 	Metadata   map[string]interface{} `json:"metadata,omitempty" firestore:"metadata,omitempty"`
-}
\ No newline at end of file
+
+	// legacyMoney indica que el documento tenía importes en el formato antiguo.
+	legacyMoney bool
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// ReservationStatus es el estado de una reserva de stock.
+type ReservationStatus string
+
+const (
+	ReservationActive    ReservationStatus = "active"
+	ReservationCommitted ReservationStatus = "committed"
+	ReservationReleased  ReservationStatus = "released"
+	ReservationExpired   ReservationStatus = "expired"
+)
+
+// ReservationLine identifica qué se reserva: una variación (VariationID) o,
+// para productos simples, el SKU del producto. SKU también puede apuntar a una
+// variación si no se conoce su ID.
+type ReservationLine struct {
+	ProductID   string `json:"productId" firestore:"productId"`
+	VariationID string `json:"variationId,omitempty" firestore:"variationId,omitempty"`
+	SKU         string `json:"sku,omitempty" firestore:"sku,omitempty"`
+	Quantity    int    `json:"quantity" firestore:"quantity"`
+	// LocationID fija la ubicación de la que se descuenta. Si está vacío y el
+	// SKU tiene stock por ubicación, se reparte entre ubicaciones.
+	LocationID string `json:"locationId,omitempty" firestore:"locationId,omitempty"`
+	// Allocations registra cuánto se tomó de cada ubicación para devolverlo
+	// al mismo sitio al liberar la reserva.
+	Allocations map[string]int `json:"allocations,omitempty" firestore:"allocations,omitempty"`
+	// BundleProductID indica el bundle pedido cuando la línea es uno de sus
+	// componentes: las líneas de bundles se reservan componente a componente.
+	BundleProductID string `json:"bundleProductId,omitempty" firestore:"bundleProductId,omitempty"`
+}
+
+// Reservation retiene stock durante el checkout hasta que se confirma
+// (commit), se libera (release) o vence su TTL.
+type Reservation struct {
+	ID        string            `json:"id" firestore:"id"`
+	Subdomain string            `json:"subdomain" firestore:"subdomain"`
+	Status    ReservationStatus `json:"status" firestore:"status"`
+	Lines     []ReservationLine `json:"lines" firestore:"lines"`
+	ExpiresAt time.Time         `json:"expiresAt" firestore:"expiresAt"`
+	CreatedAt time.Time         `json:"createdAt" firestore:"createdAt"`
+	UpdatedAt time.Time         `json:"updatedAt" firestore:"updatedAt"`
+}
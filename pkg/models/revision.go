@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// ProductRevision es la foto de un producto tras una escritura. Revision es
+// la versión que dejó la escritura, así que la primera revisión es la 1.
+type ProductRevision struct {
+	ID        string `json:"id" firestore:"id"`
+	ProductID string `json:"productId" firestore:"productId"`
+	Subdomain string `json:"subdomain" firestore:"subdomain"`
+	Revision  int64  `json:"revision" firestore:"revision"`
+	Actor     Actor  `json:"actor" firestore:"actor"`
+	// Source es el endpoint que originó la escritura, p. ej.
+	// "PATCH /api/v1/products/:id". Vacío para procesos internos.
+	Source string `json:"source,omitempty" firestore:"source,omitempty"`
+	// Changes son los campos que cambiaron respecto a la revisión anterior.
+	// Está vacío en la revisión que crea el producto.
+	Changes []FieldChange `json:"changes" firestore:"changes"`
+	// Snapshot es el documento completo del producto, con los nombres JSON.
+	Snapshot  map[string]interface{} `json:"snapshot,omitempty" firestore:"snapshot,omitempty"`
+	CreatedAt time.Time              `json:"createdAt" firestore:"createdAt"`
+}
+
+// FieldChange es el cambio de un campo. Field es la ruta con puntos; las
+// variaciones se identifican por ID, p. ej. "variations[var-1].priceMinor.amount".
+type FieldChange struct {
+	Field  string      `json:"field" firestore:"field"`
+	Before interface{} `json:"before" firestore:"before"`
+	After  interface{} `json:"after" firestore:"after"`
+}
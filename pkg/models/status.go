@@ -0,0 +1,38 @@
+package models
+
+// ProductStatus es el estado del ciclo de vida de un producto. Solo los
+// productos publicados se ven en la tienda; Active se mantiene por
+// compatibilidad y equivale a Status == StatusPublished.
+type ProductStatus string
+
+const (
+	StatusDraft     ProductStatus = "draft"
+	StatusPublished ProductStatus = "published"
+	StatusArchived  ProductStatus = "archived"
+)
+
+// statusTransitions son los cambios de estado permitidos.
+var statusTransitions = map[ProductStatus][]ProductStatus{
+	StatusDraft:     {StatusPublished, StatusArchived},
+	StatusPublished: {StatusDraft, StatusArchived},
+	StatusArchived:  {StatusDraft},
+}
+
+// CanTransitionTo indica si se puede pasar del estado s a to.
+func (s ProductStatus) CanTransitionTo(to ProductStatus) bool {
+	for _, allowed := range statusTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPublished indica si el producto es visible en la tienda. Los documentos
+// anteriores al ciclo de vida (sin status) se guían por Active.
+func (p *Product) IsPublished() bool {
+	if p.Status == "" {
+		return p.Active
+	}
+	return p.Status == StatusPublished
+}
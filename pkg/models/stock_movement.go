@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// MovementReason explica por qué cambió el stock.
+type MovementReason string
+
+const (
+	MovementInitial            MovementReason = "initial"
+	MovementAdjustment         MovementReason = "adjustment"
+	MovementReservation        MovementReason = "reservation"
+	MovementReservationRelease MovementReason = "reservation_release"
+	MovementReservationExpiry  MovementReason = "reservation_expiry"
+	MovementSale               MovementReason = "sale"
+	MovementReturn             MovementReason = "return"
+	MovementImport             MovementReason = "import"
+)
+
+// StockMovement es una entrada del libro de movimientos de stock. Es
+// append-only: nunca se modifica después de escribirse.
+type StockMovement struct {
+	ID          string         `json:"id" firestore:"id"`
+	ProductID   string         `json:"productId" firestore:"productId"`
+	VariationID string         `json:"variationId,omitempty" firestore:"variationId,omitempty"`
+	SKU         string         `json:"sku" firestore:"sku"`
+	Subdomain   string         `json:"subdomain" firestore:"subdomain"`
+	Reason      MovementReason `json:"reason" firestore:"reason"`
+	Delta       int            `json:"delta" firestore:"delta"`
+	Balance     int            `json:"balance" firestore:"balance"`
+	Actor       Actor          `json:"actor" firestore:"actor"`
+	Reference   string         `json:"reference,omitempty" firestore:"reference,omitempty"`
+	Note        string         `json:"note,omitempty" firestore:"note,omitempty"`
+	// ProductVersion es la versión del producto que dejó la escritura; ordena
+	// los movimientos de un mismo producto.
+	ProductVersion int64     `json:"productVersion" firestore:"productVersion"`
+	CreatedAt      time.Time `json:"createdAt" firestore:"createdAt"`
+}
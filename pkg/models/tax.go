@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// DefaultTaxCategory es la categoría de los productos sin TaxCategory.
+const DefaultTaxCategory = "standard"
+
+// TaxTable son las tasas de impuesto de un proyecto por región (p. ej. país
+// o departamento) y categoría de impuesto, en porcentaje (19 = 19 %). Hay una
+// tabla por proyecto (ID = project_id).
+type TaxTable struct {
+	ProjectID string `json:"project_id" firestore:"project_id"`
+	Subdomain string `json:"subdomain" firestore:"subdomain"`
+	// PricesIncludeTax indica si los precios guardados ya incluyen el
+	// impuesto (precio bruto) o son netos.
+	PricesIncludeTax bool `json:"pricesIncludeTax" firestore:"pricesIncludeTax"`
+	// DefaultRegion se usa cuando la petición no indica región.
+	DefaultRegion string                        `json:"defaultRegion" firestore:"defaultRegion"`
+	Regions       map[string]map[string]float64 `json:"regions" firestore:"regions"`
+	UpdatedAt     time.Time                     `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// TaxedAmount es un importe desglosado en neto, impuesto y bruto.
+type TaxedAmount struct {
+	Net   Money `json:"net"`
+	Tax   Money `json:"tax"`
+	Gross Money `json:"gross"`
+}
+
+// PriceTax es el desglose de impuestos de un SKU.
+type PriceTax struct {
+	Region         string      `json:"region"`
+	Category       string      `json:"category"`
+	Rate           float64     `json:"rate"`
+	Price          TaxedAmount `json:"price"`
+	EffectivePrice TaxedAmount `json:"effectivePrice"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// Webhook es una suscripción de un subdominio a eventos de catálogo.
+type Webhook struct {
+	ID        string `json:"id" firestore:"id"`
+	Subdomain string `json:"subdomain" firestore:"subdomain"`
+	URL       string `json:"url" firestore:"url"`
+	// Events limita los tipos de evento enviados; vacío recibe todos.
+	Events []EventType `json:"events" firestore:"events"`
+	// Secret firma los envíos (HMAC-SHA256). Solo se devuelve al crear el webhook.
+	Secret    string    `json:"secret,omitempty" firestore:"secret"`
+	Active    bool      `json:"active" firestore:"active"`
+	CreatedAt time.Time `json:"createdAt" firestore:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt" firestore:"updatedAt"`
+}
+
+// Wants indica si el webhook recibe eventos del tipo indicado.
+func (w *Webhook) Wants(eventType EventType) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, t := range w.Events {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// DeliveryStatus es el estado del envío de un evento a un webhook.
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	// DeliveryDead es un envío que agotó los reintentos (dead letter). Se
+	// puede reintentar a mano con replay.
+	DeliveryDead DeliveryStatus = "dead"
+)
+
+// WebhookDelivery es el envío de un evento a un webhook, con sus reintentos.
+type WebhookDelivery struct {
+	ID        string         `json:"id" firestore:"id"`
+	WebhookID string         `json:"webhookId" firestore:"webhookId"`
+	Subdomain string         `json:"subdomain" firestore:"subdomain"`
+	Event     ProductEvent   `json:"event" firestore:"event"`
+	Status    DeliveryStatus `json:"status" firestore:"status"`
+	Attempts  int            `json:"attempts" firestore:"attempts"`
+	// NextAttemptAt es cuándo toca el próximo intento de un envío pendiente.
+	NextAttemptAt  time.Time  `json:"nextAttemptAt" firestore:"nextAttemptAt"`
+	LastStatusCode int        `json:"lastStatusCode,omitempty" firestore:"lastStatusCode,omitempty"`
+	LastError      string     `json:"lastError,omitempty" firestore:"lastError,omitempty"`
+	DeliveredAt    *time.Time `json:"deliveredAt,omitempty" firestore:"deliveredAt,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt" firestore:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt" firestore:"updatedAt"`
+}
@@ -0,0 +1,218 @@
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+// ErrNoExchangeRate se devuelve cuando la tabla del proyecto no permite
+// convertir entre las dos monedas.
+var ErrNoExchangeRate = errors.New("no exchange rate")
+
+// Rate devuelve cuántas unidades de to equivalen a una unidad de from.
+func Rate(table *models.ExchangeRateTable, from, to string) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+	if table == nil {
+		return 0, fmt.Errorf("%w from %s to %s", ErrNoExchangeRate, from, to)
+	}
+	fromRate, fromOK := baseRate(table, from)
+	toRate, toOK := baseRate(table, to)
+	if !fromOK || !toOK {
+		return 0, fmt.Errorf("%w from %s to %s", ErrNoExchangeRate, from, to)
+	}
+	return toRate / fromRate, nil
+}
+
+func baseRate(table *models.ExchangeRateTable, currency string) (float64, bool) {
+	if currency == table.BaseCurrency {
+		return 1, true
+	}
+	rate, ok := table.Rates[currency]
+	return rate, ok && rate > 0
+}
+
+// Convert pasa un importe de una moneda a otra con el tipo de cambio rate,
+// teniendo en cuenta los decimales de cada moneda, y aplica el redondeo
+// configurado para la moneda destino.
+func Convert(amount models.Money, from, to string, rate float64, rounding map[string]models.RoundingRule) models.Money {
+	fromExp, _ := models.CurrencyExponent(from)
+	toExp, _ := models.CurrencyExponent(to)
+	converted := amount.Scale(rate * math.Pow10(toExp-fromExp))
+	if rule, ok := rounding[to]; ok {
+		return Round(converted, rule)
+	}
+	return converted
+}
+
+// Round ajusta el importe al valor más cercano (o al superior o inferior,
+// según Mode) de la forma Ending + k·Increment.
+func Round(amount models.Money, rule models.RoundingRule) models.Money {
+	if rule.Increment <= 0 {
+		return amount
+	}
+	steps := float64(amount-rule.Ending) / float64(rule.Increment)
+	var k float64
+	switch rule.Mode {
+	case models.RoundUp:
+		k = math.Ceil(steps)
+	case models.RoundDown:
+		k = math.Floor(steps)
+	default:
+		k = math.Round(steps)
+	}
+	rounded := rule.Ending + models.Money(k)*rule.Increment
+	// Un precio nunca se redondea a cero o negativo.
+	if rounded <= 0 {
+		rounded = rule.Ending
+		if rounded <= 0 {
+			rounded = rule.Increment
+		}
+	}
+	return rounded
+}
+
+// ConvertProduct expresa los precios ya resueltos del producto (ver
+// ApplyPriceList) en la moneda to. Los SKUs con precio fijo en esa moneda
+// (CurrencyPrices) lo usan como precio normal en lugar de convertirlo.
+func ConvertProduct(product *models.Product, table *models.ExchangeRateTable, to string) error {
+	from := product.Currency
+	if from == to {
+		return nil
+	}
+	rate, err := Rate(table, from, to)
+	if err != nil {
+		return err
+	}
+	var rounding map[string]models.RoundingRule
+	if table != nil {
+		rounding = table.Rounding
+	}
+	convert := func(amount models.Money) models.Money {
+		if amount == 0 {
+			return 0
+		}
+		return Convert(amount, from, to, rate, rounding)
+	}
+	convertSKU := func(price *models.Money, sale *models.SalePricing, tiers []models.PriceTier, overrides map[string]models.Money) {
+		discounted := sale.EffectivePrice < *price
+		override, hasOverride := overrides[to]
+
+		sale.SalePrice = convert(sale.SalePrice)
+		sale.CompareAtPrice = convert(sale.CompareAtPrice)
+		sale.EffectivePrice = convert(sale.EffectivePrice)
+		for i := range tiers {
+			tiers[i].UnitPrice = convert(tiers[i].UnitPrice)
+		}
+		*price = convert(*price)
+
+		if hasOverride {
+			*price = override
+			if !discounted || sale.EffectivePrice > override {
+				sale.EffectivePrice = override
+			}
+		}
+	}
+
+	if len(product.Variations) == 0 {
+		product.PriceTiers = append([]models.PriceTier(nil), product.PriceTiers...)
+		convertSKU(&product.Price, &product.SalePricing, product.PriceTiers, product.CurrencyPrices)
+		product.FilterPrice = product.EffectivePrice
+		product.MaxPrice = product.EffectivePrice
+	} else {
+		for i := range product.Variations {
+			v := &product.Variations[i]
+			v.PriceTiers = append([]models.PriceTier(nil), v.PriceTiers...)
+			convertSKU(&v.Price, &v.SalePricing, v.PriceTiers, v.CurrencyPrices)
+		}
+		aggregateVariations(product)
+		product.FilterPrice, product.MaxPrice = PriceRange(product.Variations)
+	}
+
+	product.ConvertedFrom = from
+	product.Currency = to
+	return nil
+}
+
+// PriceRange devuelve el precio vigente mínimo y máximo de las variaciones
+// activas, o de todas si no hay ninguna activa.
+func PriceRange(variations []models.Variation) (models.Money, models.Money) {
+	priced := []models.Variation{}
+	for _, v := range variations {
+		if v.Active {
+			priced = append(priced, v)
+		}
+	}
+	if len(priced) == 0 {
+		priced = variations
+	}
+	if len(priced) == 0 {
+		return 0, 0
+	}
+	minPrice, maxPrice := priced[0].EffectivePrice, priced[0].EffectivePrice
+	for _, v := range priced[1:] {
+		minPrice = min(minPrice, v.EffectivePrice)
+		maxPrice = max(maxPrice, v.EffectivePrice)
+	}
+	return minPrice, maxPrice
+}
+
+// ValidateExchangeRates normaliza los códigos de la tabla a mayúsculas y
+// comprueba que todas las monedas sean válidas, las tasas positivas y las
+// reglas de redondeo coherentes.
+func ValidateExchangeRates(table *models.ExchangeRateTable) error {
+	table.BaseCurrency = strings.ToUpper(table.BaseCurrency)
+	if !models.ValidCurrency(table.BaseCurrency) {
+		return fmt.Errorf("%w: baseCurrency must be a valid ISO 4217 code", ErrInvalidPricing)
+	}
+
+	rates := make(map[string]float64, len(table.Rates))
+	for code, rate := range table.Rates {
+		code = strings.ToUpper(code)
+		if !models.ValidCurrency(code) {
+			return fmt.Errorf("%w: %s is not a valid ISO 4217 code", ErrInvalidPricing, code)
+		}
+		if rate <= 0 {
+			return fmt.Errorf("%w: rate for %s must be greater than zero", ErrInvalidPricing, code)
+		}
+		rates[code] = rate
+	}
+	table.Rates = rates
+
+	rounding := make(map[string]models.RoundingRule, len(table.Rounding))
+	for code, rule := range table.Rounding {
+		code = strings.ToUpper(code)
+		if !models.ValidCurrency(code) {
+			return fmt.Errorf("%w: %s is not a valid ISO 4217 code", ErrInvalidPricing, code)
+		}
+		if rule.Increment <= 0 || rule.Ending < 0 || rule.Ending >= rule.Increment {
+			return fmt.Errorf("%w: rounding for %s needs a positive increment and an ending lower than it", ErrInvalidPricing, code)
+		}
+		switch rule.Mode {
+		case "", models.RoundNearest, models.RoundUp, models.RoundDown:
+		default:
+			return fmt.Errorf("%w: rounding mode for %s must be nearest, up or down", ErrInvalidPricing, code)
+		}
+		rounding[code] = rule
+	}
+	table.Rounding = rounding
+	return nil
+}
+
+// validateCurrencyPrices comprueba los precios fijos por moneda de un SKU.
+func validateCurrencyPrices(prices map[string]models.Money) error {
+	for code, amount := range prices {
+		if code != strings.ToUpper(code) || !models.ValidCurrency(code) {
+			return fmt.Errorf("%w: currencyPrices key %s must be an uppercase ISO 4217 code", ErrInvalidPricing, code)
+		}
+		if amount <= 0 {
+			return fmt.Errorf("%w: currencyPrices.%s must be greater than zero", ErrInvalidPricing, code)
+		}
+	}
+	return nil
+}
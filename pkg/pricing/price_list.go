@@ -0,0 +1,59 @@
+package pricing
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+// ApplyPriceList resuelve los precios del producto con la lista indicada: el
+// precio base de cada SKU pasa a ser el de la lista y la oferta programada
+// sigue aplicando si es menor. Con una lista nula, o de otro proyecto o
+// subdominio, equivale a ApplyEffectivePrices.
+func ApplyPriceList(product *models.Product, list *models.PriceList, now time.Time) {
+	if list == nil || !list.Active || list.ProjectID != product.ProjectID || list.Subdomain != product.Subdomain {
+		ApplyEffectivePrices(product, now)
+		return
+	}
+
+	product.PriceListID = list.ID
+	resolvePrices(product, now, func(sku string, price models.Money) models.Money {
+		return ListPrice(list, product, sku, price)
+	})
+}
+
+// ListPrice devuelve el precio de un SKU según la lista: el override del SKU
+// si existe, si no la primera regla que coincida con el producto, y si no el
+// precio normal.
+func ListPrice(list *models.PriceList, product *models.Product, sku string, price models.Money) models.Money {
+	if override, ok := list.Overrides[sku]; ok {
+		return override
+	}
+	for _, rule := range list.Rules {
+		if rule.Category != "" && rule.Category != product.Category {
+			continue
+		}
+		if rule.Brand != "" && rule.Brand != product.Brand {
+			continue
+		}
+		return price.Scale(1 + rule.Percent/100)
+	}
+	return price
+}
+
+// ValidatePriceList comprueba que los overrides sean positivos y que ninguna
+// regla deje el precio en cero o negativo.
+func ValidatePriceList(list *models.PriceList) error {
+	for sku, price := range list.Overrides {
+		if price <= 0 {
+			return fmt.Errorf("%w: override for %s must be greater than zero", ErrInvalidPricing, sku)
+		}
+	}
+	for i, rule := range list.Rules {
+		if rule.Percent <= -100 {
+			return fmt.Errorf("%w: rule %d must have a percent greater than -100", ErrInvalidPricing, i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+// Package pricing resuelve los precios que ve el cliente a partir de los
+// precios guardados en el producto.
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+// ErrInvalidPricing agrupa los errores de validación de precios.
+var ErrInvalidPricing = errors.New("invalid pricing")
+
+// SaleActive indica si la oferta rige en el instante now.
+func SaleActive(sale models.SalePricing, now time.Time) bool {
+	if sale.SalePrice <= 0 {
+		return false
+	}
+	if sale.SaleStartsAt != nil && now.Before(*sale.SaleStartsAt) {
+		return false
+	}
+	if sale.SaleEndsAt != nil && !now.Before(*sale.SaleEndsAt) {
+		return false
+	}
+	return true
+}
+
+// resolveSale fija EffectivePrice y OnSale a partir del precio base del SKU.
+// La oferta solo rige si es menor que ese precio base.
+func resolveSale(sale *models.SalePricing, base models.Money, now time.Time) {
+	sale.Tax = nil
+	sale.OnSale = SaleActive(*sale, now) && sale.SalePrice < base
+	sale.EffectivePrice = base
+	if sale.OnSale {
+		sale.EffectivePrice = sale.SalePrice
+	}
+}
+
+// ApplyEffectivePrices resuelve el precio vigente del producto y de cada
+// variación en el instante now.
+func ApplyEffectivePrices(product *models.Product, now time.Time) {
+	product.PriceListID = ""
+	product.ConvertedFrom = ""
+	product.Tax = nil
+	resolvePrices(product, now, func(sku string, price models.Money) models.Money { return price })
+}
+
+// resolvePrices resuelve cada SKU a partir del precio base que devuelve base.
+// En productos con variaciones el precio del producto es el mínimo de las
+// variaciones activas (o de todas si no hay activas).
+func resolvePrices(product *models.Product, now time.Time, base func(sku string, price models.Money) models.Money) {
+	if len(product.Variations) == 0 {
+		resolveSale(&product.SalePricing, base(product.SKU, product.Price), now)
+		return
+	}
+
+	for i := range product.Variations {
+		v := &product.Variations[i]
+		resolveSale(&v.SalePricing, base(v.SKU, v.Price), now)
+	}
+	aggregateVariations(product)
+}
+
+// aggregateVariations fija EffectivePrice y OnSale del producto a partir de
+// los precios ya resueltos de sus variaciones.
+func aggregateVariations(product *models.Product) {
+	onSale := false
+	minPrice := models.Money(-1)
+	fallback := models.Money(-1)
+	for _, v := range product.Variations {
+		if fallback < 0 || v.EffectivePrice < fallback {
+			fallback = v.EffectivePrice
+		}
+		if !v.Active {
+			continue
+		}
+		onSale = onSale || v.OnSale
+		if minPrice < 0 || v.EffectivePrice < minPrice {
+			minPrice = v.EffectivePrice
+		}
+	}
+	if minPrice < 0 {
+		minPrice = fallback
+	}
+	product.EffectivePrice = minPrice
+	product.OnSale = onSale
+}
+
+// NextPriceChange devuelve el próximo instante posterior a now en que empieza
+// o termina alguna oferta del producto, redondeado hacia arriba al segundo.
+func NextPriceChange(product *models.Product, now time.Time) *time.Time {
+	var next *time.Time
+	consider := func(sale models.SalePricing) {
+		if sale.SalePrice <= 0 {
+			return
+		}
+		for _, t := range []*time.Time{sale.SaleStartsAt, sale.SaleEndsAt} {
+			if t != nil && t.After(now) && (next == nil || t.Before(*next)) {
+				next = t
+			}
+		}
+	}
+
+	if len(product.Variations) == 0 {
+		consider(product.SalePricing)
+	}
+	for _, v := range product.Variations {
+		consider(v.SalePricing)
+	}
+	if next == nil {
+		return nil
+	}
+
+	rounded := next.UTC().Truncate(time.Second)
+	if rounded.Before(*next) {
+		rounded = rounded.Add(time.Second)
+	}
+	return &rounded
+}
+
+// ValidateSale comprueba que la oferta sea coherente con el precio normal.
+func ValidateSale(sale models.SalePricing, price models.Money) error {
+	if sale.SalePrice < 0 || sale.CompareAtPrice < 0 {
+		return fmt.Errorf("%w: salePrice and compareAtPrice cannot be negative", ErrInvalidPricing)
+	}
+	if sale.SalePrice > 0 && sale.SalePrice >= price {
+		return fmt.Errorf("%w: salePrice must be lower than price", ErrInvalidPricing)
+	}
+	if sale.SalePrice == 0 && (sale.SaleStartsAt != nil || sale.SaleEndsAt != nil) {
+		return fmt.Errorf("%w: saleStartsAt and saleEndsAt require a salePrice", ErrInvalidPricing)
+	}
+	if sale.SaleStartsAt != nil && sale.SaleEndsAt != nil && !sale.SaleEndsAt.After(*sale.SaleStartsAt) {
+		return fmt.Errorf("%w: saleEndsAt must be after saleStartsAt", ErrInvalidPricing)
+	}
+	return nil
+}
+
+// ValidateProduct valida las ofertas, los precios por moneda y los tramos por
+// cantidad del producto y de todas sus variaciones.
+func ValidateProduct(product *models.Product) error {
+	if len(product.Variations) == 0 {
+		if err := ValidateSale(product.SalePricing, product.Price); err != nil {
+			return err
+		}
+		if err := validateCurrencyPrices(product.CurrencyPrices); err != nil {
+			return err
+		}
+		return ValidateTiers(product.PriceTiers, product.Price)
+	}
+	for _, v := range product.Variations {
+		if err := ValidateVariation(&v); err != nil {
+			return fmt.Errorf("variation %s: %w", v.SKU, err)
+		}
+	}
+	return nil
+}
+
+// ValidateVariation valida la oferta, los precios por moneda y los tramos por
+// cantidad de una variación.
+func ValidateVariation(v *models.Variation) error {
+	if err := ValidateSale(v.SalePricing, v.Price); err != nil {
+		return err
+	}
+	if err := validateCurrencyPrices(v.CurrencyPrices); err != nil {
+		return err
+	}
+	return ValidateTiers(v.PriceTiers, v.Price)
+}
@@ -0,0 +1,123 @@
+package pricing
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+// ErrUnknownTaxRegion se devuelve cuando la región pedida no está en la
+// tabla de impuestos.
+var ErrUnknownTaxRegion = errors.New("unknown tax region")
+
+// TaxRegion devuelve la región a usar: la pedida o la de la tabla por defecto.
+func TaxRegion(table *models.TaxTable, requested string) (string, error) {
+	region := requested
+	if region == "" {
+		region = table.DefaultRegion
+	}
+	if _, ok := table.Regions[region]; !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownTaxRegion, region)
+	}
+	return region, nil
+}
+
+// TaxRate devuelve la tasa (en porcentaje) de la categoría en la región. Las
+// categorías que la región no define usan la tasa "standard", y si tampoco
+// existe la tasa es cero.
+func TaxRate(table *models.TaxTable, region, category string) (string, float64) {
+	if category == "" {
+		category = models.DefaultTaxCategory
+	}
+	rates := table.Regions[region]
+	if rate, ok := rates[category]; ok {
+		return category, rate
+	}
+	return category, rates[models.DefaultTaxCategory]
+}
+
+// ApplyTax desglosa un importe con la tasa indicada. Si includesTax, el
+// importe es bruto y se extrae el impuesto; si no, es neto y se suma.
+func ApplyTax(amount models.Money, rate float64, includesTax bool) models.TaxedAmount {
+	if includesTax {
+		net := amount.Scale(1 / (1 + rate/100))
+		return models.TaxedAmount{Net: net, Tax: amount - net, Gross: amount}
+	}
+	tax := amount.Scale(rate / 100)
+	return models.TaxedAmount{Net: amount, Tax: tax, Gross: amount + tax}
+}
+
+// ApplyTaxes añade el desglose de impuestos a cada precio del producto, que
+// ya debe estar resuelto (y convertido, si se pidió otra moneda).
+func ApplyTaxes(product *models.Product, table *models.TaxTable, region string) {
+	category, rate := TaxRate(table, region, product.TaxCategory)
+	breakdown := func(price models.Money, sale models.SalePricing) *models.PriceTax {
+		return &models.PriceTax{
+			Region:         region,
+			Category:       category,
+			Rate:           rate,
+			Price:          ApplyTax(price, rate, table.PricesIncludeTax),
+			EffectivePrice: ApplyTax(sale.EffectivePrice, rate, table.PricesIncludeTax),
+		}
+	}
+
+	if len(product.Variations) == 0 {
+		product.Tax = breakdown(product.Price, product.SalePricing)
+		return
+	}
+	for i := range product.Variations {
+		v := &product.Variations[i]
+		v.Tax = breakdown(v.Price, v.SalePricing)
+	}
+	product.Tax = breakdown(product.EffectivePrice, product.SalePricing)
+}
+
+// ValidateTaxTable comprueba que las tasas estén entre 0 y 100 y que la
+// región por defecto exista.
+func ValidateTaxTable(table *models.TaxTable) error {
+	if len(table.Regions) == 0 {
+		return fmt.Errorf("%w: at least one tax region is required", ErrInvalidPricing)
+	}
+	for region, rates := range table.Regions {
+		for category, rate := range rates {
+			if rate < 0 || rate > 100 {
+				return fmt.Errorf("%w: rate %s/%s must be between 0 and 100", ErrInvalidPricing, region, category)
+			}
+		}
+	}
+	if _, ok := table.Regions[table.DefaultRegion]; !ok {
+		return fmt.Errorf("%w: defaultRegion must be one of the regions", ErrInvalidPricing)
+	}
+	return nil
+}
+
+// ApplyLineTax añade a la línea de cotización el desglose de su total.
+func ApplyLineTax(line *QuoteLine, category string, table *models.TaxTable, region string) {
+	category, rate := TaxRate(table, region, category)
+	taxed := ApplyTax(line.LineTotal, rate, table.PricesIncludeTax)
+	line.Tax = &LineTax{Region: region, Category: category, Rate: rate, TaxedAmount: taxed}
+}
+
+// LineTax es el desglose de impuestos del total de una línea.
+type LineTax struct {
+	Region   string  `json:"region"`
+	Category string  `json:"category"`
+	Rate     float64 `json:"rate"`
+	models.TaxedAmount
+}
+
+// TaxTotals suma los desgloses de las líneas. Devuelve nil si alguna línea no
+// tiene desglose.
+func TaxTotals(lines []QuoteLine) *models.TaxedAmount {
+	var totals models.TaxedAmount
+	for _, line := range lines {
+		if line.Tax == nil {
+			return nil
+		}
+		totals.Net += line.Tax.Net
+		totals.Tax += line.Tax.Tax
+		totals.Gross += line.Tax.Gross
+	}
+	return &totals
+}
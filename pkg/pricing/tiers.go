@@ -0,0 +1,117 @@
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+var (
+	// ErrUnknownSKU se devuelve al cotizar un SKU que el producto no tiene.
+	ErrUnknownSKU = errors.New("sku not found in product")
+	// ErrUnavailable se devuelve al cotizar un producto o variación inactivos.
+	ErrUnavailable = errors.New("product is not available")
+)
+
+// ValidateTiers comprueba que los tramos sean monótonos: cantidades mínimas
+// estrictamente crecientes (desde 2) y precios unitarios estrictamente
+// decrecientes y menores que el precio normal.
+func ValidateTiers(tiers []models.PriceTier, price models.Money) error {
+	previousQuantity, previousPrice := 1, price
+	for i, tier := range tiers {
+		if tier.MinQuantity <= previousQuantity {
+			return fmt.Errorf("%w: tier %d minQuantity must be greater than %d", ErrInvalidPricing, i, previousQuantity)
+		}
+		if tier.UnitPrice <= 0 || tier.UnitPrice >= previousPrice {
+			return fmt.Errorf("%w: tier %d unitPrice must be positive and lower than %d", ErrInvalidPricing, i, previousPrice)
+		}
+		previousQuantity, previousPrice = tier.MinQuantity, tier.UnitPrice
+	}
+	return nil
+}
+
+// TierFor devuelve el tramo que corresponde a la cantidad, o nil si la
+// cantidad no alcanza el primero.
+func TierFor(tiers []models.PriceTier, quantity int) *models.PriceTier {
+	var applied *models.PriceTier
+	for i := range tiers {
+		if quantity >= tiers[i].MinQuantity {
+			applied = &tiers[i]
+		}
+	}
+	return applied
+}
+
+// QuoteLine es el precio de una cantidad de un SKU.
+type QuoteLine struct {
+	ProductID   string            `json:"productId"`
+	VariationID string            `json:"variationId,omitempty"`
+	SKU         string            `json:"sku"`
+	Quantity    int               `json:"quantity"`
+	Currency    string            `json:"currency"`
+	UnitPrice   models.Money      `json:"unitPrice"`
+	LineTotal   models.Money      `json:"lineTotal"`
+	Tier        *models.PriceTier `json:"tier"`
+	OnSale      bool              `json:"onSale"`
+	PriceListID string            `json:"priceListId,omitempty"`
+	Tax         *LineTax          `json:"tax,omitempty"`
+}
+
+// QuoteSKU cotiza quantity unidades de un SKU del producto (por variationID o
+// sku). El precio unitario es el vigente (lista de precios y oferta) o el del
+// tramo por volumen si es menor; Tier solo se informa si se aplicó.
+func QuoteSKU(product *models.Product, variationID, sku string, quantity int, list *models.PriceList, now time.Time) (QuoteLine, error) {
+	ApplyPriceList(product, list, now)
+
+	line := QuoteLine{ProductID: product.ID, Quantity: quantity, Currency: product.Currency, PriceListID: product.PriceListID}
+	var sale models.SalePricing
+	var tiers []models.PriceTier
+
+	if len(product.Variations) == 0 {
+		if variationID != "" || (sku != "" && sku != product.SKU) {
+			return line, ErrUnknownSKU
+		}
+		line.SKU, sale, tiers = product.SKU, product.SalePricing, product.PriceTiers
+	} else {
+		var variation *models.Variation
+		for i := range product.Variations {
+			v := &product.Variations[i]
+			if (variationID != "" && v.ID == variationID) || (variationID == "" && sku != "" && v.SKU == sku) {
+				variation = v
+				break
+			}
+		}
+		if variation == nil {
+			return line, ErrUnknownSKU
+		}
+		if !variation.Active {
+			return line, ErrUnavailable
+		}
+		line.VariationID, line.SKU, sale, tiers = variation.ID, variation.SKU, variation.SalePricing, variation.PriceTiers
+	}
+	if !product.Active {
+		return line, ErrUnavailable
+	}
+
+	line.UnitPrice = sale.EffectivePrice
+	line.OnSale = sale.OnSale
+	if tier := TierFor(tiers, quantity); tier != nil && tier.UnitPrice < line.UnitPrice {
+		applied := *tier
+		line.Tier = &applied
+		line.UnitPrice = tier.UnitPrice
+		line.OnSale = false
+	}
+	line.LineTotal = line.UnitPrice.Times(quantity)
+	return line, nil
+}
+
+// Subtotal suma los totales de las líneas.
+func Subtotal(lines []QuoteLine) models.Money {
+	var total models.Money
+	for _, line := range lines {
+		total += line.LineTotal
+	}
+	return total
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+// AuditLogCollection es la colección del registro de auditoría.
+const AuditLogCollection = "audit_log"
+
+// AuditFilter acota una consulta del registro de auditoría. Los campos
+// vacíos no filtran.
+type AuditFilter struct {
+	Subdomain string
+	// Unattributed limita la consulta a las entradas sin subdominio.
+	Unattributed bool
+	// Route es la ruta registrada, p. ej. "/api/v1/products/:id".
+	Route  string
+	UID    string
+	APIKey string
+	// ProductID filtra por el ID de producto afectado (target "id").
+	ProductID string
+	// From es inclusivo y To exclusivo.
+	From time.Time
+	To   time.Time
+}
+
+// AuditRepository guarda y consulta el registro de auditoría.
+type AuditRepository interface {
+	RecordAudit(ctx context.Context, entry *models.AuditEntry) error
+	// ListAuditEntries devuelve las entradas que cumplen el filtro, de la más
+	// reciente a la más antigua. limit <= 0 devuelve todas.
+	ListAuditEntries(ctx context.Context, filter AuditFilter, limit, offset int) ([]models.AuditEntry, error)
+}
+
+// matches indica si la entrada cumple el filtro.
+func (f AuditFilter) matches(entry models.AuditEntry) bool {
+	switch {
+	case f.Subdomain != "" && entry.Subdomain != f.Subdomain,
+		f.Unattributed && entry.Subdomain != "",
+		f.Route != "" && entry.Route != f.Route,
+		f.UID != "" && entry.Actor.UID != f.UID,
+		f.APIKey != "" && entry.Actor.APIKey != f.APIKey,
+		f.ProductID != "" && entry.Targets["id"] != f.ProductID,
+		!f.From.IsZero() && entry.CreatedAt.Before(f.From),
+		!f.To.IsZero() && !entry.CreatedAt.Before(f.To):
+		return false
+	}
+	return true
+}
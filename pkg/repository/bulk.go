@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+// ErrDuplicateBulkProduct se devuelve cuando un lote escribe dos veces el mismo producto.
+var ErrDuplicateBulkProduct = errors.New("the product appears more than once in the batch")
+
+// BulkWrite es una escritura de un lote. Con Create crea ese producto (con
+// el ID ya asignado); si no, modifica ProductID con Updates (merge de campos
+// como Update) o lo desactiva con Deactivate.
+type BulkWrite struct {
+	Create          *models.Product
+	ProductID       string
+	ExpectedVersion int64
+	Updates         map[string]interface{}
+	Deactivate      bool
+}
+
+func (w BulkWrite) productID() string {
+	if w.Create != nil {
+		return w.Create.ID
+	}
+	return w.ProductID
+}
+
+// BulkError indica qué escritura hizo fallar un lote.
+type BulkError struct {
+	Index int
+	Err   error
+}
+
+func (e *BulkError) Error() string {
+	return fmt.Sprintf("bulk write %d: %v", e.Index, e.Err)
+}
+
+func (e *BulkError) Unwrap() error {
+	return e.Err
+}
+
+// BulkRepository escribe varios productos de una vez.
+type BulkRepository interface {
+	// ApplyBulk aplica las escrituras en una sola transacción: se guardan
+	// todas, con sus movimientos, revisiones y eventos, o ninguna. Si una
+	// falla devuelve *BulkError. Devuelve los productos tal como quedaron,
+	// en el orden de writes.
+	ApplyBulk(ctx context.Context, writes []BulkWrite) ([]*models.Product, error)
+}
+
+// checkBulkWrites rechaza los lotes que escriben dos veces el mismo producto.
+func checkBulkWrites(writes []BulkWrite) error {
+	seen := make(map[string]bool, len(writes))
+	for i, w := range writes {
+		id := w.productID()
+		if seen[id] {
+			return &BulkError{Index: i, Err: ErrDuplicateBulkProduct}
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// bulkHistory acumula el historial de las escrituras de un lote.
+type bulkHistory struct {
+	movements []models.StockMovement
+	revisions []models.ProductRevision
+	events    []models.ProductEvent
+}
+
+// applyBulkWrite aplica una escritura sobre el documento actual del producto
+// (nil si no existe) y acumula su historial, igual que Create o Mutate.
+func applyBulkWrite(ctx context.Context, w BulkWrite, doc map[string]interface{}, history *bulkHistory) (*models.Product, error) {
+	now := time.Now().UTC()
+	if w.Create != nil {
+		if doc != nil {
+			return nil, fmt.Errorf("product %s already exists", w.Create.ID)
+		}
+		product := w.Create
+		if product.Version == 0 {
+			product.Version = 1
+		}
+		DeriveFields(product)
+		change := stockChangeFrom(ctx, models.MovementInitial)
+		movements := stockMovements(product, nil, change, now)
+		return product, history.add(product, nil, change, movements, true, now)
+	}
+
+	if doc == nil {
+		return nil, ErrNotFound
+	}
+	product, err := productFromData(doc)
+	if err != nil {
+		return nil, err
+	}
+	fn := mergeUpdates(w.Updates)
+	if w.Deactivate {
+		fn = deactivate(ctx)
+	}
+	before := stockLevels(product)
+	if err := applyMutation(product, w.ExpectedVersion, fn); err != nil {
+		return nil, err
+	}
+	change := stockChangeFrom(ctx, models.MovementAdjustment)
+	movements := stockMovements(product, before, change, product.UpdatedAt)
+	return product, history.add(product, doc, change, movements, false, product.UpdatedAt)
+}
+
+func (h *bulkHistory) add(product *models.Product, before map[string]interface{}, change StockChange, movements []models.StockMovement, created bool, now time.Time) error {
+	revision, err := newRevision(product, before, change, now)
+	if err != nil {
+		return err
+	}
+	events, err := productEvents(revision, movements, created)
+	if err != nil {
+		return err
+	}
+	h.movements = append(h.movements, movements...)
+	h.revisions = append(h.revisions, revision)
+	h.events = append(h.events, events...)
+	return nil
+}
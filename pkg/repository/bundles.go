@@ -0,0 +1,267 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/pricing"
+)
+
+// maxBundleDepth limita el anidamiento de bundles dentro de bundles.
+const maxBundleDepth = 5
+
+var (
+	// ErrInvalidBundle se devuelve cuando la definición de un bundle no es válida.
+	ErrInvalidBundle = errors.New("invalid bundle")
+	// ErrBundleCycle se devuelve cuando un bundle se contiene a sí mismo,
+	// directa o indirectamente.
+	ErrBundleCycle = errors.New("bundle cannot contain itself")
+	// ErrBundleStock se devuelve al mover stock o añadir variaciones a un
+	// bundle: su stock se deriva de los componentes.
+	ErrBundleStock = errors.New("bundle stock is derived from its components")
+)
+
+// componentLine convierte un componente en una línea de reserva para
+// reutilizar la resolución de variación/SKU de stockTarget.
+func componentLine(component models.BundleComponent) models.ReservationLine {
+	return models.ReservationLine{
+		ProductID:   component.ProductID,
+		VariationID: component.VariationID,
+		SKU:         component.SKU,
+		Quantity:    component.Quantity,
+	}
+}
+
+// ValidateBundle comprueba la definición de un bundle: sin stock ni
+// variaciones propias, con componentes existentes del mismo subdominio y sin
+// ciclos (bundle.ID no puede aparecer entre los componentes de sus
+// componentes). Los productos que no son bundles no tienen componentes.
+func ValidateBundle(ctx context.Context, repo ProductRepository, bundle *models.Product) error {
+	if !bundle.IsBundle() {
+		if bundle.Type != "" {
+			return fmt.Errorf("%w: type must be empty or %q", ErrInvalidBundle, models.ProductTypeBundle)
+		}
+		if len(bundle.Components) > 0 {
+			return fmt.Errorf("%w: only bundles can have components", ErrInvalidBundle)
+		}
+		return nil
+	}
+	if len(bundle.Variations) > 0 || len(bundle.Options) > 0 {
+		return fmt.Errorf("%w: a bundle cannot have variations or options", ErrInvalidBundle)
+	}
+	if bundle.Stock != 0 || len(bundle.Inventory) > 0 {
+		return fmt.Errorf("%w: %v", ErrInvalidBundle, ErrBundleStock)
+	}
+	if len(bundle.Components) == 0 {
+		return fmt.Errorf("%w: a bundle needs at least one component", ErrInvalidBundle)
+	}
+
+	seen := map[string]bool{}
+	for i, component := range bundle.Components {
+		if component.ProductID == "" || component.Quantity <= 0 {
+			return fmt.Errorf("%w: components[%d] requires productId and a positive quantity", ErrInvalidBundle, i)
+		}
+		if component.ProductID == bundle.ID {
+			return ErrBundleCycle
+		}
+		product, err := repo.Get(ctx, component.ProductID)
+		if errors.Is(err, ErrNotFound) {
+			return fmt.Errorf("%w: component product %s does not exist", ErrInvalidBundle, component.ProductID)
+		}
+		if err != nil {
+			return err
+		}
+		if product.Subdomain != bundle.Subdomain {
+			return fmt.Errorf("%w: component product %s belongs to another subdomain", ErrInvalidBundle, component.ProductID)
+		}
+
+		line := componentLine(component)
+		if product.IsBundle() {
+			if err := checkBundleCycle(ctx, repo, bundle.ID, product, 1); err != nil {
+				return err
+			}
+			line.VariationID, line.SKU = "", product.SKU
+		} else if _, err := stockTarget(product, &line); err != nil {
+			return fmt.Errorf("%w: component %d: %v", ErrInvalidBundle, i, err)
+		}
+
+		key := line.ProductID + "/" + line.SKU
+		if seen[key] {
+			return fmt.Errorf("%w: %s is listed more than once", ErrInvalidBundle, key)
+		}
+		seen[key] = true
+		// Se guarda la referencia resuelta para que el bundle no dependa de
+		// cómo se escribió (SKU o ID de variación).
+		bundle.Components[i].VariationID = line.VariationID
+		bundle.Components[i].SKU = line.SKU
+	}
+	return nil
+}
+
+// checkBundleCycle recorre los bundles anidados en product buscando rootID.
+func checkBundleCycle(ctx context.Context, repo ProductRepository, rootID string, product *models.Product, depth int) error {
+	if depth > maxBundleDepth {
+		return fmt.Errorf("%w: bundles can be nested at most %d levels", ErrInvalidBundle, maxBundleDepth)
+	}
+	for _, component := range product.Components {
+		if component.ProductID == rootID {
+			return ErrBundleCycle
+		}
+		nested, err := repo.Get(ctx, component.ProductID)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if nested.IsBundle() {
+			if err := checkBundleCycle(ctx, repo, rootID, nested, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// BundleAvailability devuelve cuántos bundles se pueden armar con el stock
+// actual: el mínimo, entre componentes, de stock / unidades por bundle. Un
+// componente inexistente o inactivo deja el bundle sin stock.
+func BundleAvailability(ctx context.Context, repo ProductRepository, bundle *models.Product) (int, error) {
+	return bundleAvailability(ctx, repo, bundle, map[string]*models.Product{}, 0)
+}
+
+func bundleAvailability(ctx context.Context, repo ProductRepository, bundle *models.Product, cache map[string]*models.Product, depth int) (int, error) {
+	if depth > maxBundleDepth || len(bundle.Components) == 0 {
+		return 0, nil
+	}
+
+	buildable := -1
+	for _, component := range bundle.Components {
+		product, ok := cache[component.ProductID]
+		if !ok {
+			loaded, err := repo.Get(ctx, component.ProductID)
+			if errors.Is(err, ErrNotFound) {
+				return 0, nil
+			}
+			if err != nil {
+				return 0, err
+			}
+			product, cache[component.ProductID] = loaded, loaded
+		}
+		if !product.Active {
+			return 0, nil
+		}
+
+		var available int
+		if product.IsBundle() {
+			nested, err := bundleAvailability(ctx, repo, product, cache, depth+1)
+			if err != nil {
+				return 0, err
+			}
+			available = nested
+		} else {
+			line := componentLine(component)
+			slot, err := stockTarget(product, &line)
+			if err != nil {
+				return 0, nil
+			}
+			if line.VariationID != "" && !product.Variations[findVariation(product, line.VariationID)].Active {
+				return 0, nil
+			}
+			available = slot.available("")
+		}
+
+		if count := available / component.Quantity; buildable < 0 || count < buildable {
+			buildable = count
+		}
+	}
+	if buildable < 0 {
+		return 0, nil
+	}
+	return buildable, nil
+}
+
+// ApplyBundleStock completa stock, total_stock e in_stock de los bundles con
+// su disponibilidad actual. Los demás productos no cambian.
+func ApplyBundleStock(ctx context.Context, repo ProductRepository, products ...*models.Product) error {
+	for _, product := range products {
+		if !product.IsBundle() {
+			continue
+		}
+		available, err := BundleAvailability(ctx, repo, product)
+		if err != nil {
+			return err
+		}
+		product.Stock = available
+		product.TotalStock = available
+		product.InStock = available > 0
+	}
+	return nil
+}
+
+// expandBundleLines reemplaza las líneas de bundles por las de sus
+// componentes (multiplicando las cantidades) y devuelve los productos de las
+// líneas resultantes. load carga productos dentro de la transacción de la
+// reserva, así el stock que se descuenta es el leído en ella.
+func expandBundleLines(reservation *models.Reservation, load func(ids []string) (map[string]*models.Product, error)) (map[string]*models.Product, error) {
+	products := map[string]*models.Product{}
+	bundles := map[string]bool{}
+	expanded := []models.ReservationLine{}
+
+	pending := reservation.Lines
+	for depth := 0; len(pending) > 0; depth++ {
+		if depth > maxBundleDepth {
+			return nil, ErrBundleCycle
+		}
+		missing := []string{}
+		for _, id := range reservationProductIDs(pending) {
+			if _, ok := products[id]; !ok {
+				missing = append(missing, id)
+			}
+		}
+		loaded, err := load(missing)
+		if err != nil {
+			return nil, err
+		}
+		for id, product := range loaded {
+			products[id] = product
+		}
+
+		next := []models.ReservationLine{}
+		for _, line := range pending {
+			product := products[line.ProductID]
+			if !product.IsBundle() {
+				expanded = append(expanded, line)
+				continue
+			}
+			if product.Subdomain != reservation.Subdomain {
+				return nil, fmt.Errorf("%w: %s", ErrNotFound, line.ProductID)
+			}
+			if !product.Active {
+				return nil, fmt.Errorf("%w: %s", pricing.ErrUnavailable, line.ProductID)
+			}
+			bundles[product.ID] = true
+			bundleID := line.BundleProductID
+			if bundleID == "" {
+				bundleID = product.ID
+			}
+			for _, component := range product.Components {
+				componentLine := componentLine(component)
+				componentLine.Quantity *= line.Quantity
+				componentLine.LocationID = line.LocationID
+				componentLine.BundleProductID = bundleID
+				next = append(next, componentLine)
+			}
+		}
+		pending = next
+	}
+
+	// Los bundles no tienen stock propio: no se reescriben.
+	for id := range bundles {
+		delete(products, id)
+	}
+	reservation.Lines = expanded
+	return products, nil
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/pricing"
+)
+
+// gift es un bundle con 2 TEE-S y 2 MUG: con el stock de tee y mug se arma
+// uno solo.
+func gift() *models.Product {
+	return &models.Product{
+		ID: "gift", Name: "Gift box", Currency: "USD", Subdomain: "s", ProjectID: "proj",
+		Status: models.StatusPublished, Type: models.ProductTypeBundle, SKU: "GIFT", Price: 2500,
+		Components: []models.BundleComponent{
+			{ProductID: "tee", SKU: "TEE-S", Quantity: 2},
+			{ProductID: "mug", Quantity: 2},
+		},
+	}
+}
+
+func TestValidateBundle(t *testing.T) {
+	other := mug()
+	other.ID, other.SKU, other.Subdomain = "other-mug", "OTHER-MUG", "other"
+	// outer contiene a gift; gift no puede contener a outer.
+	outer := gift()
+	outer.ID, outer.SKU = "outer", "OUTER"
+	outer.Components = []models.BundleComponent{{ProductID: "gift", Quantity: 1}}
+
+	tests := []struct {
+		name    string
+		edit    func(bundle *models.Product)
+		wantErr error
+	}{
+		{"valid", func(*models.Product) {}, nil},
+		{"not a bundle", func(b *models.Product) { b.Type, b.Components = "", nil }, nil},
+		{"unknown type", func(b *models.Product) { b.Type = "kit" }, ErrInvalidBundle},
+		{"components without bundle type", func(b *models.Product) { b.Type = "" }, ErrInvalidBundle},
+		{"own stock", func(b *models.Product) { b.Stock = 3 }, ErrInvalidBundle},
+		{"own variations", func(b *models.Product) { b.Variations = []models.Variation{{ID: "v", SKU: "V"}} }, ErrInvalidBundle},
+		{"no components", func(b *models.Product) { b.Components = nil }, ErrInvalidBundle},
+		{"zero quantity", func(b *models.Product) { b.Components[0].Quantity = 0 }, ErrInvalidBundle},
+		{"unknown product", func(b *models.Product) { b.Components[1].ProductID = "nope" }, ErrInvalidBundle},
+		{"unknown variation", func(b *models.Product) { b.Components[0].SKU = "TEE-XL" }, ErrInvalidBundle},
+		{"other subdomain", func(b *models.Product) { b.Components[1].ProductID = "other-mug" }, ErrInvalidBundle},
+		{"duplicated sku", func(b *models.Product) {
+			b.Components[1] = models.BundleComponent{ProductID: "tee", VariationID: "tee-s", Quantity: 1}
+		}, ErrInvalidBundle},
+		{"contains itself", func(b *models.Product) { b.Components[1].ProductID = "gift" }, ErrBundleCycle},
+		{"contains a bundle that contains it", func(b *models.Product) {
+			b.Components = append(b.Components, models.BundleComponent{ProductID: "outer", Quantity: 1})
+		}, ErrBundleCycle},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestRepository(t, tee(), mug(), other, outer)
+			bundle := gift()
+			tt.edit(bundle)
+			if err := ValidateBundle(context.Background(), repo, bundle); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ValidateBundle() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateBundleResolvesComponents(t *testing.T) {
+	repo := newTestRepository(t, tee(), mug())
+	bundle := gift()
+	if err := ValidateBundle(context.Background(), repo, bundle); err != nil {
+		t.Fatal(err)
+	}
+	if got := bundle.Components[0]; got.VariationID != "tee-s" || got.SKU != "TEE-S" {
+		t.Errorf("variation component = %+v, want it resolved to tee-s", got)
+	}
+	if got := bundle.Components[1]; got.SKU != "MUG" {
+		t.Errorf("simple component = %+v, want SKU MUG", got)
+	}
+}
+
+func TestBundleAvailability(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(tee, mug *models.Product)
+		want int
+	}{
+		{"limited by the scarcest component", func(*models.Product, *models.Product) {}, 1},
+		{"more stock", func(tee, mug *models.Product) { tee.Variations[0].Stock, mug.Stock = 9, 8 }, 4},
+		{"component out of stock", func(_, mug *models.Product) { mug.Stock = 1 }, 0},
+		{"inactive component", func(_, mug *models.Product) { mug.Status = models.StatusDraft }, 0},
+		{"inactive variation", func(tee, _ *models.Product) { tee.Variations[0].Active = false }, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			teeProduct, mugProduct := tee(), mug()
+			tt.edit(teeProduct, mugProduct)
+			repo := newTestRepository(t, teeProduct, mugProduct)
+			bundle := gift()
+			if err := ApplyBundleStock(context.Background(), repo, bundle); err != nil {
+				t.Fatal(err)
+			}
+			if bundle.Stock != tt.want || bundle.TotalStock != tt.want || bundle.InStock != (tt.want > 0) {
+				t.Errorf("bundle stock = %d/%d/%v, want %d", bundle.Stock, bundle.TotalStock, bundle.InStock, tt.want)
+			}
+		})
+	}
+}
+
+func TestReserveBundle(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t, tee(), mug(), gift())
+
+	reservation := newReservation(models.ReservationLine{ProductID: "gift", Quantity: 1})
+	if err := repo.Reserve(ctx, reservation); err != nil {
+		t.Fatal(err)
+	}
+	if len(reservation.Lines) != 2 {
+		t.Fatalf("lines = %+v, want one per component", reservation.Lines)
+	}
+	for _, line := range reservation.Lines {
+		if line.BundleProductID != "gift" || line.Quantity != 2 {
+			t.Errorf("line = %+v, want 2 units from bundle gift", line)
+		}
+	}
+	if got := stockOf(t, repo, "tee", "tee-s"); got != 3 {
+		t.Errorf("TEE-S stock = %d, want 3", got)
+	}
+	if got := stockOf(t, repo, "mug", ""); got != 0 {
+		t.Errorf("MUG stock = %d, want 0", got)
+	}
+
+	// Ya no queda stock para otro bundle.
+	if err := repo.Reserve(ctx, newReservation(models.ReservationLine{ProductID: "gift", Quantity: 1})); !errors.Is(err, ErrInsufficientStock) {
+		t.Errorf("second Reserve() error = %v, want %v", err, ErrInsufficientStock)
+	}
+	if _, err := repo.ReleaseReservation(ctx, reservation.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := stockOf(t, repo, "mug", ""); got != 2 {
+		t.Errorf("MUG stock after release = %d, want 2", got)
+	}
+}
+
+func TestReserveInactiveBundle(t *testing.T) {
+	bundle := gift()
+	bundle.Status = models.StatusArchived
+	repo := newTestRepository(t, tee(), mug(), bundle)
+	err := repo.Reserve(context.Background(), newReservation(models.ReservationLine{ProductID: "gift", Quantity: 1}))
+	if !errors.Is(err, pricing.ErrUnavailable) {
+		t.Fatalf("Reserve() error = %v, want %v", err, pricing.ErrUnavailable)
+	}
+	if got := stockOf(t, repo, "mug", ""); got != 2 {
+		t.Errorf("MUG stock = %d, want 2", got)
+	}
+}
+
+// Un bundle no puede recibir variaciones generadas aunque tenga opciones
+// guardadas.
+func TestGenerateVariationsRejectsBundles(t *testing.T) {
+	bundle := gift()
+	bundle.Options = []models.ProductOption{{Name: "color", Values: []string{"red", "blue"}}}
+	repo := newTestRepository(t, tee(), mug(), bundle)
+
+	_, _, err := GenerateVariations(context.Background(), repo, "gift", AnyVersion, VariationTemplate{Price: 2500})
+	if !errors.Is(err, ErrBundleStock) {
+		t.Fatalf("err = %v, want ErrBundleStock", err)
+	}
+	if stored, _ := repo.Get(context.Background(), "gift"); len(stored.Variations) != 0 {
+		t.Errorf("variations = %d, want 0", len(stored.Variations))
+	}
+}
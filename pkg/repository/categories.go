@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/andrescris/firestore/lib/firebase"
+	"github.com/andrescris/products/pkg/models"
+)
+
+// CategoriesCollection es la colección del árbol de categorías.
+const CategoriesCollection = "categories"
+
+var (
+	// ErrCategoryNotFound se devuelve cuando la categoría (o su padre) no existe.
+	ErrCategoryNotFound = errors.New("category not found")
+	// ErrCategoryCycle se devuelve al mover una categoría bajo sí misma o bajo
+	// uno de sus descendientes.
+	ErrCategoryCycle = errors.New("a category cannot be moved under itself or its descendants")
+	// ErrCategorySlugTaken se devuelve cuando una categoría hermana ya usa el slug.
+	ErrCategorySlugTaken = errors.New("a sibling category already uses this slug")
+	// ErrCategoryHasChildren se devuelve al borrar una categoría con subcategorías.
+	ErrCategoryHasChildren = errors.New("category has subcategories")
+	// ErrCategoryProject se devuelve cuando el padre es de otro proyecto.
+	ErrCategoryProject = errors.New("parent category belongs to another project")
+)
+
+// CategoryRepository gestiona el árbol de categorías. El repositorio mantiene
+// Path, Ancestors y Depth de toda la rama cuando una categoría cambia de
+// slug o de padre.
+type CategoryRepository interface {
+	CreateCategory(ctx context.Context, category *models.Category) error
+	GetCategory(ctx context.Context, id string) (*models.Category, error)
+	// ListCategories devuelve las categorías del proyecto ordenadas por ruta.
+	ListCategories(ctx context.Context, projectID string) ([]models.Category, error)
+	// UpdateCategory aplica fn (que puede cambiar nombre, slug o padre) y
+	// devuelve la categoría y todos sus descendientes ya recalculados.
+	UpdateCategory(ctx context.Context, id string, fn func(*models.Category) error) ([]models.Category, error)
+	// DeleteCategory borra una categoría sin subcategorías.
+	DeleteCategory(ctx context.Context, id string) error
+}
+
+var (
+	slugInvalid = regexp.MustCompile(`[^a-z0-9]+`)
+	slugAccents = strings.NewReplacer("á", "a", "à", "a", "ä", "a", "â", "a", "é", "e", "è", "e", "ë", "e", "ê", "e",
+		"í", "i", "ì", "i", "ï", "i", "î", "i", "ó", "o", "ò", "o", "ö", "o", "ô", "o",
+		"ú", "u", "ù", "u", "ü", "u", "û", "u", "ñ", "n", "ç", "c")
+)
+
+// Slugify convierte un nombre en un slug: minúsculas, sin tildes y con
+// guiones ("Ropa de Niño" → "ropa-de-nino").
+func Slugify(name string) string {
+	slug := slugAccents.Replace(strings.ToLower(name))
+	return strings.Trim(slugInvalid.ReplaceAllString(slug, "-"), "-")
+}
+
+// placeCategory fija la ruta de la categoría bajo parent (nil para la raíz).
+func placeCategory(category *models.Category, parent *models.Category) {
+	if parent == nil {
+		category.ParentID = ""
+		category.Ancestors = []string{}
+		category.Path = category.Slug
+		category.Depth = 0
+		return
+	}
+	category.ParentID = parent.ID
+	category.Ancestors = parent.Lineage()
+	category.Path = parent.Path + "/" + category.Slug
+	category.Depth = parent.Depth + 1
+}
+
+// checkMove valida que parent pueda ser el nuevo padre de category.
+func checkMove(category *models.Category, parent *models.Category) error {
+	if parent == nil {
+		return nil
+	}
+	if parent.ProjectID != category.ProjectID {
+		return ErrCategoryProject
+	}
+	if parent.ID == category.ID {
+		return ErrCategoryCycle
+	}
+	for _, ancestor := range parent.Ancestors {
+		if ancestor == category.ID {
+			return ErrCategoryCycle
+		}
+	}
+	return nil
+}
+
+// checkSlug valida que ninguna hermana (otra categoría con el mismo padre en
+// el proyecto) use el slug de category.
+func checkSlug(category *models.Category, siblings []models.Category) error {
+	for _, sibling := range siblings {
+		if sibling.ID != category.ID && sibling.Slug == category.Slug {
+			return ErrCategorySlugTaken
+		}
+	}
+	return nil
+}
+
+// rebuildSubtree recalcula las rutas de los descendientes de root, que ya
+// está en su nueva posición. Devuelve root seguido de los descendientes.
+func rebuildSubtree(root models.Category, descendants []models.Category) []models.Category {
+	sort.Slice(descendants, func(i, j int) bool {
+		return descendants[i].Depth < descendants[j].Depth
+	})
+
+	placed := map[string]models.Category{root.ID: root}
+	result := []models.Category{root}
+	for _, descendant := range descendants {
+		parent, ok := placed[descendant.ParentID]
+		if !ok {
+			continue
+		}
+		placeCategory(&descendant, &parent)
+		descendant.UpdatedAt = root.UpdatedAt
+		placed[descendant.ID] = descendant
+		result = append(result, descendant)
+	}
+	return result
+}
+
+// CategorySchema devuelve el esquema de atributos efectivo de la categoría:
+// el de sus ancestros, de la raíz hacia abajo, y el propio.
+func CategorySchema(ctx context.Context, repo CategoryRepository, category *models.Category) ([]models.AttributeDefinition, error) {
+	schemas := make([][]models.AttributeDefinition, 0, len(category.Ancestors)+1)
+	for _, id := range category.Ancestors {
+		ancestor, err := repo.GetCategory(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, ancestor.Attributes)
+	}
+	schemas = append(schemas, category.Attributes)
+	return models.MergeDefinitions(schemas...), nil
+}
+
+// SetProductCategory enlaza el producto con la categoría: guarda su ID, su
+// ruta en Category y el linaje para filtrar por subárbol.
+func SetProductCategory(product *models.Product, category *models.Category) {
+	product.CategoryID = category.ID
+	product.Category = category.Path
+	product.CategoryIDs = category.Lineage()
+}
+
+// CategoryPathChanged indica si la ruta o los ancestros de la categoría
+// cambiaron, que es lo que los productos guardan de ella.
+func CategoryPathChanged(before, after *models.Category) bool {
+	return before.Path != after.Path || !slices.Equal(before.Ancestors, after.Ancestors)
+}
+
+// ReindexCategoryProducts actualiza la ruta y el linaje guardados en los
+// productos de las categorías indicadas (p. ej. tras mover una rama) y
+// devuelve cuántos productos se actualizaron. Los productos que ya tienen la
+// ruta y el linaje de su categoría no se escriben, así no cambian de versión
+// ni suman una revisión.
+func ReindexCategoryProducts(ctx context.Context, products ProductRepository, categories []models.Category) (int, error) {
+	updated := 0
+	for i := range categories {
+		category := &categories[i]
+		options := firebase.QueryOptions{
+			Filters: []firebase.QueryFilter{{Field: "categoryId", Operator: "==", Value: category.ID}},
+		}
+		err := EachProduct(ctx, products, options, 200, func(product models.Product) error {
+			if product.Category == category.Path && slices.Equal(product.CategoryIDs, category.Lineage()) {
+				return nil
+			}
+			_, err := products.Mutate(ctx, product.ID, AnyVersion, func(p *models.Product) error {
+				if p.CategoryID == category.ID {
+					SetProductCategory(p, category)
+				}
+				return nil
+			})
+			if err == nil {
+				updated++
+			}
+			return err
+		})
+		if err != nil {
+			return updated, err
+		}
+	}
+	return updated, nil
+}
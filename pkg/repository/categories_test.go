@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+// Reindexar solo escribe los productos cuya ruta o linaje quedó desactualizado.
+func TestReindexCategoryProductsSkipsCurrentProducts(t *testing.T) {
+	category := models.Category{ID: "pants", Slug: "pantalones", Path: "ropa/pantalones", Ancestors: []string{"ropa"}}
+	tests := []struct {
+		name        string
+		edit        func(*models.Category)
+		wantUpdated int
+		wantPath    string
+	}{
+		{"unchanged", func(*models.Category) {}, 0, "ropa/pantalones"},
+		{"renamed", func(c *models.Category) { c.Path = "ropa/jeans" }, 1, "ropa/jeans"},
+		{"moved", func(c *models.Category) { c.Path = "hombre/pantalones"; c.Ancestors = []string{"hombre"} }, 1, "hombre/pantalones"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product := mug()
+			SetProductCategory(product, &category)
+			repo := newTestRepository(t, product)
+			before, err := repo.Get(context.Background(), "mug")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			edited := category
+			edited.Ancestors = append([]string(nil), category.Ancestors...)
+			tt.edit(&edited)
+			if got := CategoryPathChanged(&category, &edited); got != (tt.wantUpdated > 0) {
+				t.Errorf("CategoryPathChanged = %v", got)
+			}
+			updated, err := ReindexCategoryProducts(context.Background(), repo, []models.Category{edited})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if updated != tt.wantUpdated {
+				t.Errorf("updated = %d, want %d", updated, tt.wantUpdated)
+			}
+
+			after, err := repo.Get(context.Background(), "mug")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if after.Category != tt.wantPath {
+				t.Errorf("category = %q, want %q", after.Category, tt.wantPath)
+			}
+			if want := before.Version + int64(tt.wantUpdated); after.Version != want {
+				t.Errorf("version = %d, want %d", after.Version, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/pricing"
+)
+
+// DeriveFields recalcula los campos desnormalizados del producto a partir de
+// sus datos: active según status, stock total por ubicación, precios
+// vigentes, filter_price, max_price, total_stock, in_stock,
+// active_variation_count, next_price_change_at y variation_deleted_at.
+// Todas las escrituras del repositorio lo aplican justo antes de guardar, así
+// que no hace falta llamarlo desde los handlers.
+//
+// Los precios se calculan con el precio vigente (el de oferta si la ventana
+// está abierta). En productos con variaciones solo cuentan las variaciones
+// activas; si no queda ninguna, los precios se calculan sobre todas para que
+// el producto siga siendo filtrable por precio.
+func DeriveFields(product *models.Product) {
+	deriveFieldsAt(product, time.Now().UTC())
+}
+
+func deriveFieldsAt(product *models.Product, now time.Time) {
+	syncStatus(product)
+	syncInventoryTotals(product)
+	pricing.ApplyEffectivePrices(product, now)
+	product.NextPriceChangeAt = pricing.NextPriceChange(product, now)
+	syncTrash(product)
+
+	if len(product.Variations) == 0 {
+		product.FilterPrice = product.EffectivePrice
+		product.MaxPrice = product.EffectivePrice
+		product.TotalStock = product.Stock
+		product.ActiveVariationCount = 0
+		product.InStock = product.TotalStock > 0
+		return
+	}
+
+	active := []models.Variation{}
+	for _, v := range product.Variations {
+		if v.Active {
+			active = append(active, v)
+		}
+	}
+	minPrice, maxPrice := pricing.PriceRange(product.Variations)
+
+	totalStock := 0
+	for _, v := range active {
+		totalStock += v.Stock
+	}
+
+	product.FilterPrice = minPrice
+	product.MaxPrice = maxPrice
+	product.TotalStock = totalStock
+	product.ActiveVariationCount = len(active)
+	product.InStock = totalStock > 0
+}
@@ -0,0 +1,249 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+	"github.com/google/uuid"
+)
+
+// OutboxCollection guarda los eventos de catálogo (outbox transaccional).
+const OutboxCollection = "outbox_events"
+
+// ErrEventNotFound se devuelve cuando el evento no está en el outbox.
+var ErrEventNotFound = errors.New("event not found")
+
+// EventRepository lee el registro de eventos de catálogo. Los eventos se
+// conservan después de repartirse a los webhooks hasta que los elimina la
+// retención, para que los streams puedan retomarse.
+type EventRepository interface {
+	// ListEvents devuelve los eventos del subdominio con ID posterior a
+	// afterID, del más antiguo al más nuevo. afterID vacío empieza por el primero.
+	ListEvents(ctx context.Context, subdomain, afterID string, limit int) ([]models.ProductEvent, error)
+	// PurgeEvents elimina hasta limit eventos ya repartidos creados antes de
+	// before y devuelve cuántos eliminó.
+	PurgeEvents(ctx context.Context, before time.Time, limit int) (int, error)
+}
+
+// EventCursor devuelve un ID anterior a todos los eventos creados desde at,
+// útil como afterID para leer "desde ahora".
+func EventCursor(at time.Time) string {
+	return fmt.Sprintf("evt-%020d", at.UnixNano())
+}
+
+// newEventID genera un ID que ordena los eventos por fecha de creación y,
+// dentro de una misma escritura, por su posición seq.
+func newEventID(at time.Time, seq int) string {
+	return fmt.Sprintf("evt-%020d-%02d-%s", at.UnixNano(), seq, uuid.New().String()[:8])
+}
+
+// stockFields son los campos de primer nivel que solo cambian con el stock;
+// sus cambios se publican como stock.changed y no como product.updated.
+var stockFields = map[string]bool{
+	"stock":                  true,
+	"inventory":              true,
+	"total_stock":            true,
+	"in_stock":               true,
+	"active_variation_count": true,
+	"variation_deleted_at":   true,
+}
+
+// productEvents traduce una escritura a eventos de catálogo a partir de su
+// revisión y de sus movimientos de stock. created indica que la escritura
+// creó el producto.
+func productEvents(revision models.ProductRevision, movements []models.StockMovement, created bool) ([]models.ProductEvent, error) {
+	events := []models.ProductEvent{}
+	add := func(eventType models.EventType, variationID string, data map[string]interface{}) error {
+		normalized, err := normalizeData(data)
+		if err != nil {
+			return err
+		}
+		events = append(events, models.ProductEvent{
+			ID:             newEventID(revision.CreatedAt, len(events)),
+			Type:           eventType,
+			Subdomain:      revision.Subdomain,
+			ProductID:      revision.ProductID,
+			VariationID:    variationID,
+			ProductVersion: revision.Revision,
+			Actor:          revision.Actor,
+			ProductStatus:  models.ProductStatus(snapshotString(revision.Snapshot, "status")),
+			CategoryIDs:    snapshotStrings(revision.Snapshot, "category_ids"),
+			Data:           normalized,
+			CreatedAt:      revision.CreatedAt,
+		})
+		return nil
+	}
+
+	if created {
+		if err := add(models.EventProductCreated, "", map[string]interface{}{"product": revision.Snapshot}); err != nil {
+			return nil, err
+		}
+	} else {
+		product, variations := classifyChanges(revision.Changes)
+		switch {
+		case product.deactivated:
+			if err := add(models.EventProductDeactivated, "", map[string]interface{}{"product": revision.Snapshot}); err != nil {
+				return nil, err
+			}
+		case len(product.changes) > 0:
+			data := map[string]interface{}{"product": revision.Snapshot, "changes": product.changes}
+			if err := add(models.EventProductUpdated, "", data); err != nil {
+				return nil, err
+			}
+		}
+		for _, v := range variations {
+			if v.eventType == "" {
+				continue
+			}
+			variation := snapshotVariation(revision.Snapshot, v.id)
+			if variation == nil {
+				// La variación se eliminó definitivamente.
+				variation = v.removed
+			}
+			data := map[string]interface{}{"variation": variation}
+			if v.eventType == models.EventVariationUpdated {
+				data["changes"] = v.changes
+			}
+			if err := add(v.eventType, v.id, data); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(movements) > 0 {
+		if err := add(models.EventStockChanged, "", map[string]interface{}{"movements": movements}); err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+// productChange resume los cambios de primer nivel de una revisión.
+type productChange struct {
+	deactivated bool
+	changes     []models.FieldChange
+}
+
+// variationChange resume los cambios de una variación. eventType queda vacío
+// si solo cambió su stock.
+type variationChange struct {
+	id        string
+	eventType models.EventType
+	changes   []models.FieldChange
+	removed   interface{}
+}
+
+// classifyChanges reparte los cambios de una revisión entre el producto y
+// cada variación, en el orden en que aparecen.
+func classifyChanges(changes []models.FieldChange) (productChange, []*variationChange) {
+	var product productChange
+	variations := []*variationChange{}
+	byID := map[string]*variationChange{}
+
+	for _, change := range changes {
+		if id, rest, ok := variationField(change.Field); ok {
+			v := byID[id]
+			if v == nil {
+				v = &variationChange{id: id}
+				byID[id] = v
+				variations = append(variations, v)
+			}
+			v.classify(rest, change)
+			continue
+		}
+
+		root := strings.SplitN(change.Field, ".", 2)[0]
+		switch {
+		case change.Field == "deletedAt" && change.Before == nil && change.After != nil,
+			change.Field == "status" && change.After == string(models.StatusArchived):
+			product.deactivated = true
+		case stockFields[root]:
+		default:
+			product.changes = append(product.changes, change)
+		}
+	}
+	return product, variations
+}
+
+func (v *variationChange) classify(rest string, change models.FieldChange) {
+	switch {
+	case rest == "" && change.Before == nil:
+		v.eventType = models.EventVariationCreated
+	case rest == "" && change.After == nil:
+		v.eventType = models.EventVariationDeactivated
+		v.removed = change.Before
+	case rest == ".active" && change.After == false:
+		v.eventType = models.EventVariationDeactivated
+	case rest == ".stock" || strings.HasPrefix(rest, ".inventory"):
+	default:
+		if v.eventType == "" {
+			v.eventType = models.EventVariationUpdated
+		}
+		v.changes = append(v.changes, change)
+	}
+}
+
+// variationField separa "variations[ID].resto" en el ID y el resto.
+func variationField(field string) (id, rest string, ok bool) {
+	if !strings.HasPrefix(field, "variations[") {
+		return "", "", false
+	}
+	end := strings.Index(field, "]")
+	if end < 0 {
+		return "", "", false
+	}
+	return field[len("variations["):end], field[end+1:], true
+}
+
+// snapshotVariation busca la variación en el documento del producto.
+func snapshotVariation(snapshot map[string]interface{}, id string) interface{} {
+	variations, _ := snapshot["variations"].([]interface{})
+	for _, item := range variations {
+		if v, ok := item.(map[string]interface{}); ok && v["id"] == id {
+			return v
+		}
+	}
+	return nil
+}
+
+func snapshotString(snapshot map[string]interface{}, field string) string {
+	value, _ := snapshot[field].(string)
+	return value
+}
+
+func snapshotStrings(snapshot map[string]interface{}, field string) []string {
+	items, _ := snapshot[field].([]interface{})
+	values := []string{}
+	for _, item := range items {
+		if value, ok := item.(string); ok {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
+
+// eventsFor genera los eventos de varios productos modificados juntos.
+func eventsFor(revisions []models.ProductRevision, movements []models.StockMovement) ([]models.ProductEvent, error) {
+	events := []models.ProductEvent{}
+	for _, revision := range revisions {
+		own := []models.StockMovement{}
+		for _, m := range movements {
+			if m.ProductID == revision.ProductID {
+				own = append(own, m)
+			}
+		}
+		productEvents, err := productEvents(revision, own, false)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, productEvents...)
+	}
+	return events, nil
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+// ExchangeRatesCollection guarda una tabla de tipos de cambio por proyecto.
+const ExchangeRatesCollection = "exchange_rates"
+
+// ErrExchangeRatesNotFound se devuelve cuando el proyecto no tiene tabla de
+// tipos de cambio.
+var ErrExchangeRatesNotFound = errors.New("exchange rate table not found")
+
+// ExchangeRateRepository gestiona las tablas de tipos de cambio.
+type ExchangeRateRepository interface {
+	GetExchangeRates(ctx context.Context, projectID string) (*models.ExchangeRateTable, error)
+	// SaveExchangeRates reemplaza la tabla del proyecto.
+	SaveExchangeRates(ctx context.Context, table *models.ExchangeRateTable) error
+}
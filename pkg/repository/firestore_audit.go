@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+
+	gcfirestore "cloud.google.com/go/firestore"
+	"github.com/andrescris/products/pkg/models"
+)
+
+func (r *FirestoreRepository) RecordAudit(ctx context.Context, entry *models.AuditEntry) error {
+	_, err := r.client.Collection(AuditLogCollection).Doc(entry.ID).Create(ctx, entry)
+	return err
+}
+
+func (r *FirestoreRepository) ListAuditEntries(ctx context.Context, filter AuditFilter, limit, offset int) ([]models.AuditEntry, error) {
+	query := r.client.Collection(AuditLogCollection).Query
+	if filter.Subdomain != "" {
+		query = query.Where("subdomain", "==", filter.Subdomain)
+	}
+	if filter.Unattributed {
+		query = query.Where("subdomain", "==", "")
+	}
+	if filter.Route != "" {
+		query = query.Where("route", "==", filter.Route)
+	}
+	if filter.UID != "" {
+		query = query.Where("actor.uid", "==", filter.UID)
+	}
+	if filter.APIKey != "" {
+		query = query.Where("actor.apiKey", "==", filter.APIKey)
+	}
+	if filter.ProductID != "" {
+		query = query.Where("targets.id", "==", filter.ProductID)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("createdAt", ">=", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("createdAt", "<", filter.To)
+	}
+	query = query.OrderBy("createdAt", gcfirestore.Desc)
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	snaps, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]models.AuditEntry, 0, len(snaps))
+	for _, snap := range snaps {
+		var entry models.AuditEntry
+		if err := snap.DataTo(&entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+
+	gcfirestore "cloud.google.com/go/firestore"
+	"github.com/andrescris/products/pkg/models"
+)
+
+// ApplyBulk aplica el lote en una transacción de Firestore: primero lee todos
+// los productos y después escribe los documentos y su historial.
+func (r *FirestoreRepository) ApplyBulk(ctx context.Context, writes []BulkWrite) ([]*models.Product, error) {
+	if err := checkBulkWrites(writes); err != nil {
+		return nil, err
+	}
+
+	refs := make([]*gcfirestore.DocumentRef, len(writes))
+	for i, w := range writes {
+		refs[i] = r.client.Collection(ProductsCollection).Doc(w.productID())
+	}
+
+	var products []*models.Product
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *gcfirestore.Transaction) error {
+		snaps, err := tx.GetAll(refs)
+		if err != nil {
+			return err
+		}
+
+		history := &bulkHistory{}
+		products = make([]*models.Product, len(writes))
+		docs := make([]map[string]interface{}, len(writes))
+		for i, w := range writes {
+			var doc map[string]interface{}
+			if snaps[i].Exists() {
+				doc = snaps[i].Data()
+			}
+			product, err := applyBulkWrite(ctx, w, doc, history)
+			if err != nil {
+				return &BulkError{Index: i, Err: err}
+			}
+			if docs[i], err = productToData(product); err != nil {
+				return &BulkError{Index: i, Err: err}
+			}
+			products[i] = product
+		}
+
+		for i, w := range writes {
+			if w.Create != nil {
+				err = tx.Create(refs[i], docs[i])
+			} else {
+				err = tx.Set(refs[i], docs[i])
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return r.appendHistoryTx(tx, history.movements, history.revisions, history.events)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	gcfirestore "cloud.google.com/go/firestore"
+	"github.com/andrescris/products/pkg/models"
+)
+
+func (r *FirestoreRepository) CreateCategory(ctx context.Context, category *models.Category) error {
+	ref := r.client.Collection(CategoriesCollection).Doc(category.ID)
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *gcfirestore.Transaction) error {
+		if err := r.placeCategoryTx(tx, category); err != nil {
+			return err
+		}
+		return tx.Create(ref, category)
+	})
+}
+
+func (r *FirestoreRepository) GetCategory(ctx context.Context, id string) (*models.Category, error) {
+	snap, err := r.client.Collection(CategoriesCollection).Doc(id).Get(ctx)
+	return categoryFromSnapshot(snap, err)
+}
+
+func (r *FirestoreRepository) ListCategories(ctx context.Context, projectID string) ([]models.Category, error) {
+	snaps, err := r.client.Collection(CategoriesCollection).
+		Where("project_id", "==", projectID).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	categories, err := categoriesFromSnapshots(snaps)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].Path < categories[j].Path
+	})
+	return categories, nil
+}
+
+func (r *FirestoreRepository) UpdateCategory(ctx context.Context, id string, fn func(*models.Category) error) ([]models.Category, error) {
+	collection := r.client.Collection(CategoriesCollection)
+
+	var subtree []models.Category
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *gcfirestore.Transaction) error {
+		category, err := categoryFromSnapshot(tx.Get(collection.Doc(id)))
+		if err != nil {
+			return err
+		}
+		if err := fn(category); err != nil {
+			return err
+		}
+		if err := r.placeCategoryTx(tx, category); err != nil {
+			return err
+		}
+		category.UpdatedAt = time.Now().UTC()
+
+		snaps, err := tx.Documents(collection.Where("ancestors", "array-contains", id)).GetAll()
+		if err != nil {
+			return err
+		}
+		descendants, err := categoriesFromSnapshots(snaps)
+		if err != nil {
+			return err
+		}
+
+		subtree = rebuildSubtree(*category, descendants)
+		for _, c := range subtree {
+			if err := tx.Set(collection.Doc(c.ID), c); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return subtree, nil
+}
+
+func (r *FirestoreRepository) DeleteCategory(ctx context.Context, id string) error {
+	collection := r.client.Collection(CategoriesCollection)
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *gcfirestore.Transaction) error {
+		if _, err := categoryFromSnapshot(tx.Get(collection.Doc(id))); err != nil {
+			return err
+		}
+		children, err := tx.Documents(collection.Where("parentId", "==", id).Limit(1)).GetAll()
+		if err != nil {
+			return err
+		}
+		if len(children) > 0 {
+			return ErrCategoryHasChildren
+		}
+		return tx.Delete(collection.Doc(id))
+	})
+}
+
+// placeCategoryTx valida el padre y el slug de la categoría y calcula su ruta
+// leyendo dentro de la transacción.
+func (r *FirestoreRepository) placeCategoryTx(tx *gcfirestore.Transaction, category *models.Category) error {
+	collection := r.client.Collection(CategoriesCollection)
+
+	var parent *models.Category
+	if category.ParentID != "" {
+		p, err := categoryFromSnapshot(tx.Get(collection.Doc(category.ParentID)))
+		if err != nil {
+			return err
+		}
+		parent = p
+	}
+	if err := checkMove(category, parent); err != nil {
+		return err
+	}
+	placeCategory(category, parent)
+
+	snaps, err := tx.Documents(collection.
+		Where("project_id", "==", category.ProjectID).
+		Where("parentId", "==", category.ParentID).
+		Where("slug", "==", category.Slug)).GetAll()
+	if err != nil {
+		return err
+	}
+	siblings, err := categoriesFromSnapshots(snaps)
+	if err != nil {
+		return err
+	}
+	return checkSlug(category, siblings)
+}
+
+func categoryFromSnapshot(snap *gcfirestore.DocumentSnapshot, err error) (*models.Category, error) {
+	if snap != nil && !snap.Exists() {
+		return nil, ErrCategoryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var category models.Category
+	if err := snap.DataTo(&category); err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+func categoriesFromSnapshots(snaps []*gcfirestore.DocumentSnapshot) ([]models.Category, error) {
+	categories := make([]models.Category, 0, len(snaps))
+	for _, snap := range snaps {
+		var category models.Category
+		if err := snap.DataTo(&category); err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, nil
+}
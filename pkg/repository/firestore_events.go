@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	gcfirestore "cloud.google.com/go/firestore"
+	"github.com/andrescris/products/pkg/models"
+)
+
+func (r *FirestoreRepository) ListEvents(ctx context.Context, subdomain, afterID string, limit int) ([]models.ProductEvent, error) {
+	query := r.client.Collection(OutboxCollection).
+		Where("subdomain", "==", subdomain).
+		Where("id", ">", afterID).
+		OrderBy("id", gcfirestore.Asc)
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	return r.queryEvents(ctx, query)
+}
+
+func (r *FirestoreRepository) PurgeEvents(ctx context.Context, before time.Time, limit int) (int, error) {
+	query := r.client.Collection(OutboxCollection).
+		Where("dispatched", "==", true).
+		Where("createdAt", "<", before)
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	snaps, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return 0, err
+	}
+	for i, snap := range snaps {
+		if _, err := snap.Ref.Delete(ctx); err != nil {
+			return i, err
+		}
+	}
+	return len(snaps), nil
+}
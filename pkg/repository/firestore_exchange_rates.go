@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+func (r *FirestoreRepository) GetExchangeRates(ctx context.Context, projectID string) (*models.ExchangeRateTable, error) {
+	snap, err := r.client.Collection(ExchangeRatesCollection).Doc(projectID).Get(ctx)
+	if snap != nil && !snap.Exists() {
+		return nil, ErrExchangeRatesNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var table models.ExchangeRateTable
+	if err := snap.DataTo(&table); err != nil {
+		return nil, err
+	}
+	return &table, nil
+}
+
+func (r *FirestoreRepository) SaveExchangeRates(ctx context.Context, table *models.ExchangeRateTable) error {
+	_, err := r.client.Collection(ExchangeRatesCollection).Doc(table.ProjectID).Set(ctx, table)
+	return err
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	gcfirestore "cloud.google.com/go/firestore"
+	"github.com/andrescris/products/pkg/models"
+)
+
+func (r *FirestoreRepository) CreateLocation(ctx context.Context, location *models.Location) error {
+	_, err := r.client.Collection(LocationsCollection).Doc(location.ID).Create(ctx, location)
+	return err
+}
+
+func (r *FirestoreRepository) GetLocation(ctx context.Context, id string) (*models.Location, error) {
+	snap, err := r.client.Collection(LocationsCollection).Doc(id).Get(ctx)
+	if snap != nil && !snap.Exists() {
+		return nil, ErrLocationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var location models.Location
+	if err := snap.DataTo(&location); err != nil {
+		return nil, err
+	}
+	return &location, nil
+}
+
+func (r *FirestoreRepository) ListLocations(ctx context.Context, projectID string) ([]models.Location, error) {
+	snaps, err := r.client.Collection(LocationsCollection).
+		Where("project_id", "==", projectID).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	locations := make([]models.Location, 0, len(snaps))
+	for _, snap := range snaps {
+		var location models.Location
+		if err := snap.DataTo(&location); err != nil {
+			return nil, err
+		}
+		locations = append(locations, location)
+	}
+	sort.Slice(locations, func(i, j int) bool {
+		return locations[i].Name < locations[j].Name
+	})
+	return locations, nil
+}
+
+func (r *FirestoreRepository) UpdateLocation(ctx context.Context, id string, fn func(*models.Location) error) (*models.Location, error) {
+	ref := r.client.Collection(LocationsCollection).Doc(id)
+
+	var result *models.Location
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *gcfirestore.Transaction) error {
+		snap, err := tx.Get(ref)
+		if snap != nil && !snap.Exists() {
+			return ErrLocationNotFound
+		}
+		if err != nil {
+			return err
+		}
+		var location models.Location
+		if err := snap.DataTo(&location); err != nil {
+			return err
+		}
+		if err := fn(&location); err != nil {
+			return err
+		}
+		location.UpdatedAt = time.Now().UTC()
+		result = &location
+		return tx.Set(ref, location)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *FirestoreRepository) TransferStock(ctx context.Context, transfer *models.StockTransfer) error {
+	ids := make([]string, len(transfer.Lines))
+	for i, line := range transfer.Lines {
+		ids[i] = line.ProductID
+	}
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *gcfirestore.Transaction) error {
+		products, err := r.loadProductsTx(tx, uniqueStrings(ids), false)
+		if err != nil {
+			return err
+		}
+		docs, err := captureDocs(products)
+		if err != nil {
+			return err
+		}
+		if err := transferStock(products, transfer); err != nil {
+			return err
+		}
+		touchProducts(products)
+		if err := r.storeProductsTx(tx, products, docs, nil, stockChangeFrom(ctx, models.MovementAdjustment)); err != nil {
+			return err
+		}
+		return tx.Create(r.client.Collection(StockTransfersCollection).Doc(transfer.ID), transfer)
+	})
+}
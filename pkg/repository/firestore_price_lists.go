@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	gcfirestore "cloud.google.com/go/firestore"
+	"github.com/andrescris/products/pkg/models"
+)
+
+func (r *FirestoreRepository) CreatePriceList(ctx context.Context, list *models.PriceList) error {
+	_, err := r.client.Collection(PriceListsCollection).Doc(list.ID).Create(ctx, list)
+	return err
+}
+
+func (r *FirestoreRepository) GetPriceList(ctx context.Context, id string) (*models.PriceList, error) {
+	snap, err := r.client.Collection(PriceListsCollection).Doc(id).Get(ctx)
+	if snap != nil && !snap.Exists() {
+		return nil, ErrPriceListNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list models.PriceList
+	if err := snap.DataTo(&list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+func (r *FirestoreRepository) ListPriceLists(ctx context.Context, projectID string) ([]models.PriceList, error) {
+	snaps, err := r.client.Collection(PriceListsCollection).
+		Where("project_id", "==", projectID).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	lists := make([]models.PriceList, 0, len(snaps))
+	for _, snap := range snaps {
+		var list models.PriceList
+		if err := snap.DataTo(&list); err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+	}
+	sort.Slice(lists, func(i, j int) bool {
+		return lists[i].Name < lists[j].Name
+	})
+	return lists, nil
+}
+
+func (r *FirestoreRepository) FindPriceList(ctx context.Context, subdomain, customerGroup string) (*models.PriceList, error) {
+	snaps, err := r.client.Collection(PriceListsCollection).
+		Where("subdomain", "==", subdomain).
+		Where("customerGroup", "==", customerGroup).
+		Where("active", "==", true).
+		Limit(1).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(snaps) == 0 {
+		return nil, ErrPriceListNotFound
+	}
+	var list models.PriceList
+	if err := snaps[0].DataTo(&list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+func (r *FirestoreRepository) UpdatePriceList(ctx context.Context, id string, fn func(*models.PriceList) error) (*models.PriceList, error) {
+	ref := r.client.Collection(PriceListsCollection).Doc(id)
+
+	var result *models.PriceList
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *gcfirestore.Transaction) error {
+		snap, err := tx.Get(ref)
+		if snap != nil && !snap.Exists() {
+			return ErrPriceListNotFound
+		}
+		if err != nil {
+			return err
+		}
+		var list models.PriceList
+		if err := snap.DataTo(&list); err != nil {
+			return err
+		}
+		if err := fn(&list); err != nil {
+			return err
+		}
+		list.UpdatedAt = time.Now().UTC()
+		result = &list
+		return tx.Set(ref, list)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,281 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	gcfirestore "cloud.google.com/go/firestore"
+	"github.com/andrescris/firestore/lib/firebase"
+	"github.com/andrescris/firestore/lib/firebase/firestore"
+	"github.com/andrescris/products/pkg/models"
+)
+
+// FirestoreRepository implementa ProductRepository sobre la librería de Firestore.
+// Las lecturas usan la librería; las escrituras read-modify-write usan
+// transacciones del cliente para no perder cambios concurrentes.
+type FirestoreRepository struct {
+	client *gcfirestore.Client
+}
+
+// NewFirestoreRepository crea el repositorio. Firebase ya debe estar inicializado.
+func NewFirestoreRepository(client *gcfirestore.Client) *FirestoreRepository {
+	return &FirestoreRepository{client: client}
+}
+
+// Get devuelve ErrNotFound solo si el documento no existe; los demás errores
+// de Firestore (permisos, red, cuota) se devuelven tal cual.
+func (r *FirestoreRepository) Get(ctx context.Context, id string) (*models.Product, error) {
+	snap, err := r.client.Collection(ProductsCollection).Doc(id).Get(ctx)
+	if snap != nil && !snap.Exists() {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return productFromData(snap.Data())
+}
+
+// Create guarda el producto y, en la misma transacción, los movimientos de
+// stock inicial y la primera revisión.
+func (r *FirestoreRepository) Create(ctx context.Context, product *models.Product) error {
+	if product.Version == 0 {
+		product.Version = 1
+	}
+	DeriveFields(product)
+	data, err := productToData(product)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	change := stockChangeFrom(ctx, models.MovementInitial)
+	movements := stockMovements(product, nil, change, now)
+	revision, err := newRevision(product, nil, change, now)
+	if err != nil {
+		return err
+	}
+	events, err := productEvents(revision, movements, true)
+	if err != nil {
+		return err
+	}
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *gcfirestore.Transaction) error {
+		if err := tx.Create(r.client.Collection(ProductsCollection).Doc(product.ID), data); err != nil {
+			return err
+		}
+		return r.appendHistoryTx(tx, movements, []models.ProductRevision{revision}, events)
+	})
+}
+
+func (r *FirestoreRepository) Query(ctx context.Context, options firebase.QueryOptions) ([]models.Product, error) {
+	docs, err := firestore.QueryDocuments(ctx, ProductsCollection, options)
+	if err != nil {
+		return nil, err
+	}
+
+	products := make([]models.Product, 0, len(docs))
+	for _, doc := range docs {
+		product, err := productFromData(doc.Data)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, *product)
+	}
+	return products, nil
+}
+
+// Mutate lee, modifica y reescribe el producto dentro de una transacción de
+// Firestore, que reintenta automáticamente si otro escritor se adelanta.
+func (r *FirestoreRepository) Mutate(ctx context.Context, id string, expectedVersion int64, fn func(*models.Product) error) (*models.Product, error) {
+	ref := r.client.Collection(ProductsCollection).Doc(id)
+
+	var result *models.Product
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *gcfirestore.Transaction) error {
+		snap, err := tx.Get(ref)
+		if snap != nil && !snap.Exists() {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		product, err := productFromData(snap.Data())
+		if err != nil {
+			return err
+		}
+		before := stockLevels(product)
+		if err := applyMutation(product, expectedVersion, fn); err != nil {
+			return err
+		}
+
+		data, err := productToData(product)
+		if err != nil {
+			return err
+		}
+		if err := tx.Set(ref, data); err != nil {
+			return err
+		}
+		change := stockChangeFrom(ctx, models.MovementAdjustment)
+		movements := stockMovements(product, before, change, product.UpdatedAt)
+		revision, err := newRevision(product, snap.Data(), change, product.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		events, err := productEvents(revision, movements, false)
+		if err != nil {
+			return err
+		}
+		if err := r.appendHistoryTx(tx, movements, []models.ProductRevision{revision}, events); err != nil {
+			return err
+		}
+		result = product
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *FirestoreRepository) Update(ctx context.Context, id string, expectedVersion int64, updates map[string]interface{}) (*models.Product, error) {
+	return r.Mutate(ctx, id, expectedVersion, mergeUpdates(updates))
+}
+
+func (r *FirestoreRepository) Deactivate(ctx context.Context, id string, expectedVersion int64) (*models.Product, error) {
+	return r.Mutate(ctx, id, expectedVersion, deactivate(ctx))
+}
+
+func (r *FirestoreRepository) AddVariation(ctx context.Context, productID string, expectedVersion int64, variation models.Variation) (*models.Product, error) {
+	return r.Mutate(ctx, productID, expectedVersion, addVariation(variation))
+}
+
+func (r *FirestoreRepository) UpdateVariation(ctx context.Context, productID, variationID string, expectedVersion int64, fn func(*models.Variation) error) (*models.Product, error) {
+	return r.Mutate(ctx, productID, expectedVersion, updateVariation(variationID, fn))
+}
+
+func (r *FirestoreRepository) DeactivateVariation(ctx context.Context, productID, variationID string, expectedVersion int64) (*models.Product, error) {
+	return r.Mutate(ctx, productID, expectedVersion, updateVariation(variationID, deactivateVariation(ctx)))
+}
+
+// Delete elimina el producto y todo lo que guarda copias de sus datos: las
+// revisiones, los movimientos de stock, los eventos del outbox y los envíos
+// de esos eventos a webhooks. El producto se borra al final para que, si algo
+// falla a medias, la retención vuelva a intentarlo.
+func (r *FirestoreRepository) Delete(ctx context.Context, id string) error {
+	related := []struct{ collection, field string }{
+		{RevisionsCollection, "productId"},
+		{StockMovementsCollection, "productId"},
+		{OutboxCollection, "productId"},
+		{WebhookDeliveriesCollection, "event.productId"},
+	}
+	for _, rel := range related {
+		snaps, err := r.client.Collection(rel.collection).Where(rel.field, "==", id).Documents(ctx).GetAll()
+		if err != nil {
+			return err
+		}
+		for _, snap := range snaps {
+			if _, err := snap.Ref.Delete(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := r.client.Collection(ProductsCollection).Doc(id).Delete(ctx)
+	return err
+}
+
+func (r *FirestoreRepository) ListStockMovements(ctx context.Context, productID string, limit, offset int) ([]models.StockMovement, error) {
+	query := r.client.Collection(StockMovementsCollection).
+		Where("productId", "==", productID).
+		OrderBy("productVersion", gcfirestore.Desc).
+		OrderBy("sku", gcfirestore.Asc)
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	snaps, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	movements := make([]models.StockMovement, 0, len(snaps))
+	for _, snap := range snaps {
+		var movement models.StockMovement
+		if err := snap.DataTo(&movement); err != nil {
+			return nil, err
+		}
+		movements = append(movements, movement)
+	}
+	return movements, nil
+}
+
+func (r *FirestoreRepository) ListRevisions(ctx context.Context, productID string, limit, offset int) ([]models.ProductRevision, error) {
+	query := r.client.Collection(RevisionsCollection).
+		Where("productId", "==", productID).
+		OrderBy("revision", gcfirestore.Desc)
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	snaps, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	revisions := make([]models.ProductRevision, 0, len(snaps))
+	for _, snap := range snaps {
+		var revision models.ProductRevision
+		if err := snap.DataTo(&revision); err != nil {
+			return nil, err
+		}
+		revision.Snapshot = nil
+		revisions = append(revisions, revision)
+	}
+	return revisions, nil
+}
+
+func (r *FirestoreRepository) GetRevision(ctx context.Context, productID string, revision int64) (*models.ProductRevision, error) {
+	snap, err := r.client.Collection(RevisionsCollection).Doc(revisionID(productID, revision)).Get(ctx)
+	if snap != nil && !snap.Exists() {
+		return nil, ErrRevisionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var stored models.ProductRevision
+	if err := snap.DataTo(&stored); err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// appendHistoryTx agrega dentro de la transacción lo que deja cada escritura
+// de productos: movimientos de stock, revisiones y eventos del outbox.
+func (r *FirestoreRepository) appendHistoryTx(tx *gcfirestore.Transaction, movements []models.StockMovement, revisions []models.ProductRevision, events []models.ProductEvent) error {
+	if err := r.appendMovementsTx(tx, movements); err != nil {
+		return err
+	}
+	for _, revision := range revisions {
+		if err := tx.Create(r.client.Collection(RevisionsCollection).Doc(revision.ID), revision); err != nil {
+			return err
+		}
+	}
+	for _, event := range events {
+		if err := tx.Create(r.client.Collection(OutboxCollection).Doc(event.ID), event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendMovementsTx agrega movimientos al libro dentro de la transacción.
+func (r *FirestoreRepository) appendMovementsTx(tx *gcfirestore.Transaction, movements []models.StockMovement) error {
+	for _, movement := range movements {
+		if err := tx.Create(r.client.Collection(StockMovementsCollection).Doc(movement.ID), movement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
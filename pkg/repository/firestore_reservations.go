@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	gcfirestore "cloud.google.com/go/firestore"
+	"github.com/andrescris/products/pkg/models"
+)
+
+func (r *FirestoreRepository) Reserve(ctx context.Context, reservation *models.Reservation) error {
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *gcfirestore.Transaction) error {
+		products, err := expandBundleLines(reservation, func(ids []string) (map[string]*models.Product, error) {
+			return r.loadProductsTx(tx, ids, false)
+		})
+		if err != nil {
+			return err
+		}
+		before := captureStock(products)
+		docs, err := captureDocs(products)
+		if err != nil {
+			return err
+		}
+		if err := reserveStock(products, reservation); err != nil {
+			return err
+		}
+		touchProducts(products)
+		change := reservationStockChange(ctx, models.MovementReservation, reservation.ID)
+		movements := movementsFor(products, before, change, reservation.CreatedAt)
+		if err := r.storeProductsTx(tx, products, docs, movements, change); err != nil {
+			return err
+		}
+		return tx.Create(r.client.Collection(ReservationsCollection).Doc(reservation.ID), reservation)
+	})
+}
+
+func (r *FirestoreRepository) GetReservation(ctx context.Context, id string) (*models.Reservation, error) {
+	snap, err := r.client.Collection(ReservationsCollection).Doc(id).Get(ctx)
+	if snap != nil && !snap.Exists() {
+		return nil, ErrReservationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var reservation models.Reservation
+	if err := snap.DataTo(&reservation); err != nil {
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+func (r *FirestoreRepository) CommitReservation(ctx context.Context, id string, now time.Time) (*models.Reservation, error) {
+	ref := r.client.Collection(ReservationsCollection).Doc(id)
+
+	var result *models.Reservation
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *gcfirestore.Transaction) error {
+		reservation, err := getReservationTx(tx, ref)
+		if err != nil {
+			return err
+		}
+		if reservation.Status == models.ReservationActive && now.After(reservation.ExpiresAt) {
+			return ErrReservationExpired
+		}
+		if err := closeReservation(reservation, models.ReservationCommitted, now); err != nil {
+			return err
+		}
+		result = reservation
+		return tx.Set(ref, reservation)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *FirestoreRepository) ReleaseReservation(ctx context.Context, id string) (*models.Reservation, error) {
+	change := reservationStockChange(ctx, models.MovementReservationRelease, id)
+	return r.returnReservation(ctx, id, models.ReservationReleased, change, time.Now().UTC(), false)
+}
+
+func (r *FirestoreRepository) ExpireReservation(ctx context.Context, id string, now time.Time) (*models.Reservation, error) {
+	change := reservationStockChange(ctx, models.MovementReservationExpiry, id)
+	return r.returnReservation(ctx, id, models.ReservationExpired, change, now, true)
+}
+
+func (r *FirestoreRepository) ListExpiredReservations(ctx context.Context, now time.Time, limit int) ([]models.Reservation, error) {
+	query := r.client.Collection(ReservationsCollection).
+		Where("status", "==", string(models.ReservationActive)).
+		Where("expiresAt", "<=", now).
+		OrderBy("expiresAt", gcfirestore.Asc)
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	snaps, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	reservations := make([]models.Reservation, 0, len(snaps))
+	for _, snap := range snaps {
+		var reservation models.Reservation
+		if err := snap.DataTo(&reservation); err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, reservation)
+	}
+	return reservations, nil
+}
+
+// returnReservation cierra la reserva con el estado indicado y devuelve su stock.
+// Con onlyIfExpired, una reserva que no está activa o no ha vencido se deja igual.
+func (r *FirestoreRepository) returnReservation(ctx context.Context, id string, status models.ReservationStatus, change StockChange, now time.Time, onlyIfExpired bool) (*models.Reservation, error) {
+	ref := r.client.Collection(ReservationsCollection).Doc(id)
+
+	var result *models.Reservation
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *gcfirestore.Transaction) error {
+		reservation, err := getReservationTx(tx, ref)
+		if err != nil {
+			return err
+		}
+		result = reservation
+		if onlyIfExpired && (reservation.Status != models.ReservationActive || reservation.ExpiresAt.After(now)) {
+			return nil
+		}
+		if err := closeReservation(reservation, status, now); err != nil {
+			return err
+		}
+
+		products, err := r.loadProductsTx(tx, reservationProductIDs(reservation.Lines), true)
+		if err != nil {
+			return err
+		}
+		before := captureStock(products)
+		docs, err := captureDocs(products)
+		if err != nil {
+			return err
+		}
+		restoreStock(products, reservation)
+		touchProducts(products)
+		if err := r.storeProductsTx(tx, products, docs, movementsFor(products, before, change, now), change); err != nil {
+			return err
+		}
+		return tx.Set(ref, reservation)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func getReservationTx(tx *gcfirestore.Transaction, ref *gcfirestore.DocumentRef) (*models.Reservation, error) {
+	snap, err := tx.Get(ref)
+	if snap != nil && !snap.Exists() {
+		return nil, ErrReservationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var reservation models.Reservation
+	if err := snap.DataTo(&reservation); err != nil {
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+// loadProductsTx lee los productos dentro de la transacción. Con skipMissing,
+// los productos que ya no existen se omiten en lugar de devolver ErrNotFound.
+func (r *FirestoreRepository) loadProductsTx(tx *gcfirestore.Transaction, ids []string, skipMissing bool) (map[string]*models.Product, error) {
+	products := make(map[string]*models.Product, len(ids))
+	for _, id := range ids {
+		snap, err := tx.Get(r.client.Collection(ProductsCollection).Doc(id))
+		if snap != nil && !snap.Exists() {
+			if skipMissing {
+				continue
+			}
+			return nil, ErrNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		product, err := productFromData(snap.Data())
+		if err != nil {
+			return nil, err
+		}
+		products[id] = product
+	}
+	return products, nil
+}
+
+// storeProductsTx escribe los productos dentro de la transacción junto con
+// sus movimientos de stock, revisiones y eventos. before son los documentos
+// previos (ver captureDocs).
+func (r *FirestoreRepository) storeProductsTx(tx *gcfirestore.Transaction, products map[string]*models.Product, before map[string]map[string]interface{}, movements []models.StockMovement, change StockChange) error {
+	revisions, err := revisionsFor(products, before, change, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	events, err := eventsFor(revisions, movements)
+	if err != nil {
+		return err
+	}
+	for id, product := range products {
+		data, err := productToData(product)
+		if err != nil {
+			return err
+		}
+		if err := tx.Set(r.client.Collection(ProductsCollection).Doc(id), data); err != nil {
+			return err
+		}
+	}
+	return r.appendHistoryTx(tx, movements, revisions, events)
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+func (r *FirestoreRepository) GetTaxTable(ctx context.Context, projectID string) (*models.TaxTable, error) {
+	snap, err := r.client.Collection(TaxTablesCollection).Doc(projectID).Get(ctx)
+	if snap != nil && !snap.Exists() {
+		return nil, ErrTaxTableNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var table models.TaxTable
+	if err := snap.DataTo(&table); err != nil {
+		return nil, err
+	}
+	return &table, nil
+}
+
+func (r *FirestoreRepository) SaveTaxTable(ctx context.Context, table *models.TaxTable) error {
+	_, err := r.client.Collection(TaxTablesCollection).Doc(table.ProjectID).Set(ctx, table)
+	return err
+}
@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	gcfirestore "cloud.google.com/go/firestore"
+	"github.com/andrescris/products/pkg/models"
+)
+
+func (r *FirestoreRepository) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	_, err := r.client.Collection(WebhooksCollection).Doc(webhook.ID).Create(ctx, webhook)
+	return err
+}
+
+func (r *FirestoreRepository) GetWebhook(ctx context.Context, id string) (*models.Webhook, error) {
+	snap, err := r.client.Collection(WebhooksCollection).Doc(id).Get(ctx)
+	if snap != nil && !snap.Exists() {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var webhook models.Webhook
+	if err := snap.DataTo(&webhook); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (r *FirestoreRepository) ListWebhooks(ctx context.Context, subdomain string) ([]models.Webhook, error) {
+	snaps, err := r.client.Collection(WebhooksCollection).
+		Where("subdomain", "==", subdomain).
+		OrderBy("createdAt", gcfirestore.Asc).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks := make([]models.Webhook, 0, len(snaps))
+	for _, snap := range snaps {
+		var webhook models.Webhook
+		if err := snap.DataTo(&webhook); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, nil
+}
+
+func (r *FirestoreRepository) UpdateWebhook(ctx context.Context, id string, fn func(*models.Webhook) error) (*models.Webhook, error) {
+	ref := r.client.Collection(WebhooksCollection).Doc(id)
+
+	var result *models.Webhook
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *gcfirestore.Transaction) error {
+		snap, err := tx.Get(ref)
+		if snap != nil && !snap.Exists() {
+			return ErrWebhookNotFound
+		}
+		if err != nil {
+			return err
+		}
+		var webhook models.Webhook
+		if err := snap.DataTo(&webhook); err != nil {
+			return err
+		}
+		if err := fn(&webhook); err != nil {
+			return err
+		}
+		webhook.UpdatedAt = time.Now().UTC()
+		result = &webhook
+		return tx.Set(ref, webhook)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *FirestoreRepository) DeleteWebhook(ctx context.Context, id string) error {
+	ref := r.client.Collection(WebhooksCollection).Doc(id)
+	snap, err := ref.Get(ctx)
+	if snap != nil && !snap.Exists() {
+		return ErrWebhookNotFound
+	}
+	if err != nil {
+		return err
+	}
+	_, err = ref.Delete(ctx)
+	return err
+}
+
+func (r *FirestoreRepository) ListPendingEvents(ctx context.Context, limit int) ([]models.ProductEvent, error) {
+	query := r.client.Collection(OutboxCollection).
+		Where("dispatched", "==", false).
+		OrderBy("id", gcfirestore.Asc)
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	return r.queryEvents(ctx, query)
+}
+
+func (r *FirestoreRepository) DispatchEvent(ctx context.Context, eventID string, deliveries []models.WebhookDelivery) error {
+	ref := r.client.Collection(OutboxCollection).Doc(eventID)
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *gcfirestore.Transaction) error {
+		snap, err := tx.Get(ref)
+		if snap != nil && !snap.Exists() {
+			return ErrEventNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if dispatched, _ := snap.Data()["dispatched"].(bool); dispatched {
+			// Otra instancia ya lo repartió.
+			return nil
+		}
+		if err := tx.Update(ref, []gcfirestore.Update{{Path: "dispatched", Value: true}}); err != nil {
+			return err
+		}
+		for _, delivery := range deliveries {
+			if err := tx.Create(r.client.Collection(WebhookDeliveriesCollection).Doc(delivery.ID), delivery); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *FirestoreRepository) ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	query := r.client.Collection(WebhookDeliveriesCollection).
+		Where("status", "==", string(models.DeliveryPending)).
+		Where("nextAttemptAt", "<=", now).
+		OrderBy("nextAttemptAt", gcfirestore.Asc)
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	return r.queryDeliveries(ctx, query)
+}
+
+func (r *FirestoreRepository) ListDeliveries(ctx context.Context, webhookID string, status models.DeliveryStatus, limit, offset int) ([]models.WebhookDelivery, error) {
+	query := r.client.Collection(WebhookDeliveriesCollection).Where("webhookId", "==", webhookID)
+	if status != "" {
+		query = query.Where("status", "==", string(status))
+	}
+	query = query.OrderBy("createdAt", gcfirestore.Desc)
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	return r.queryDeliveries(ctx, query)
+}
+
+func (r *FirestoreRepository) GetDelivery(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	snap, err := r.client.Collection(WebhookDeliveriesCollection).Doc(id).Get(ctx)
+	if snap != nil && !snap.Exists() {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var delivery models.WebhookDelivery
+	if err := snap.DataTo(&delivery); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (r *FirestoreRepository) UpdateDelivery(ctx context.Context, id string, fn func(*models.WebhookDelivery) error) (*models.WebhookDelivery, error) {
+	ref := r.client.Collection(WebhookDeliveriesCollection).Doc(id)
+
+	var result *models.WebhookDelivery
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *gcfirestore.Transaction) error {
+		snap, err := tx.Get(ref)
+		if snap != nil && !snap.Exists() {
+			return ErrDeliveryNotFound
+		}
+		if err != nil {
+			return err
+		}
+		var delivery models.WebhookDelivery
+		if err := snap.DataTo(&delivery); err != nil {
+			return err
+		}
+		if err := fn(&delivery); err != nil {
+			return err
+		}
+		delivery.UpdatedAt = time.Now().UTC()
+		result = &delivery
+		return tx.Set(ref, delivery)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *FirestoreRepository) queryEvents(ctx context.Context, query gcfirestore.Query) ([]models.ProductEvent, error) {
+	snaps, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	events := make([]models.ProductEvent, 0, len(snaps))
+	for _, snap := range snaps {
+		var event models.ProductEvent
+		if err := snap.DataTo(&event); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (r *FirestoreRepository) queryDeliveries(ctx context.Context, query gcfirestore.Query) ([]models.WebhookDelivery, error) {
+	snaps, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	deliveries := make([]models.WebhookDelivery, 0, len(snaps))
+	for _, snap := range snaps {
+		var delivery models.WebhookDelivery
+		if err := snap.DataTo(&delivery); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
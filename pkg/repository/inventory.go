@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+var (
+	// ErrLocationRequired se devuelve al mover stock sin indicar ubicación en
+	// un SKU que tiene stock por ubicación.
+	ErrLocationRequired = errors.New("this SKU is stocked per location; a locationId is required")
+	// ErrSameLocation se devuelve en transferencias con origen igual al destino.
+	ErrSameLocation = errors.New("source and destination locations must differ")
+	// ErrNotPerLocation se devuelve al transferir un SKU que no lleva stock por ubicación.
+	ErrNotPerLocation = errors.New("this SKU is not stocked per location")
+)
+
+// stockSlot da acceso al stock de un SKU (producto simple o variación)
+// manteniendo la regla de que, si hay inventario por ubicación, Stock es su suma.
+type stockSlot struct {
+	stock     *int
+	inventory *map[string]int
+}
+
+func productSlot(product *models.Product) stockSlot {
+	return stockSlot{stock: &product.Stock, inventory: &product.Inventory}
+}
+
+func variationSlot(v *models.Variation) stockSlot {
+	return stockSlot{stock: &v.Stock, inventory: &v.Inventory}
+}
+
+// perLocation indica si el SKU lleva stock por ubicación.
+func (s stockSlot) perLocation() bool {
+	return len(*s.inventory) > 0
+}
+
+// available devuelve el stock de una ubicación, o el total si no se indica
+// ubicación o el SKU no lleva stock por ubicación.
+func (s stockSlot) available(locationID string) int {
+	if locationID == "" || !s.perLocation() {
+		return *s.stock
+	}
+	return (*s.inventory)[locationID]
+}
+
+// add suma qty (puede ser negativo) al total o a una ubicación. La primera vez
+// que un SKU sin inventario por ubicación recibe una ubicación, su stock
+// existente se asigna a esa ubicación para no perderlo.
+func (s stockSlot) add(locationID string, qty int) error {
+	if locationID == "" {
+		if s.perLocation() {
+			return ErrLocationRequired
+		}
+		*s.stock += qty
+		return nil
+	}
+	if *s.inventory == nil {
+		*s.inventory = make(map[string]int)
+	}
+	if len(*s.inventory) == 0 && *s.stock != 0 {
+		(*s.inventory)[locationID] = *s.stock
+	}
+	(*s.inventory)[locationID] += qty
+	s.syncTotal()
+	return nil
+}
+
+// take retira qty de una ubicación concreta o, si locationID está vacío,
+// reparte entre ubicaciones en orden de ID. Devuelve lo tomado de cada
+// ubicación, o ok=false sin modificar nada si no alcanza.
+func (s stockSlot) take(locationID string, qty int) (map[string]int, bool) {
+	if s.available(locationID) < qty {
+		return nil, false
+	}
+	if !s.perLocation() {
+		*s.stock -= qty
+		return nil, true
+	}
+	if locationID != "" {
+		(*s.inventory)[locationID] -= qty
+		s.syncTotal()
+		return map[string]int{locationID: qty}, true
+	}
+
+	allocations := make(map[string]int)
+	remaining := qty
+	for _, id := range sortedLocations(*s.inventory) {
+		if remaining == 0 {
+			break
+		}
+		n := (*s.inventory)[id]
+		if n <= 0 {
+			continue
+		}
+		if n > remaining {
+			n = remaining
+		}
+		(*s.inventory)[id] -= n
+		allocations[id] = n
+		remaining -= n
+	}
+	s.syncTotal()
+	return allocations, true
+}
+
+// restore devuelve lo tomado con take. Sin asignaciones (reserva anterior al
+// stock por ubicación) se devuelve a la primera ubicación del SKU.
+func (s stockSlot) restore(qty int, allocations map[string]int) {
+	if !s.perLocation() && len(allocations) == 0 {
+		*s.stock += qty
+		return
+	}
+	if len(allocations) == 0 {
+		allocations = map[string]int{sortedLocations(*s.inventory)[0]: qty}
+	}
+	if *s.inventory == nil {
+		*s.inventory = make(map[string]int)
+	}
+	for id, n := range allocations {
+		(*s.inventory)[id] += n
+	}
+	s.syncTotal()
+}
+
+// syncTotal recalcula Stock como la suma del inventario por ubicación.
+func (s stockSlot) syncTotal() {
+	if !s.perLocation() {
+		return
+	}
+	total := 0
+	for _, n := range *s.inventory {
+		total += n
+	}
+	*s.stock = total
+}
+
+// syncInventoryTotals aplica syncTotal a todos los SKU del producto.
+func syncInventoryTotals(product *models.Product) {
+	productSlot(product).syncTotal()
+	for i := range product.Variations {
+		variationSlot(&product.Variations[i]).syncTotal()
+	}
+}
+
+func sortedLocations(inventory map[string]int) []string {
+	ids := make([]string, 0, len(inventory))
+	for id := range inventory {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// InventoryLocations devuelve los IDs de ubicación usados por el producto y
+// sus variaciones, para validarlos contra las ubicaciones del proyecto.
+func InventoryLocations(product *models.Product) []string {
+	seen := make(map[string]bool)
+	for id := range product.Inventory {
+		seen[id] = true
+	}
+	for _, v := range product.Variations {
+		for id := range v.Inventory {
+			seen[id] = true
+		}
+	}
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// transferStock mueve las líneas de una ubicación a otra sobre productos ya
+// cargados. Valida todas las líneas antes de devolver los faltantes.
+func transferStock(products map[string]*models.Product, transfer *models.StockTransfer) error {
+	if transfer.FromLocationID == transfer.ToLocationID {
+		return ErrSameLocation
+	}
+
+	shortages := []StockShortage{}
+	for i := range transfer.Lines {
+		line := &transfer.Lines[i]
+		product := products[line.ProductID]
+		if product.Subdomain != transfer.Subdomain {
+			return fmt.Errorf("%w: %s", ErrNotFound, line.ProductID)
+		}
+		target := models.ReservationLine{ProductID: line.ProductID, VariationID: line.VariationID, SKU: line.SKU}
+		slot, err := stockTarget(product, &target)
+		if err != nil {
+			return err
+		}
+		line.VariationID, line.SKU = target.VariationID, target.SKU
+		if !slot.perLocation() {
+			return fmt.Errorf("%w: %s", ErrNotPerLocation, line.SKU)
+		}
+
+		if _, ok := slot.take(transfer.FromLocationID, line.Quantity); !ok {
+			shortages = append(shortages, StockShortage{
+				ProductID:   line.ProductID,
+				VariationID: line.VariationID,
+				SKU:         line.SKU,
+				Requested:   line.Quantity,
+				Available:   slot.available(transfer.FromLocationID),
+			})
+			continue
+		}
+		if err := slot.add(transfer.ToLocationID, line.Quantity); err != nil {
+			return err
+		}
+	}
+	if len(shortages) > 0 {
+		return &InsufficientStockError{Shortages: shortages}
+	}
+	return nil
+}
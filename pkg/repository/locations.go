@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+const (
+	// LocationsCollection es la colección de ubicaciones de inventario.
+	LocationsCollection = "locations"
+	// StockTransfersCollection guarda el historial de transferencias.
+	StockTransfersCollection = "stock_transfers"
+)
+
+// ErrLocationNotFound se devuelve cuando la ubicación no existe.
+var ErrLocationNotFound = errors.New("location not found")
+
+// LocationRepository gestiona las ubicaciones de inventario y las
+// transferencias de stock entre ellas.
+type LocationRepository interface {
+	CreateLocation(ctx context.Context, location *models.Location) error
+	GetLocation(ctx context.Context, id string) (*models.Location, error)
+	// ListLocations devuelve las ubicaciones de un proyecto ordenadas por nombre.
+	ListLocations(ctx context.Context, projectID string) ([]models.Location, error)
+	// UpdateLocation aplica fn sobre la ubicación y la guarda.
+	UpdateLocation(ctx context.Context, id string, fn func(*models.Location) error) (*models.Location, error)
+
+	// TransferStock mueve todas las líneas entre ubicaciones en una sola
+	// transacción y guarda la transferencia. Si alguna línea no tiene stock
+	// suficiente en el origen no se modifica nada.
+	TransferStock(ctx context.Context, transfer *models.StockTransfer) error
+}
@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+func (r *MemoryRepository) RecordAudit(ctx context.Context, entry *models.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.audit = append(r.audit, *entry)
+	return nil
+}
+
+func (r *MemoryRepository) ListAuditEntries(ctx context.Context, filter AuditFilter, limit, offset int) ([]models.AuditEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := []models.AuditEntry{}
+	for i := len(r.audit) - 1; i >= 0; i-- {
+		if filter.matches(r.audit[i]) {
+			entries = append(entries, r.audit[i])
+		}
+	}
+	return paginate(entries, limit, offset), nil
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+// ApplyBulk aplica el lote bajo el lock de escritura y solo publica los
+// documentos si todas las escrituras salen bien.
+func (r *MemoryRepository) ApplyBulk(ctx context.Context, writes []BulkWrite) ([]*models.Product, error) {
+	if err := checkBulkWrites(writes); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	staged := make(map[string]map[string]interface{}, len(writes))
+	history := &bulkHistory{}
+	products := make([]*models.Product, len(writes))
+	for i, w := range writes {
+		product, err := applyBulkWrite(ctx, w, r.docs[w.productID()], history)
+		if err != nil {
+			return nil, &BulkError{Index: i, Err: err}
+		}
+		data, err := productToData(product)
+		if err != nil {
+			return nil, &BulkError{Index: i, Err: err}
+		}
+		staged[product.ID] = data
+		products[i] = product
+	}
+
+	for id, data := range staged {
+		r.docs[id] = data
+	}
+	r.movements = append(r.movements, history.movements...)
+	r.revisions = append(r.revisions, history.revisions...)
+	r.events = append(r.events, history.events...)
+	return products, nil
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+func (r *MemoryRepository) CreateCategory(ctx context.Context, category *models.Category) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.categories[category.ID]; exists {
+		return fmt.Errorf("category %s already exists", category.ID)
+	}
+	if err := r.placeCategoryLocked(category); err != nil {
+		return err
+	}
+	r.categories[category.ID] = *category
+	return nil
+}
+
+func (r *MemoryRepository) GetCategory(ctx context.Context, id string) (*models.Category, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	category, ok := r.categories[id]
+	if !ok {
+		return nil, ErrCategoryNotFound
+	}
+	return &category, nil
+}
+
+func (r *MemoryRepository) ListCategories(ctx context.Context, projectID string) ([]models.Category, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	categories := []models.Category{}
+	for _, category := range r.categories {
+		if category.ProjectID == projectID {
+			categories = append(categories, category)
+		}
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].Path < categories[j].Path
+	})
+	return categories, nil
+}
+
+func (r *MemoryRepository) UpdateCategory(ctx context.Context, id string, fn func(*models.Category) error) ([]models.Category, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	category, ok := r.categories[id]
+	if !ok {
+		return nil, ErrCategoryNotFound
+	}
+	if err := fn(&category); err != nil {
+		return nil, err
+	}
+	if err := r.placeCategoryLocked(&category); err != nil {
+		return nil, err
+	}
+	category.UpdatedAt = time.Now().UTC()
+
+	descendants := []models.Category{}
+	for _, other := range r.categories {
+		for _, ancestor := range other.Ancestors {
+			if ancestor == id {
+				descendants = append(descendants, other)
+				break
+			}
+		}
+	}
+	subtree := rebuildSubtree(category, descendants)
+	for _, c := range subtree {
+		r.categories[c.ID] = c
+	}
+	return subtree, nil
+}
+
+func (r *MemoryRepository) DeleteCategory(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.categories[id]; !ok {
+		return ErrCategoryNotFound
+	}
+	for _, other := range r.categories {
+		if other.ParentID == id {
+			return ErrCategoryHasChildren
+		}
+	}
+	delete(r.categories, id)
+	return nil
+}
+
+// placeCategoryLocked valida el padre y el slug de la categoría y calcula su
+// ruta. Requiere r.mu tomado.
+func (r *MemoryRepository) placeCategoryLocked(category *models.Category) error {
+	var parent *models.Category
+	if category.ParentID != "" {
+		p, ok := r.categories[category.ParentID]
+		if !ok {
+			return ErrCategoryNotFound
+		}
+		parent = &p
+	}
+	if err := checkMove(category, parent); err != nil {
+		return err
+	}
+	placeCategory(category, parent)
+
+	siblings := []models.Category{}
+	for _, other := range r.categories {
+		if other.ProjectID == category.ProjectID && other.ParentID == category.ParentID {
+			siblings = append(siblings, other)
+		}
+	}
+	return checkSlug(category, siblings)
+}
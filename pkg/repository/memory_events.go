@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+func (r *MemoryRepository) ListEvents(ctx context.Context, subdomain, afterID string, limit int) ([]models.ProductEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	events := []models.ProductEvent{}
+	for _, event := range r.events {
+		if event.Subdomain == subdomain && event.ID > afterID {
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return paginate(events, limit, 0), nil
+}
+
+func (r *MemoryRepository) PurgeEvents(ctx context.Context, before time.Time, limit int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.events[:0]
+	purged := 0
+	for _, event := range r.events {
+		if event.Dispatched && event.CreatedAt.Before(before) && (limit <= 0 || purged < limit) {
+			purged++
+			continue
+		}
+		kept = append(kept, event)
+	}
+	r.events = kept
+	return purged, nil
+}
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+func (r *MemoryRepository) GetExchangeRates(ctx context.Context, projectID string) (*models.ExchangeRateTable, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	table, ok := r.exchangeRates[projectID]
+	if !ok {
+		return nil, ErrExchangeRatesNotFound
+	}
+	return &table, nil
+}
+
+func (r *MemoryRepository) SaveExchangeRates(ctx context.Context, table *models.ExchangeRateTable) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.exchangeRates[table.ProjectID] = *table
+	return nil
+}
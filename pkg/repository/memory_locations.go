@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+func (r *MemoryRepository) CreateLocation(ctx context.Context, location *models.Location) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.locations[location.ID]; exists {
+		return fmt.Errorf("location %s already exists", location.ID)
+	}
+	r.locations[location.ID] = *location
+	return nil
+}
+
+func (r *MemoryRepository) GetLocation(ctx context.Context, id string) (*models.Location, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	location, ok := r.locations[id]
+	if !ok {
+		return nil, ErrLocationNotFound
+	}
+	return &location, nil
+}
+
+func (r *MemoryRepository) ListLocations(ctx context.Context, projectID string) ([]models.Location, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	locations := []models.Location{}
+	for _, location := range r.locations {
+		if location.ProjectID == projectID {
+			locations = append(locations, location)
+		}
+	}
+	sort.Slice(locations, func(i, j int) bool {
+		return locations[i].Name < locations[j].Name
+	})
+	return locations, nil
+}
+
+func (r *MemoryRepository) UpdateLocation(ctx context.Context, id string, fn func(*models.Location) error) (*models.Location, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	location, ok := r.locations[id]
+	if !ok {
+		return nil, ErrLocationNotFound
+	}
+	if err := fn(&location); err != nil {
+		return nil, err
+	}
+	location.UpdatedAt = time.Now().UTC()
+	r.locations[id] = location
+	return &location, nil
+}
+
+func (r *MemoryRepository) TransferStock(ctx context.Context, transfer *models.StockTransfer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make([]string, len(transfer.Lines))
+	for i, line := range transfer.Lines {
+		ids[i] = line.ProductID
+	}
+	products, err := r.loadProductsLocked(uniqueStrings(ids))
+	if err != nil {
+		return err
+	}
+	if err := transferStock(products, transfer); err != nil {
+		return err
+	}
+	touchProducts(products)
+	if err := r.storeProductsLocked(products, nil, stockChangeFrom(ctx, models.MovementAdjustment)); err != nil {
+		return err
+	}
+	r.transfers = append(r.transfers, *transfer)
+	return nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+func (r *MemoryRepository) CreatePriceList(ctx context.Context, list *models.PriceList) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.priceLists[list.ID]; exists {
+		return fmt.Errorf("price list %s already exists", list.ID)
+	}
+	r.priceLists[list.ID] = *list
+	return nil
+}
+
+func (r *MemoryRepository) GetPriceList(ctx context.Context, id string) (*models.PriceList, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list, ok := r.priceLists[id]
+	if !ok {
+		return nil, ErrPriceListNotFound
+	}
+	return &list, nil
+}
+
+func (r *MemoryRepository) ListPriceLists(ctx context.Context, projectID string) ([]models.PriceList, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	lists := []models.PriceList{}
+	for _, list := range r.priceLists {
+		if list.ProjectID == projectID {
+			lists = append(lists, list)
+		}
+	}
+	sort.Slice(lists, func(i, j int) bool {
+		return lists[i].Name < lists[j].Name
+	})
+	return lists, nil
+}
+
+func (r *MemoryRepository) FindPriceList(ctx context.Context, subdomain, customerGroup string) (*models.PriceList, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, list := range r.priceLists {
+		if list.Active && list.Subdomain == subdomain && list.CustomerGroup == customerGroup {
+			return &list, nil
+		}
+	}
+	return nil, ErrPriceListNotFound
+}
+
+func (r *MemoryRepository) UpdatePriceList(ctx context.Context, id string, fn func(*models.PriceList) error) (*models.PriceList, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list, ok := r.priceLists[id]
+	if !ok {
+		return nil, ErrPriceListNotFound
+	}
+	if err := fn(&list); err != nil {
+		return nil, err
+	}
+	list.UpdatedAt = time.Now().UTC()
+	r.priceLists[id] = list
+	return &list, nil
+}
@@ -0,0 +1,271 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/andrescris/firestore/lib/firebase"
+	"github.com/andrescris/products/pkg/models"
+)
+
+// MemoryRepository implementa ProductRepository en memoria. Guarda los documentos
+// con la misma forma que Firestore (mapas con los nombres JSON) para que los
+// filtros de QueryOptions se comporten igual que en producción.
+type MemoryRepository struct {
+	mu           sync.RWMutex
+	docs         map[string]map[string]interface{}
+	reservations map[string]models.Reservation
+	movements    []models.StockMovement
+	revisions    []models.ProductRevision
+	events       []models.ProductEvent
+	locations    map[string]models.Location
+	transfers    []models.StockTransfer
+	priceLists   map[string]models.PriceList
+	// exchangeRates y taxTables usan project_id como clave.
+	exchangeRates map[string]models.ExchangeRateTable
+	taxTables     map[string]models.TaxTable
+	categories    map[string]models.Category
+	audit         []models.AuditEntry
+	webhooks      map[string]models.Webhook
+	deliveries    map[string]models.WebhookDelivery
+}
+
+// NewMemoryRepository crea un repositorio vacío.
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		docs:          make(map[string]map[string]interface{}),
+		reservations:  make(map[string]models.Reservation),
+		locations:     make(map[string]models.Location),
+		priceLists:    make(map[string]models.PriceList),
+		exchangeRates: make(map[string]models.ExchangeRateTable),
+		taxTables:     make(map[string]models.TaxTable),
+		categories:    make(map[string]models.Category),
+		webhooks:      make(map[string]models.Webhook),
+		deliveries:    make(map[string]models.WebhookDelivery),
+	}
+}
+
+func (r *MemoryRepository) Get(ctx context.Context, id string) (*models.Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	data, ok := r.docs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return productFromData(data)
+}
+
+func (r *MemoryRepository) Create(ctx context.Context, product *models.Product) error {
+	if product.Version == 0 {
+		product.Version = 1
+	}
+	DeriveFields(product)
+	data, err := productToData(product)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	change := stockChangeFrom(ctx, models.MovementInitial)
+	movements := stockMovements(product, nil, change, now)
+	revision, err := newRevision(product, nil, change, now)
+	if err != nil {
+		return err
+	}
+	events, err := productEvents(revision, movements, true)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.docs[product.ID]; exists {
+		return fmt.Errorf("product %s already exists", product.ID)
+	}
+	r.docs[product.ID] = data
+
+	r.movements = append(r.movements, movements...)
+	r.revisions = append(r.revisions, revision)
+	r.events = append(r.events, events...)
+	return nil
+}
+
+func (r *MemoryRepository) Query(ctx context.Context, options firebase.QueryOptions) ([]models.Product, error) {
+	r.mu.RLock()
+	docs := make([]map[string]interface{}, 0, len(r.docs))
+	for _, data := range r.docs {
+		docs = append(docs, data)
+	}
+	r.mu.RUnlock()
+
+	matched, err := applyQuery(docs, options)
+	if err != nil {
+		return nil, err
+	}
+
+	products := make([]models.Product, 0, len(matched))
+	for _, data := range matched {
+		product, err := productFromData(data)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, *product)
+	}
+	return products, nil
+}
+
+// Mutate aplica fn bajo el lock de escritura, que hace las veces de transacción.
+func (r *MemoryRepository) Mutate(ctx context.Context, id string, expectedVersion int64, fn func(*models.Product) error) (*models.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, ok := r.docs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	product, err := productFromData(data)
+	if err != nil {
+		return nil, err
+	}
+	before := stockLevels(product)
+	if err := applyMutation(product, expectedVersion, fn); err != nil {
+		return nil, err
+	}
+
+	updated, err := productToData(product)
+	if err != nil {
+		return nil, err
+	}
+	change := stockChangeFrom(ctx, models.MovementAdjustment)
+	movements := stockMovements(product, before, change, product.UpdatedAt)
+	revision, err := newRevision(product, data, change, product.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	events, err := productEvents(revision, movements, false)
+	if err != nil {
+		return nil, err
+	}
+	r.docs[id] = updated
+
+	r.movements = append(r.movements, movements...)
+	r.revisions = append(r.revisions, revision)
+	r.events = append(r.events, events...)
+	return product, nil
+}
+
+func (r *MemoryRepository) Update(ctx context.Context, id string, expectedVersion int64, updates map[string]interface{}) (*models.Product, error) {
+	return r.Mutate(ctx, id, expectedVersion, mergeUpdates(updates))
+}
+
+func (r *MemoryRepository) Deactivate(ctx context.Context, id string, expectedVersion int64) (*models.Product, error) {
+	return r.Mutate(ctx, id, expectedVersion, deactivate(ctx))
+}
+
+func (r *MemoryRepository) AddVariation(ctx context.Context, productID string, expectedVersion int64, variation models.Variation) (*models.Product, error) {
+	return r.Mutate(ctx, productID, expectedVersion, addVariation(variation))
+}
+
+func (r *MemoryRepository) UpdateVariation(ctx context.Context, productID, variationID string, expectedVersion int64, fn func(*models.Variation) error) (*models.Product, error) {
+	return r.Mutate(ctx, productID, expectedVersion, updateVariation(variationID, fn))
+}
+
+func (r *MemoryRepository) DeactivateVariation(ctx context.Context, productID, variationID string, expectedVersion int64) (*models.Product, error) {
+	return r.Mutate(ctx, productID, expectedVersion, updateVariation(variationID, deactivateVariation(ctx)))
+}
+
+func (r *MemoryRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.docs[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.docs, id)
+
+	// Como en Firestore, también se borra todo lo que copia datos del producto.
+	revisions := r.revisions[:0]
+	for _, revision := range r.revisions {
+		if revision.ProductID != id {
+			revisions = append(revisions, revision)
+		}
+	}
+	r.revisions = revisions
+	movements := r.movements[:0]
+	for _, movement := range r.movements {
+		if movement.ProductID != id {
+			movements = append(movements, movement)
+		}
+	}
+	r.movements = movements
+	events := r.events[:0]
+	for _, event := range r.events {
+		if event.ProductID != id {
+			events = append(events, event)
+		}
+	}
+	r.events = events
+	for deliveryID, delivery := range r.deliveries {
+		if delivery.Event.ProductID == id {
+			delete(r.deliveries, deliveryID)
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) ListStockMovements(ctx context.Context, productID string, limit, offset int) ([]models.StockMovement, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	movements := []models.StockMovement{}
+	for _, m := range r.movements {
+		if m.ProductID == productID {
+			movements = append(movements, m)
+		}
+	}
+	sortMovementsNewestFirst(movements)
+	return paginate(movements, limit, offset), nil
+}
+
+func (r *MemoryRepository) ListRevisions(ctx context.Context, productID string, limit, offset int) ([]models.ProductRevision, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	revisions := []models.ProductRevision{}
+	for i := len(r.revisions) - 1; i >= 0; i-- {
+		if revision := r.revisions[i]; revision.ProductID == productID {
+			revision.Snapshot = nil
+			revisions = append(revisions, revision)
+		}
+	}
+	return paginate(revisions, limit, offset), nil
+}
+
+func (r *MemoryRepository) GetRevision(ctx context.Context, productID string, revision int64) (*models.ProductRevision, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, stored := range r.revisions {
+		if stored.ProductID == productID && stored.Revision == revision {
+			return &stored, nil
+		}
+	}
+	return nil, ErrRevisionNotFound
+}
+
+// paginate aplica offset y limit (limit <= 0 significa sin límite).
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return []T{}
+	}
+	if offset > 0 {
+		items = items[offset:]
+	}
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+func (r *MemoryRepository) Reserve(ctx context.Context, reservation *models.Reservation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	products, err := expandBundleLines(reservation, r.loadProductsLocked)
+	if err != nil {
+		return err
+	}
+	before := captureStock(products)
+	if err := reserveStock(products, reservation); err != nil {
+		return err
+	}
+	touchProducts(products)
+	change := reservationStockChange(ctx, models.MovementReservation, reservation.ID)
+	movements := movementsFor(products, before, change, reservation.CreatedAt)
+	if err := r.storeProductsLocked(products, movements, change); err != nil {
+		return err
+	}
+	r.reservations[reservation.ID] = *reservation
+	return nil
+}
+
+func (r *MemoryRepository) GetReservation(ctx context.Context, id string) (*models.Reservation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	reservation, ok := r.reservations[id]
+	if !ok {
+		return nil, ErrReservationNotFound
+	}
+	return &reservation, nil
+}
+
+func (r *MemoryRepository) CommitReservation(ctx context.Context, id string, now time.Time) (*models.Reservation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reservation, ok := r.reservations[id]
+	if !ok {
+		return nil, ErrReservationNotFound
+	}
+	if reservation.Status == models.ReservationActive && now.After(reservation.ExpiresAt) {
+		return nil, ErrReservationExpired
+	}
+	if err := closeReservation(&reservation, models.ReservationCommitted, now); err != nil {
+		return nil, err
+	}
+	r.reservations[id] = reservation
+	return &reservation, nil
+}
+
+func (r *MemoryRepository) ReleaseReservation(ctx context.Context, id string) (*models.Reservation, error) {
+	change := reservationStockChange(ctx, models.MovementReservationRelease, id)
+	return r.returnReservation(id, models.ReservationReleased, change, time.Now().UTC(), false)
+}
+
+func (r *MemoryRepository) ExpireReservation(ctx context.Context, id string, now time.Time) (*models.Reservation, error) {
+	change := reservationStockChange(ctx, models.MovementReservationExpiry, id)
+	return r.returnReservation(id, models.ReservationExpired, change, now, true)
+}
+
+func (r *MemoryRepository) ListExpiredReservations(ctx context.Context, now time.Time, limit int) ([]models.Reservation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	expired := []models.Reservation{}
+	for _, reservation := range r.reservations {
+		if reservation.Status == models.ReservationActive && !reservation.ExpiresAt.After(now) {
+			expired = append(expired, reservation)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool {
+		return expired[i].ExpiresAt.Before(expired[j].ExpiresAt)
+	})
+	if limit > 0 && len(expired) > limit {
+		expired = expired[:limit]
+	}
+	return expired, nil
+}
+
+// returnReservation cierra la reserva con el estado indicado y devuelve su stock.
+// Con onlyIfExpired, una reserva que no está activa o no ha vencido se deja igual.
+func (r *MemoryRepository) returnReservation(id string, status models.ReservationStatus, change StockChange, now time.Time, onlyIfExpired bool) (*models.Reservation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reservation, ok := r.reservations[id]
+	if !ok {
+		return nil, ErrReservationNotFound
+	}
+	if onlyIfExpired && (reservation.Status != models.ReservationActive || reservation.ExpiresAt.After(now)) {
+		return &reservation, nil
+	}
+	if err := closeReservation(&reservation, status, now); err != nil {
+		return nil, err
+	}
+
+	products := r.existingProductsLocked(reservationProductIDs(reservation.Lines))
+	before := captureStock(products)
+	restoreStock(products, &reservation)
+	touchProducts(products)
+	if err := r.storeProductsLocked(products, movementsFor(products, before, change, now), change); err != nil {
+		return nil, err
+	}
+	r.reservations[id] = reservation
+	return &reservation, nil
+}
+
+// loadProductsLocked carga los productos indicados. Requiere r.mu tomado.
+func (r *MemoryRepository) loadProductsLocked(ids []string) (map[string]*models.Product, error) {
+	products := make(map[string]*models.Product, len(ids))
+	for _, id := range ids {
+		data, ok := r.docs[id]
+		if !ok {
+			return nil, ErrNotFound
+		}
+		product, err := productFromData(data)
+		if err != nil {
+			return nil, err
+		}
+		products[id] = product
+	}
+	return products, nil
+}
+
+// existingProductsLocked es como loadProductsLocked pero ignora los que no existen.
+func (r *MemoryRepository) existingProductsLocked(ids []string) map[string]*models.Product {
+	products := make(map[string]*models.Product, len(ids))
+	for _, id := range ids {
+		if data, ok := r.docs[id]; ok {
+			if product, err := productFromData(data); err == nil {
+				products[id] = product
+			}
+		}
+	}
+	return products
+}
+
+// storeProductsLocked guarda los productos junto con sus movimientos de
+// stock, revisiones y eventos. Requiere r.mu tomado.
+func (r *MemoryRepository) storeProductsLocked(products map[string]*models.Product, movements []models.StockMovement, change StockChange) error {
+	revisions, err := revisionsFor(products, r.docs, change, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	events, err := eventsFor(revisions, movements)
+	if err != nil {
+		return err
+	}
+	for id, product := range products {
+		data, err := productToData(product)
+		if err != nil {
+			return err
+		}
+		r.docs[id] = data
+	}
+	r.movements = append(r.movements, movements...)
+	r.revisions = append(r.revisions, revisions...)
+	r.events = append(r.events, events...)
+	return nil
+}
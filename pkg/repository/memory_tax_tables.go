@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+func (r *MemoryRepository) GetTaxTable(ctx context.Context, projectID string) (*models.TaxTable, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	table, ok := r.taxTables[projectID]
+	if !ok {
+		return nil, ErrTaxTableNotFound
+	}
+	return &table, nil
+}
+
+func (r *MemoryRepository) SaveTaxTable(ctx context.Context, table *models.TaxTable) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.taxTables[table.ProjectID] = *table
+	return nil
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+func (r *MemoryRepository) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.webhooks[webhook.ID] = *webhook
+	return nil
+}
+
+func (r *MemoryRepository) GetWebhook(ctx context.Context, id string) (*models.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	webhook, ok := r.webhooks[id]
+	if !ok {
+		return nil, ErrWebhookNotFound
+	}
+	return &webhook, nil
+}
+
+func (r *MemoryRepository) ListWebhooks(ctx context.Context, subdomain string) ([]models.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	webhooks := []models.Webhook{}
+	for _, webhook := range r.webhooks {
+		if webhook.Subdomain == subdomain {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt)
+	})
+	return webhooks, nil
+}
+
+func (r *MemoryRepository) UpdateWebhook(ctx context.Context, id string, fn func(*models.Webhook) error) (*models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	webhook, ok := r.webhooks[id]
+	if !ok {
+		return nil, ErrWebhookNotFound
+	}
+	if err := fn(&webhook); err != nil {
+		return nil, err
+	}
+	webhook.UpdatedAt = time.Now().UTC()
+	r.webhooks[id] = webhook
+	return &webhook, nil
+}
+
+func (r *MemoryRepository) DeleteWebhook(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.webhooks[id]; !ok {
+		return ErrWebhookNotFound
+	}
+	delete(r.webhooks, id)
+	return nil
+}
+
+func (r *MemoryRepository) ListPendingEvents(ctx context.Context, limit int) ([]models.ProductEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	pending := []models.ProductEvent{}
+	for _, event := range r.events {
+		if !event.Dispatched {
+			pending = append(pending, event)
+		}
+	}
+	return paginate(pending, limit, 0), nil
+}
+
+func (r *MemoryRepository) DispatchEvent(ctx context.Context, eventID string, deliveries []models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.events {
+		if r.events[i].ID == eventID {
+			r.events[i].Dispatched = true
+			for _, delivery := range deliveries {
+				r.deliveries[delivery.ID] = delivery
+			}
+			return nil
+		}
+	}
+	return ErrEventNotFound
+}
+
+func (r *MemoryRepository) ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	due := []models.WebhookDelivery{}
+	for _, delivery := range r.deliveries {
+		if delivery.Status == models.DeliveryPending && !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+	})
+	return paginate(due, limit, 0), nil
+}
+
+func (r *MemoryRepository) ListDeliveries(ctx context.Context, webhookID string, status models.DeliveryStatus, limit, offset int) ([]models.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	deliveries := []models.WebhookDelivery{}
+	for _, delivery := range r.deliveries {
+		if delivery.WebhookID == webhookID && (status == "" || delivery.Status == status) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+	})
+	return paginate(deliveries, limit, offset), nil
+}
+
+func (r *MemoryRepository) GetDelivery(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	delivery, ok := r.deliveries[id]
+	if !ok {
+		return nil, ErrDeliveryNotFound
+	}
+	return &delivery, nil
+}
+
+func (r *MemoryRepository) UpdateDelivery(ctx context.Context, id string, fn func(*models.WebhookDelivery) error) (*models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delivery, ok := r.deliveries[id]
+	if !ok {
+		return nil, ErrDeliveryNotFound
+	}
+	if err := fn(&delivery); err != nil {
+		return nil, err
+	}
+	delivery.UpdatedAt = time.Now().UTC()
+	r.deliveries[id] = delivery
+	return &delivery, nil
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/pricing"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNoOptions se devuelve al generar variaciones de un producto sin opciones.
+	ErrNoOptions = errors.New("product has no options to generate variations from")
+	// ErrInvalidSKUPattern se devuelve cuando la plantilla de SKU usa una
+	// opción que el producto no declara o no distingue las combinaciones.
+	ErrInvalidSKUPattern = errors.New("invalid sku pattern")
+)
+
+// VariationTemplate describe las variaciones a generar: la plantilla de SKU
+// ("CAM-{talla}-{color}", con {sku} para el SKU del producto) y el precio y
+// stock iniciales de cada una.
+type VariationTemplate struct {
+	SKUPattern string       `json:"skuPattern"`
+	Price      models.Money `json:"price"`
+	Stock      int          `json:"stock"`
+}
+
+var skuPlaceholder = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// DefaultSKUPattern es la plantilla que se usa si no se indica otra:
+// "{sku}-{opción1}-{opción2}..." (sin {sku} si el producto no tiene SKU).
+func DefaultSKUPattern(product *models.Product) string {
+	parts := []string{}
+	if product.SKU != "" {
+		parts = append(parts, "{sku}")
+	}
+	for _, option := range product.Options {
+		parts = append(parts, "{"+option.Name+"}")
+	}
+	return strings.Join(parts, "-")
+}
+
+// renderSKU sustituye cada {opción} por su valor (en mayúsculas y con guiones
+// en lugar de espacios) y {sku} por el SKU del producto.
+func renderSKU(pattern, productSKU string, attributes map[string]string) (string, error) {
+	var missing error
+	sku := skuPlaceholder.ReplaceAllStringFunc(pattern, func(match string) string {
+		name := match[1 : len(match)-1]
+		if name == "sku" {
+			if productSKU == "" {
+				missing = fmt.Errorf("%w: {sku} requires the product to have a sku", ErrInvalidSKUPattern)
+			}
+			return productSKU
+		}
+		value, ok := attributes[name]
+		if !ok {
+			missing = fmt.Errorf("%w: unknown option {%s}", ErrInvalidSKUPattern, name)
+			return match
+		}
+		return strings.ToUpper(strings.Join(strings.Fields(value), "-"))
+	})
+	return sku, missing
+}
+
+// PlanVariations devuelve las variaciones que faltan para completar la matriz
+// de opciones del producto. Una combinación ya existe si alguna variación
+// tiene los mismos valores para todas las opciones.
+func PlanVariations(product *models.Product, template VariationTemplate) ([]models.Variation, error) {
+	if len(product.Options) == 0 {
+		return nil, ErrNoOptions
+	}
+	pattern := template.SKUPattern
+	if pattern == "" {
+		pattern = DefaultSKUPattern(product)
+	}
+
+	skus := map[string]bool{}
+	for _, v := range product.Variations {
+		skus[v.SKU] = true
+	}
+
+	planned := []models.Variation{}
+	for _, attributes := range models.OptionCombinations(product.Options) {
+		if hasCombination(product, attributes) {
+			continue
+		}
+		sku, err := renderSKU(pattern, product.SKU, attributes)
+		if err != nil {
+			return nil, err
+		}
+		if skus[sku] {
+			return nil, fmt.Errorf("%w: it produces the SKU %s more than once or for an existing variation", ErrInvalidSKUPattern, sku)
+		}
+		skus[sku] = true
+		planned = append(planned, models.Variation{
+			SKU:        sku,
+			Price:      template.Price,
+			Stock:      template.Stock,
+			Attributes: attributes,
+			Active:     true,
+			SalePricing: models.SalePricing{
+				EffectivePrice: template.Price,
+			},
+		})
+	}
+	return planned, nil
+}
+
+func hasCombination(product *models.Product, attributes map[string]string) bool {
+	for _, v := range product.Variations {
+		matches := true
+		for name, value := range attributes {
+			if v.Attributes[name] != value {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateVariations crea en una sola escritura las variaciones que faltan
+// según PlanVariations, recalculadas con los datos más recientes del
+// producto. Devuelve el producto guardado y las variaciones creadas. Los
+// bundles no pueden tener variaciones: devuelve ErrBundleStock.
+func GenerateVariations(ctx context.Context, repo ProductRepository, productID string, expectedVersion int64, template VariationTemplate) (*models.Product, []models.Variation, error) {
+	var created []models.Variation
+	product, err := repo.Mutate(ctx, productID, expectedVersion, func(product *models.Product) error {
+		if product.IsBundle() {
+			return ErrBundleStock
+		}
+		planned, err := PlanVariations(product, template)
+		if err != nil {
+			return err
+		}
+		for i := range planned {
+			planned[i].ID = "var-" + uuid.New().String()
+			if err := pricing.ValidateVariation(&planned[i]); err != nil {
+				return err
+			}
+		}
+		product.Variations = append(product.Variations, planned...)
+		created = planned
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return product, created, nil
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+// PriceListsCollection es la colección de listas de precios por grupo de clientes.
+const PriceListsCollection = "price_lists"
+
+// ErrPriceListNotFound se devuelve cuando la lista de precios no existe.
+var ErrPriceListNotFound = errors.New("price list not found")
+
+// PriceListRepository gestiona las listas de precios.
+type PriceListRepository interface {
+	CreatePriceList(ctx context.Context, list *models.PriceList) error
+	GetPriceList(ctx context.Context, id string) (*models.PriceList, error)
+	// ListPriceLists devuelve las listas de un proyecto ordenadas por nombre.
+	ListPriceLists(ctx context.Context, projectID string) ([]models.PriceList, error)
+	// FindPriceList devuelve la lista activa de un grupo de clientes en el
+	// subdominio, o ErrPriceListNotFound si no hay ninguna.
+	FindPriceList(ctx context.Context, subdomain, customerGroup string) (*models.PriceList, error)
+	// UpdatePriceList aplica fn sobre la lista y la guarda.
+	UpdatePriceList(ctx context.Context, id string, fn func(*models.PriceList) error) (*models.PriceList, error)
+}
@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/andrescris/firestore/lib/firebase"
+)
+
+// applyQuery filtra, ordena y pagina documentos en memoria siguiendo la misma
+// semántica que Firestore para los operadores soportados.
+func applyQuery(docs []map[string]interface{}, options firebase.QueryOptions) ([]map[string]interface{}, error) {
+	filters := make([]firebase.QueryFilter, len(options.Filters))
+	for i, f := range options.Filters {
+		f.Value = normalizeValue(f.Value)
+		filters[i] = f
+	}
+
+	matched := []map[string]interface{}{}
+	for _, doc := range docs {
+		ok, err := matchesFilters(doc, filters)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, doc)
+		}
+	}
+
+	// Orden estable por ID para que los resultados sean deterministas, y
+	// luego por el campo pedido. Igual que Firestore, ordenar por un campo
+	// excluye los documentos que no lo tienen.
+	sort.SliceStable(matched, func(i, j int) bool {
+		return compareValues(matched[i]["id"], matched[j]["id"]) < 0
+	})
+	if options.OrderBy != "" {
+		withField := matched[:0]
+		for _, doc := range matched {
+			if _, ok := lookupField(doc, options.OrderBy); ok {
+				withField = append(withField, doc)
+			}
+		}
+		matched = withField
+
+		desc := strings.EqualFold(options.OrderDir, "desc")
+		sort.SliceStable(matched, func(i, j int) bool {
+			a, _ := lookupField(matched[i], options.OrderBy)
+			b, _ := lookupField(matched[j], options.OrderBy)
+			if desc {
+				return compareValues(a, b) > 0
+			}
+			return compareValues(a, b) < 0
+		})
+	}
+
+	if options.Offset > 0 {
+		if options.Offset >= len(matched) {
+			return []map[string]interface{}{}, nil
+		}
+		matched = matched[options.Offset:]
+	}
+	if options.Limit > 0 && options.Limit < len(matched) {
+		matched = matched[:options.Limit]
+	}
+	return matched, nil
+}
+
+func matchesFilters(doc map[string]interface{}, filters []firebase.QueryFilter) (bool, error) {
+	for _, f := range filters {
+		value, exists := lookupField(doc, f.Field)
+		ok, err := matchFilter(value, exists, f)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func matchFilter(value interface{}, exists bool, f firebase.QueryFilter) (bool, error) {
+	switch f.Operator {
+	case "==":
+		return exists && valuesEqual(value, f.Value), nil
+	case "!=":
+		return exists && value != nil && !valuesEqual(value, f.Value), nil
+	case "<", "<=", ">", ">=":
+		if !exists || !sameType(value, f.Value) {
+			return false, nil
+		}
+		cmp := compareValues(value, f.Value)
+		switch f.Operator {
+		case "<":
+			return cmp < 0, nil
+		case "<=":
+			return cmp <= 0, nil
+		case ">":
+			return cmp > 0, nil
+		default:
+			return cmp >= 0, nil
+		}
+	case "in", "not-in":
+		candidates, ok := f.Value.([]interface{})
+		if !ok {
+			return false, fmt.Errorf("operator %q requires an array value", f.Operator)
+		}
+		found := false
+		for _, c := range candidates {
+			if valuesEqual(value, c) {
+				found = true
+				break
+			}
+		}
+		if f.Operator == "in" {
+			return exists && found, nil
+		}
+		return exists && value != nil && !found, nil
+	case "array-contains":
+		items, ok := value.([]interface{})
+		if !exists || !ok {
+			return false, nil
+		}
+		for _, item := range items {
+			if valuesEqual(item, f.Value) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "array-contains-any":
+		candidates, ok := f.Value.([]interface{})
+		if !ok {
+			return false, fmt.Errorf("operator %q requires an array value", f.Operator)
+		}
+		items, ok := value.([]interface{})
+		if !exists || !ok {
+			return false, nil
+		}
+		for _, item := range items {
+			for _, c := range candidates {
+				if valuesEqual(item, c) {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	default:
+		return false, fmt.Errorf("unsupported query operator %q", f.Operator)
+	}
+}
+
+// lookupField resuelve rutas con punto ("dimensions.width") dentro del documento.
+func lookupField(doc map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = doc
+	for _, part := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = m[part]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// normalizeValue convierte los números a float64, que es como quedan los
+// documentos después de pasar por JSON.
+func normalizeValue(v interface{}) interface{} {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case float32:
+		return float64(n)
+	case []string:
+		out := make([]interface{}, len(n))
+		for i, s := range n {
+			out[i] = s
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(n))
+		for i, item := range n {
+			out[i] = normalizeValue(item)
+		}
+		return out
+	}
+	return v
+}
+
+func valuesEqual(a, b interface{}) bool {
+	return reflect.DeepEqual(normalizeValue(a), normalizeValue(b))
+}
+
+func sameType(a, b interface{}) bool {
+	return typeRank(normalizeValue(a)) == typeRank(normalizeValue(b))
+}
+
+// typeRank sigue el orden de tipos de Firestore: null < bool < número < string.
+func typeRank(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case float64:
+		return 2
+	case string:
+		return 3
+	case []interface{}:
+		return 4
+	case map[string]interface{}:
+		return 5
+	}
+	return 6
+}
+
+func compareValues(a, b interface{}) int {
+	a, b = normalizeValue(a), normalizeValue(b)
+	ra, rb := typeRank(a), typeRank(b)
+	if ra != rb {
+		return ra - rb
+	}
+	switch x := a.(type) {
+	case bool:
+		y := b.(bool)
+		if x == y {
+			return 0
+		}
+		if !x {
+			return -1
+		}
+		return 1
+	case float64:
+		y := b.(float64)
+		if x < y {
+			return -1
+		}
+		if x > y {
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(x, b.(string))
+	}
+	return 0
+}
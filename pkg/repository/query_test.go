@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/andrescris/firestore/lib/firebase"
+)
+
+func queryDocs() []map[string]interface{} {
+	return []map[string]interface{}{
+		{"id": "c", "name": "Cap", "price": 15.0, "active": true, "tags": []interface{}{"summer", "hat"}, "dimensions": map[string]interface{}{"width": 20.0}},
+		{"id": "a", "name": "Tee", "price": 10.0, "active": true, "tags": []interface{}{"summer"}},
+		{"id": "d", "name": "Coat", "price": 90.0, "active": false, "tags": []interface{}{"winter"}, "dimensions": map[string]interface{}{"width": 60.0}},
+		{"id": "b", "name": "Sock", "price": 3.0, "active": true},
+	}
+}
+
+func ids(docs []map[string]interface{}) []string {
+	out := []string{}
+	for _, doc := range docs {
+		out = append(out, doc["id"].(string))
+	}
+	return out
+}
+
+func TestApplyQuery(t *testing.T) {
+	filter := func(field, op string, value interface{}) firebase.QueryFilter {
+		return firebase.QueryFilter{Field: field, Operator: op, Value: value}
+	}
+	tests := []struct {
+		name    string
+		options firebase.QueryOptions
+		want    []string
+	}{
+		{"no options sorts by id", firebase.QueryOptions{}, []string{"a", "b", "c", "d"}},
+		{"equality", firebase.QueryOptions{Filters: []firebase.QueryFilter{filter("active", "==", true)}}, []string{"a", "b", "c"}},
+		{"not equal skips missing", firebase.QueryOptions{Filters: []firebase.QueryFilter{filter("dimensions.width", "!=", 20)}}, []string{"d"}},
+		{"int matches float", firebase.QueryOptions{Filters: []firebase.QueryFilter{filter("price", "==", 10)}}, []string{"a"}},
+		{"range", firebase.QueryOptions{Filters: []firebase.QueryFilter{filter("price", ">=", 10), filter("price", "<", 90)}}, []string{"a", "c"}},
+		{"range ignores other types", firebase.QueryOptions{Filters: []firebase.QueryFilter{filter("name", ">", 1)}}, []string{}},
+		{"string range", firebase.QueryOptions{Filters: []firebase.QueryFilter{filter("id", ">", "b")}}, []string{"c", "d"}},
+		{"in", firebase.QueryOptions{Filters: []firebase.QueryFilter{filter("name", "in", []interface{}{"Tee", "Coat"})}}, []string{"a", "d"}},
+		{"not-in", firebase.QueryOptions{Filters: []firebase.QueryFilter{filter("name", "not-in", []interface{}{"Tee", "Coat"})}}, []string{"b", "c"}},
+		{"array-contains", firebase.QueryOptions{Filters: []firebase.QueryFilter{filter("tags", "array-contains", "summer")}}, []string{"a", "c"}},
+		{"array-contains-any", firebase.QueryOptions{Filters: []firebase.QueryFilter{filter("tags", "array-contains-any", []string{"hat", "winter"})}}, []string{"c", "d"}},
+		{"nested field", firebase.QueryOptions{Filters: []firebase.QueryFilter{filter("dimensions.width", ">", 30)}}, []string{"d"}},
+		{"order asc", firebase.QueryOptions{OrderBy: "price"}, []string{"b", "a", "c", "d"}},
+		{"order desc", firebase.QueryOptions{OrderBy: "price", OrderDir: "desc"}, []string{"d", "c", "a", "b"}},
+		{"order drops docs without field", firebase.QueryOptions{OrderBy: "dimensions.width"}, []string{"c", "d"}},
+		{"limit", firebase.QueryOptions{OrderBy: "price", Limit: 2}, []string{"b", "a"}},
+		{"offset", firebase.QueryOptions{OrderBy: "price", Offset: 1, Limit: 2}, []string{"a", "c"}},
+		{"offset past end", firebase.QueryOptions{Offset: 10}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyQuery(queryDocs(), tt.options)
+			if err != nil {
+				t.Fatalf("applyQuery: %v", err)
+			}
+			if !reflect.DeepEqual(ids(got), tt.want) {
+				t.Fatalf("applyQuery = %v, want %v", ids(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyQueryErrors(t *testing.T) {
+	tests := []firebase.QueryFilter{
+		{Field: "price", Operator: "~=", Value: 1},
+		{Field: "name", Operator: "in", Value: "Tee"},
+		{Field: "tags", Operator: "array-contains-any", Value: "hat"},
+	}
+	for _, f := range tests {
+		if _, err := applyQuery(queryDocs(), firebase.QueryOptions{Filters: []firebase.QueryFilter{f}}); err == nil {
+			t.Errorf("applyQuery with %s %s: expected an error", f.Field, f.Operator)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/andrescris/firestore/lib/firebase"
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/pricing"
+)
+
+// ProductsCollection es la colección de Firestore donde viven los productos.
+const ProductsCollection = "products"
+
+var (
+	// ErrNotFound se devuelve cuando el producto solicitado no existe.
+	ErrNotFound = errors.New("product not found")
+	// ErrInvalidUpdate se devuelve cuando los datos de una actualización no
+	// tienen el formato esperado (p. ej. un importe mal formado).
+	ErrInvalidUpdate = errors.New("invalid update")
+	// ErrVariationNotFound se devuelve cuando el producto existe pero la variación no.
+	ErrVariationNotFound = errors.New("variation not found")
+	// ErrVersionConflict se devuelve cuando la versión esperada no coincide con la almacenada.
+	ErrVersionConflict = errors.New("product version conflict")
+	// ErrDuplicateSKU se devuelve al añadir una variación con un SKU ya usado en el producto.
+	ErrDuplicateSKU = errors.New("a variation with this SKU already exists for this product")
+)
+
+// AnyVersion desactiva la comprobación de versión en las escrituras.
+const AnyVersion int64 = -1
+
+// ProductRepository abstrae el almacenamiento de productos para que los handlers
+// no dependan directamente de Firestore.
+type ProductRepository interface {
+	// Get obtiene un producto por su ID.
+	Get(ctx context.Context, id string) (*models.Product, error)
+	// Create guarda un producto nuevo. El ID ya debe venir asignado.
+	Create(ctx context.Context, product *models.Product) error
+	// Query lista productos respetando filtros, orden y límites de QueryOptions.
+	Query(ctx context.Context, options firebase.QueryOptions) ([]models.Product, error)
+
+	// Las escrituras siguientes son read-modify-write transaccionales. Si
+	// expectedVersion no es AnyVersion y no coincide con la versión almacenada
+	// devuelven ErrVersionConflict sin escribir nada. Todas incrementan Version
+	// y devuelven el producto tal como quedó guardado.
+
+	// Mutate aplica fn sobre el producto dentro de una transacción.
+	Mutate(ctx context.Context, id string, expectedVersion int64, fn func(*models.Product) error) (*models.Product, error)
+	// Update aplica una actualización parcial (merge de campos de primer nivel).
+	Update(ctx context.Context, id string, expectedVersion int64, updates map[string]interface{}) (*models.Product, error)
+	// Deactivate realiza el soft delete de un producto: lo archiva y lo deja
+	// en la papelera. El autor se toma del StockChange del context.
+	Deactivate(ctx context.Context, id string, expectedVersion int64) (*models.Product, error)
+
+	// AddVariation añade una variación a un producto existente.
+	AddVariation(ctx context.Context, productID string, expectedVersion int64, variation models.Variation) (*models.Product, error)
+	// UpdateVariation aplica fn sobre la variación indicada y guarda el producto.
+	UpdateVariation(ctx context.Context, productID, variationID string, expectedVersion int64, fn func(*models.Variation) error) (*models.Product, error)
+	// DeactivateVariation marca una variación como inactiva.
+	DeactivateVariation(ctx context.Context, productID, variationID string, expectedVersion int64) (*models.Product, error)
+
+	// Delete elimina el producto definitivamente junto con sus revisiones,
+	// movimientos de stock, eventos y envíos a webhooks. Solo lo usa la
+	// retención de la papelera.
+	Delete(ctx context.Context, id string) error
+}
+
+// Store agrupa todos los repositorios que implementa cada backend
+// (FirestoreRepository y MemoryRepository).
+type Store interface {
+	ProductRepository
+	ReservationRepository
+	StockMovementRepository
+	RevisionRepository
+	LocationRepository
+	PriceListRepository
+	ExchangeRateRepository
+	TaxTableRepository
+	CategoryRepository
+	AuditRepository
+	WebhookRepository
+	EventRepository
+	BulkRepository
+}
+
+// productToData convierte un producto al mapa que se persiste, usando los tags JSON
+// para que los nombres de campo coincidan con los filtros de QueryOptions.
+func productToData(product *models.Product) (map[string]interface{}, error) {
+	jsonData, err := json.Marshal(product)
+	if err != nil {
+		return nil, err
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(jsonData, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// productFromData reconstruye un producto a partir del documento almacenado.
+func productFromData(data map[string]interface{}) (*models.Product, error) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var product models.Product
+	if err := json.Unmarshal(jsonData, &product); err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// applyMutation comprueba la versión, aplica fn y prepara los campos de control
+// antes de que la implementación escriba el producto.
+func applyMutation(product *models.Product, expectedVersion int64, fn func(*models.Product) error) error {
+	if expectedVersion != AnyVersion && product.Version != expectedVersion {
+		return ErrVersionConflict
+	}
+	if err := fn(product); err != nil {
+		return err
+	}
+	DeriveFields(product)
+	product.Version++
+	product.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// mergeUpdates aplica un mapa de campos (con nombres JSON) sobre el producto.
+func mergeUpdates(updates map[string]interface{}) func(*models.Product) error {
+	return func(product *models.Product) error {
+		data, err := productToData(product)
+		if err != nil {
+			return err
+		}
+		normalized, err := normalizeData(updates)
+		if err != nil {
+			return err
+		}
+		for field, value := range normalized {
+			data[field] = value
+			// El *Minor guardado es el del importe anterior y tendría
+			// prioridad sobre el nuevo.
+			delete(data, models.MinorField(field))
+		}
+		merged, err := productFromData(data)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidUpdate, err)
+		}
+		// La versión la controla applyMutation, no el cliente.
+		merged.Version = product.Version
+		if err := pricing.ValidateProduct(merged); err != nil {
+			return err
+		}
+		*product = *merged
+		return nil
+	}
+}
+
+// deactivate archiva el producto y lo deja en la papelera a nombre del autor
+// de la escritura.
+func deactivate(ctx context.Context) func(*models.Product) error {
+	return func(product *models.Product) error {
+		product.Active = false
+		product.Status = models.StatusArchived
+		if product.DeletedAt == nil {
+			now := time.Now().UTC().Truncate(time.Second)
+			actor := stockChangeFrom(ctx, "").Actor
+			product.DeletedAt = &now
+			product.DeletedBy = &actor
+		}
+		return nil
+	}
+}
+
+func addVariation(variation models.Variation) func(*models.Product) error {
+	return func(product *models.Product) error {
+		if product.IsBundle() {
+			return ErrBundleStock
+		}
+		for _, v := range product.Variations {
+			if v.SKU == variation.SKU {
+				return ErrDuplicateSKU
+			}
+		}
+		if err := pricing.ValidateVariation(&variation); err != nil {
+			return err
+		}
+		product.Variations = append(product.Variations, variation)
+		return nil
+	}
+}
+
+func updateVariation(variationID string, fn func(*models.Variation) error) func(*models.Product) error {
+	return func(product *models.Product) error {
+		i := findVariation(product, variationID)
+		if i < 0 {
+			return ErrVariationNotFound
+		}
+		v := &product.Variations[i]
+		if err := fn(v); err != nil {
+			return err
+		}
+		return pricing.ValidateVariation(v)
+	}
+}
+
+func deactivateVariation(ctx context.Context) func(*models.Variation) error {
+	return func(v *models.Variation) error {
+		v.Active = false
+		if v.DeletedAt == nil {
+			now := time.Now().UTC().Truncate(time.Second)
+			actor := stockChangeFrom(ctx, "").Actor
+			v.DeletedAt = &now
+			v.DeletedBy = &actor
+		}
+		return nil
+	}
+}
+
+// normalizeData pasa los valores por JSON para que tipos como time.Time o int
+// queden igual que los de un documento creado con productToData.
+func normalizeData(data map[string]interface{}) (map[string]interface{}, error) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var normalized map[string]interface{}
+	if err := json.Unmarshal(jsonData, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// findVariation devuelve el índice de la variación dentro del producto, o -1.
+func findVariation(product *models.Product, variationID string) int {
+	for i, v := range product.Variations {
+		if v.ID == variationID {
+			return i
+		}
+	}
+	return -1
+}
+
+// EachProduct recorre todos los productos que cumplen los filtros de options
+// en páginas de batchSize, ordenados por ID. Cada página empieza después del
+// último ID de la anterior, así que las escrituras durante el recorrido no
+// desplazan las páginas.
+func EachProduct(ctx context.Context, repo ProductRepository, options firebase.QueryOptions, batchSize int, fn func(models.Product) error) error {
+	filters := options.Filters
+	options.OrderBy = "id"
+	options.OrderDir = "asc"
+	options.Limit = batchSize
+	options.Offset = 0
+	after := ""
+	for {
+		options.Filters = filters
+		if after != "" {
+			options.Filters = append(filters[:len(filters):len(filters)], firebase.QueryFilter{Field: "id", Operator: ">", Value: after})
+		}
+		products, err := repo.Query(ctx, options)
+		if err != nil {
+			return err
+		}
+		for _, product := range products {
+			if err := fn(product); err != nil {
+				return err
+			}
+		}
+		if len(products) < batchSize {
+			return nil
+		}
+		after = products[len(products)-1].ID
+	}
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+// Un importe actualizado reemplaza al exacto guardado en su campo *Minor.
+func TestUpdateReplacesStoredMinorAmount(t *testing.T) {
+	tests := []struct {
+		name    string
+		updates map[string]interface{}
+		want    models.Money
+	}{
+		{"legacy decimal", map[string]interface{}{"price": 7.5}, 750},
+		{"amount object", map[string]interface{}{"price": map[string]interface{}{"amount": 800}}, 800},
+		{"other field", map[string]interface{}{"description": "Big mug"}, 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestRepository(t, mug())
+			updated, err := repo.Update(context.Background(), "mug", AnyVersion, tt.updates)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stored, err := repo.Get(context.Background(), "mug")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if updated.Price != tt.want || stored.Price != tt.want || stored.HasLegacyMoney() {
+				t.Errorf("price = %d, stored %d (legacy %t); want %d", updated.Price, stored.Price, stored.HasLegacyMoney(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/pricing"
+)
+
+// ReservationsCollection es la colección donde se guardan las reservas de stock.
+const ReservationsCollection = "stock_reservations"
+
+var (
+	// ErrReservationNotFound se devuelve cuando la reserva no existe.
+	ErrReservationNotFound = errors.New("reservation not found")
+	// ErrReservationClosed se devuelve al confirmar o liberar una reserva que ya no está activa.
+	ErrReservationClosed = errors.New("reservation is no longer active")
+	// ErrReservationExpired se devuelve al confirmar una reserva cuyo TTL ya venció.
+	ErrReservationExpired = errors.New("reservation has expired")
+	// ErrInsufficientStock es el error base de InsufficientStockError.
+	ErrInsufficientStock = errors.New("insufficient stock")
+)
+
+// StockShortage describe una línea que no se pudo reservar.
+type StockShortage struct {
+	ProductID   string `json:"productId"`
+	VariationID string `json:"variationId,omitempty"`
+	SKU         string `json:"sku"`
+	Requested   int    `json:"requested"`
+	Available   int    `json:"available"`
+}
+
+// InsufficientStockError lista todas las líneas sin stock suficiente.
+type InsufficientStockError struct {
+	Shortages []StockShortage
+}
+
+func (e *InsufficientStockError) Error() string {
+	skus := make([]string, len(e.Shortages))
+	for i, s := range e.Shortages {
+		skus[i] = s.SKU
+	}
+	return fmt.Sprintf("insufficient stock for %s", strings.Join(skus, ", "))
+}
+
+func (e *InsufficientStockError) Is(target error) bool {
+	return target == ErrInsufficientStock
+}
+
+// ReservationRepository reserva y libera stock de forma atómica: todas las
+// líneas de una reserva se aplican en una sola transacción junto con el
+// documento de la reserva.
+type ReservationRepository interface {
+	// Reserve descuenta el stock de todas las líneas y guarda la reserva. Si
+	// alguna línea no tiene stock no se modifica nada. Las líneas de bundles
+	// se reemplazan por las de sus componentes.
+	Reserve(ctx context.Context, reservation *models.Reservation) error
+	// GetReservation obtiene una reserva por su ID.
+	GetReservation(ctx context.Context, id string) (*models.Reservation, error)
+	// CommitReservation confirma la venta: el stock queda descontado.
+	CommitReservation(ctx context.Context, id string, now time.Time) (*models.Reservation, error)
+	// ReleaseReservation devuelve el stock y marca la reserva como liberada.
+	ReleaseReservation(ctx context.Context, id string) (*models.Reservation, error)
+	// ExpireReservation devuelve el stock de una reserva vencida. Si la reserva
+	// ya no está activa o aún no vence, no hace nada.
+	ExpireReservation(ctx context.Context, id string, now time.Time) (*models.Reservation, error)
+	// ListExpiredReservations devuelve hasta limit reservas activas vencidas.
+	ListExpiredReservations(ctx context.Context, now time.Time, limit int) ([]models.Reservation, error)
+}
+
+// reservationProductIDs devuelve los IDs de producto distintos de las líneas.
+func reservationProductIDs(lines []models.ReservationLine) []string {
+	ids := make([]string, len(lines))
+	for i, line := range lines {
+		ids[i] = line.ProductID
+	}
+	return uniqueStrings(ids)
+}
+
+// uniqueStrings elimina duplicados conservando el orden.
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool)
+	unique := []string{}
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
+
+// stockTarget devuelve el stock que corresponde a la línea y completa
+// VariationID/SKU para que la reserva quede autodescriptiva. En un producto
+// simple, un SKU vacío apunta al stock del producto.
+func stockTarget(product *models.Product, line *models.ReservationLine) (stockSlot, error) {
+	if product.IsBundle() {
+		return stockSlot{}, ErrBundleStock
+	}
+	if line.VariationID != "" {
+		i := findVariation(product, line.VariationID)
+		if i < 0 {
+			return stockSlot{}, fmt.Errorf("%w: %s", ErrVariationNotFound, line.VariationID)
+		}
+		line.SKU = product.Variations[i].SKU
+		return variationSlot(&product.Variations[i]), nil
+	}
+	if len(product.Variations) == 0 && (line.SKU == "" || product.SKU == line.SKU) {
+		line.SKU = product.SKU
+		return productSlot(product), nil
+	}
+	for i, v := range product.Variations {
+		if v.SKU == line.SKU {
+			line.VariationID = v.ID
+			return variationSlot(&product.Variations[i]), nil
+		}
+	}
+	return stockSlot{}, fmt.Errorf("%w: sku %s", ErrVariationNotFound, line.SKU)
+}
+
+// reserveStock descuenta las cantidades sobre los productos ya cargados. Valida
+// todas las líneas antes de devolver error para informar cada faltante. Un
+// producto o variación inactivos devuelven pricing.ErrUnavailable, como al
+// cotizarlos.
+func reserveStock(products map[string]*models.Product, reservation *models.Reservation) error {
+	shortages := []StockShortage{}
+	for i := range reservation.Lines {
+		line := &reservation.Lines[i]
+		product := products[line.ProductID]
+		if product.Subdomain != reservation.Subdomain {
+			return fmt.Errorf("%w: %s", ErrNotFound, line.ProductID)
+		}
+		slot, err := stockTarget(product, line)
+		if err != nil {
+			return err
+		}
+		if !product.Active {
+			return fmt.Errorf("%w: %s", pricing.ErrUnavailable, line.ProductID)
+		}
+		if line.VariationID != "" && !product.Variations[findVariation(product, line.VariationID)].Active {
+			return fmt.Errorf("%w: %s", pricing.ErrUnavailable, line.SKU)
+		}
+		allocations, ok := slot.take(line.LocationID, line.Quantity)
+		if !ok {
+			shortages = append(shortages, StockShortage{
+				ProductID:   line.ProductID,
+				VariationID: line.VariationID,
+				SKU:         line.SKU,
+				Requested:   line.Quantity,
+				Available:   slot.available(line.LocationID),
+			})
+			continue
+		}
+		line.Allocations = allocations
+	}
+	if len(shortages) > 0 {
+		return &InsufficientStockError{Shortages: shortages}
+	}
+	return nil
+}
+
+// restoreStock devuelve al stock las cantidades de la reserva.
+func restoreStock(products map[string]*models.Product, reservation *models.Reservation) {
+	for i := range reservation.Lines {
+		line := &reservation.Lines[i]
+		product, ok := products[line.ProductID]
+		if !ok {
+			// El producto desapareció: no hay stock al que devolver.
+			continue
+		}
+		slot, err := stockTarget(product, line)
+		if err != nil {
+			continue
+		}
+		slot.restore(line.Quantity, line.Allocations)
+	}
+}
+
+// touchProducts marca los productos modificados por una reserva como una
+// escritura más, para que If-Match detecte el cambio de stock.
+func touchProducts(products map[string]*models.Product) {
+	for _, product := range products {
+		applyMutation(product, AnyVersion, func(*models.Product) error { return nil })
+	}
+}
+
+// closeReservation valida la transición desde el estado activo.
+func closeReservation(reservation *models.Reservation, status models.ReservationStatus, now time.Time) error {
+	if reservation.Status != models.ReservationActive {
+		return ErrReservationClosed
+	}
+	reservation.Status = status
+	reservation.UpdatedAt = now
+	return nil
+}
@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/pricing"
+)
+
+// tee es un producto publicado con dos variaciones: S (5 unidades) y M
+// (inactiva, 3 unidades).
+func tee() *models.Product {
+	return &models.Product{
+		ID: "tee", Name: "Tee", Currency: "USD", Subdomain: "s", ProjectID: "proj",
+		Status: models.StatusPublished,
+		Variations: []models.Variation{
+			{ID: "tee-s", SKU: "TEE-S", Price: 1000, Stock: 5, Active: true},
+			{ID: "tee-m", SKU: "TEE-M", Price: 1000, Stock: 3, Active: false},
+		},
+	}
+}
+
+// mug es un producto simple publicado con 2 unidades.
+func mug() *models.Product {
+	return &models.Product{
+		ID: "mug", Name: "Mug", Currency: "USD", Subdomain: "s", ProjectID: "proj",
+		Status: models.StatusPublished, SKU: "MUG", Price: 500, Stock: 2,
+	}
+}
+
+// newTestRepository crea un MemoryRepository con los productos indicados.
+func newTestRepository(t *testing.T, products ...*models.Product) *MemoryRepository {
+	t.Helper()
+	repo := NewMemoryRepository()
+	for _, product := range products {
+		if err := repo.Create(context.Background(), product); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo
+}
+
+func newReservation(lines ...models.ReservationLine) *models.Reservation {
+	now := time.Now().UTC()
+	return &models.Reservation{
+		ID: "res-1", Subdomain: "s", Status: models.ReservationActive, Lines: lines,
+		ExpiresAt: now.Add(time.Minute), CreatedAt: now, UpdatedAt: now,
+	}
+}
+
+// stockOf devuelve el stock del producto simple o de la variación indicada.
+func stockOf(t *testing.T, repo *MemoryRepository, productID, variationID string) int {
+	t.Helper()
+	product, err := repo.Get(context.Background(), productID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if variationID == "" {
+		return product.Stock
+	}
+	return product.Variations[findVariation(product, variationID)].Stock
+}
+
+func TestReserve(t *testing.T) {
+	archived := mug()
+	archived.ID, archived.SKU, archived.Status = "old-mug", "OLD-MUG", models.StatusArchived
+	other := mug()
+	other.ID, other.SKU, other.Subdomain = "other-mug", "OTHER-MUG", "other"
+
+	tests := []struct {
+		name    string
+		lines   []models.ReservationLine
+		wantErr error
+	}{
+		{"variation by id", []models.ReservationLine{{ProductID: "tee", VariationID: "tee-s", Quantity: 5}}, nil},
+		{"variation by sku", []models.ReservationLine{{ProductID: "tee", SKU: "TEE-S", Quantity: 1}}, nil},
+		{"simple product", []models.ReservationLine{{ProductID: "mug", Quantity: 2}}, nil},
+		{"several lines", []models.ReservationLine{{ProductID: "tee", SKU: "TEE-S", Quantity: 1}, {ProductID: "mug", SKU: "MUG", Quantity: 1}}, nil},
+		{"insufficient stock", []models.ReservationLine{{ProductID: "tee", SKU: "TEE-S", Quantity: 6}}, ErrInsufficientStock},
+		{"one line short reserves nothing", []models.ReservationLine{{ProductID: "tee", SKU: "TEE-S", Quantity: 1}, {ProductID: "mug", Quantity: 3}}, ErrInsufficientStock},
+		{"inactive variation", []models.ReservationLine{{ProductID: "tee", SKU: "TEE-M", Quantity: 1}}, pricing.ErrUnavailable},
+		{"archived product", []models.ReservationLine{{ProductID: "old-mug", Quantity: 1}}, pricing.ErrUnavailable},
+		{"unknown sku", []models.ReservationLine{{ProductID: "tee", SKU: "TEE-XL", Quantity: 1}}, ErrVariationNotFound},
+		{"unknown product", []models.ReservationLine{{ProductID: "nope", Quantity: 1}}, ErrNotFound},
+		{"other subdomain", []models.ReservationLine{{ProductID: "other-mug", Quantity: 1}}, ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestRepository(t, tee(), mug(), archived, other)
+			reservation := newReservation(tt.lines...)
+			err := repo.Reserve(context.Background(), reservation)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Reserve() error = %v, want %v", err, tt.wantErr)
+			}
+
+			reserved := map[string]int{}
+			if err == nil {
+				for _, line := range reservation.Lines {
+					reserved[line.ProductID+"/"+line.VariationID] += line.Quantity
+				}
+			} else if _, getErr := repo.GetReservation(context.Background(), reservation.ID); !errors.Is(getErr, ErrReservationNotFound) {
+				t.Errorf("a failed reservation was stored: %v", getErr)
+			}
+			if got, want := stockOf(t, repo, "tee", "tee-s"), 5-reserved["tee/tee-s"]; got != want {
+				t.Errorf("TEE-S stock = %d, want %d", got, want)
+			}
+			if got, want := stockOf(t, repo, "mug", ""), 2-reserved["mug/"]; got != want {
+				t.Errorf("MUG stock = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestReservationLifecycle(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name      string
+		close     func(repo *MemoryRepository, id string) (*models.Reservation, error)
+		want      models.ReservationStatus
+		wantStock int
+	}{
+		{"commit keeps the stock out", func(repo *MemoryRepository, id string) (*models.Reservation, error) {
+			return repo.CommitReservation(ctx, id, time.Now().UTC())
+		}, models.ReservationCommitted, 3},
+		{"release returns the stock", func(repo *MemoryRepository, id string) (*models.Reservation, error) {
+			return repo.ReleaseReservation(ctx, id)
+		}, models.ReservationReleased, 5},
+		{"expire returns the stock", func(repo *MemoryRepository, id string) (*models.Reservation, error) {
+			return repo.ExpireReservation(ctx, id, time.Now().UTC().Add(time.Hour))
+		}, models.ReservationExpired, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestRepository(t, tee())
+			reservation := newReservation(models.ReservationLine{ProductID: "tee", SKU: "TEE-S", Quantity: 2})
+			if err := repo.Reserve(ctx, reservation); err != nil {
+				t.Fatal(err)
+			}
+			closed, err := tt.close(repo, reservation.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if closed.Status != tt.want {
+				t.Errorf("status = %s, want %s", closed.Status, tt.want)
+			}
+			if got := stockOf(t, repo, "tee", "tee-s"); got != tt.wantStock {
+				t.Errorf("stock = %d, want %d", got, tt.wantStock)
+			}
+			// Una reserva cerrada no se vuelve a cerrar ni devuelve stock dos veces.
+			if _, err := repo.ReleaseReservation(ctx, reservation.ID); !errors.Is(err, ErrReservationClosed) {
+				t.Errorf("second close error = %v, want %v", err, ErrReservationClosed)
+			}
+			if got := stockOf(t, repo, "tee", "tee-s"); got != tt.wantStock {
+				t.Errorf("stock after second close = %d, want %d", got, tt.wantStock)
+			}
+		})
+	}
+}
+
+func TestCommitExpiredReservation(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t, tee())
+	reservation := newReservation(models.ReservationLine{ProductID: "tee", SKU: "TEE-S", Quantity: 2})
+	if err := repo.Reserve(ctx, reservation); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CommitReservation(ctx, reservation.ID, reservation.ExpiresAt.Add(time.Second)); !errors.Is(err, ErrReservationExpired) {
+		t.Fatalf("CommitReservation() error = %v, want %v", err, ErrReservationExpired)
+	}
+	expired, err := repo.ListExpiredReservations(ctx, reservation.ExpiresAt.Add(time.Second), 10)
+	if err != nil || len(expired) != 1 {
+		t.Fatalf("ListExpiredReservations() = %v, %v", expired, err)
+	}
+}
@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+// RevisionsCollection es la colección del historial de revisiones de productos.
+const RevisionsCollection = "product_revisions"
+
+// ErrRevisionNotFound se devuelve cuando el producto no tiene la revisión pedida.
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// RevisionRepository da acceso de lectura al historial. Igual que el libro de
+// movimientos, las revisiones se escriben solas: cada escritura de producto
+// guarda la suya en la misma transacción.
+type RevisionRepository interface {
+	// ListRevisions devuelve las revisiones del producto, de la más reciente
+	// a la más antigua y sin Snapshot. limit <= 0 devuelve todas.
+	ListRevisions(ctx context.Context, productID string, limit, offset int) ([]models.ProductRevision, error)
+	// GetRevision devuelve una revisión completa, con Snapshot.
+	GetRevision(ctx context.Context, productID string, revision int64) (*models.ProductRevision, error)
+}
+
+// revisionID es el ID del documento de una revisión; permite leerla sin consultas.
+func revisionID(productID string, revision int64) string {
+	return fmt.Sprintf("%s@%d", productID, revision)
+}
+
+// newRevision arma la revisión que deja una escritura. before es el documento
+// anterior, o nil si la escritura crea el producto.
+func newRevision(product *models.Product, before map[string]interface{}, change StockChange, now time.Time) (models.ProductRevision, error) {
+	snapshot, err := productToData(product)
+	if err != nil {
+		return models.ProductRevision{}, err
+	}
+	changes := []models.FieldChange{}
+	if before != nil {
+		changes = DiffDocs(before, snapshot)
+	}
+	return models.ProductRevision{
+		ID:        revisionID(product.ID, product.Version),
+		ProductID: product.ID,
+		Subdomain: product.Subdomain,
+		Revision:  product.Version,
+		Actor:     change.Actor,
+		Source:    change.Source,
+		Changes:   changes,
+		Snapshot:  snapshot,
+		CreatedAt: now,
+	}, nil
+}
+
+// captureDocs guarda el documento de varios productos antes de modificarlos.
+func captureDocs(products map[string]*models.Product) (map[string]map[string]interface{}, error) {
+	docs := make(map[string]map[string]interface{}, len(products))
+	for id, product := range products {
+		data, err := productToData(product)
+		if err != nil {
+			return nil, err
+		}
+		docs[id] = data
+	}
+	return docs, nil
+}
+
+// revisionsFor genera las revisiones de varios productos modificados juntos.
+func revisionsFor(products map[string]*models.Product, before map[string]map[string]interface{}, change StockChange, now time.Time) ([]models.ProductRevision, error) {
+	ids := make([]string, 0, len(products))
+	for id := range products {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	revisions := make([]models.ProductRevision, 0, len(ids))
+	for _, id := range ids {
+		revision, err := newRevision(products[id], before[id], change, now)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, nil
+}
+
+// revisionNoise son los campos que cambian en todas las escrituras y no
+// aportan nada al diff.
+var revisionNoise = map[string]bool{"version": true, "updatedAt": true}
+
+// DiffDocs compara dos documentos de producto campo a campo. Los objetos se
+// recorren por sus claves y las listas de objetos con id (variaciones) por
+// ID; cualquier otro valor se compara entero.
+func DiffDocs(before, after map[string]interface{}) []models.FieldChange {
+	changes := []models.FieldChange{}
+	diffMaps("", before, after, &changes)
+	return changes
+}
+
+func diffMaps(path string, before, after map[string]interface{}, changes *[]models.FieldChange) {
+	keys := make([]string, 0, len(before)+len(after))
+	for key := range before {
+		keys = append(keys, key)
+	}
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if path == "" && revisionNoise[key] {
+			continue
+		}
+		field := key
+		if path != "" {
+			field = path + "." + key
+		}
+		diffValues(field, before[key], after[key], changes)
+	}
+}
+
+func diffValues(path string, before, after interface{}, changes *[]models.FieldChange) {
+	beforeMap, okBefore := before.(map[string]interface{})
+	afterMap, okAfter := after.(map[string]interface{})
+	if okBefore && okAfter {
+		diffMaps(path, beforeMap, afterMap, changes)
+		return
+	}
+
+	beforeIDs, beforeItems, okBefore := itemsByID(before)
+	afterIDs, afterItems, okAfter := itemsByID(after)
+	if okBefore && okAfter {
+		for _, id := range afterIDs {
+			diffValues(path+"["+id+"]", beforeItems[id], afterItems[id], changes)
+		}
+		for _, id := range beforeIDs {
+			if _, ok := afterItems[id]; !ok {
+				diffValues(path+"["+id+"]", beforeItems[id], nil, changes)
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		*changes = append(*changes, models.FieldChange{Field: path, Before: before, After: after})
+	}
+}
+
+// itemsByID indexa una lista de objetos por su campo id y devuelve los IDs
+// en orden. Devuelve false si value no es una lista o algún elemento no tiene id.
+func itemsByID(value interface{}) ([]string, map[string]interface{}, bool) {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, nil, false
+	}
+	ids := make([]string, 0, len(list))
+	items := make(map[string]interface{}, len(list))
+	for _, item := range list {
+		object, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, nil, false
+		}
+		id, ok := object["id"].(string)
+		if !ok || id == "" {
+			return nil, nil, false
+		}
+		ids = append(ids, id)
+		items[id] = object
+	}
+	return ids, items, true
+}
+
+// RestoreRevision devuelve cómo quedaría el producto con la foto de la
+// revisión, sin guardarlo. Solo se restaura el contenido: se conservan el
+// estado del ciclo de vida, la papelera, la programación de publicación y el
+// stock actuales, y las variaciones creadas después de la revisión siguen en
+// el producto (con sus reservas). Las variaciones restauradas mantienen su
+// alta o baja actual; las que ya no existen vuelven sin stock. La categoría y
+// el bundle restaurados se deben validar de nuevo antes de aplicarlo con
+// Rollback.
+func RestoreRevision(product *models.Product, revision *models.ProductRevision) (*models.Product, error) {
+	restored, err := productFromData(revision.Snapshot)
+	if err != nil {
+		return nil, err
+	}
+	restored.ID = product.ID
+	restored.Version = product.Version
+	restored.CreatedAt = product.CreatedAt
+	restored.Subdomain = product.Subdomain
+	restored.ProjectID = product.ProjectID
+	restored.Status = product.Status
+	restored.Active = product.Active
+	restored.PublishAt = product.PublishAt
+	restored.DeletedAt = product.DeletedAt
+	restored.DeletedBy = product.DeletedBy
+	restored.Stock = product.Stock
+	restored.Inventory = product.Inventory
+
+	restoredIDs := make(map[string]bool, len(restored.Variations))
+	for i := range restored.Variations {
+		v := &restored.Variations[i]
+		restoredIDs[v.ID] = true
+		v.Stock, v.Inventory = 0, nil
+		if j := findVariation(product, v.ID); j >= 0 {
+			current := product.Variations[j]
+			v.Stock = current.Stock
+			v.Inventory = current.Inventory
+			v.Active = current.Active
+			v.DeletedAt = current.DeletedAt
+			v.DeletedBy = current.DeletedBy
+		}
+	}
+	for _, v := range product.Variations {
+		if !restoredIDs[v.ID] {
+			restored.Variations = append(restored.Variations, v)
+		}
+	}
+	return restored, nil
+}
+
+// Rollback guarda el producto que calculó RestoreRevision. Si el producto
+// cambió desde entonces devuelve ErrVersionConflict, porque la validación se
+// hizo sobre la versión anterior.
+func Rollback(restored *models.Product) func(*models.Product) error {
+	return func(product *models.Product) error {
+		if product.Version != restored.Version {
+			return ErrVersionConflict
+		}
+		*product = *restored
+		return nil
+	}
+}
@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+func TestDiffDocs(t *testing.T) {
+	variation := func(id string, price float64) map[string]interface{} {
+		return map[string]interface{}{"id": id, "price": price}
+	}
+	tests := []struct {
+		name   string
+		before map[string]interface{}
+		after  map[string]interface{}
+		want   []models.FieldChange
+	}{
+		{"no changes", map[string]interface{}{"name": "Tee"}, map[string]interface{}{"name": "Tee"}, []models.FieldChange{}},
+		{"changed, added and removed fields in key order",
+			map[string]interface{}{"name": "Tee", "brand": "Acme"},
+			map[string]interface{}{"name": "T-shirt", "weight": 0.2},
+			[]models.FieldChange{
+				{Field: "brand", Before: "Acme", After: nil},
+				{Field: "name", Before: "Tee", After: "T-shirt"},
+				{Field: "weight", Before: nil, After: 0.2},
+			}},
+		{"nested objects use dotted paths",
+			map[string]interface{}{"metadata": map[string]interface{}{"a": 1.0, "b": 2.0}},
+			map[string]interface{}{"metadata": map[string]interface{}{"a": 1.0, "b": 3.0}},
+			[]models.FieldChange{{Field: "metadata.b", Before: 2.0, After: 3.0}}},
+		{"variations are matched by id",
+			map[string]interface{}{"variations": []interface{}{variation("v1", 10), variation("v2", 20)}},
+			map[string]interface{}{"variations": []interface{}{variation("v2", 20), variation("v1", 9), variation("v3", 30)}},
+			[]models.FieldChange{
+				{Field: "variations[v1].price", Before: 10.0, After: 9.0},
+				{Field: "variations[v3]", Before: nil, After: variation("v3", 30)},
+			}},
+		{"removed variation",
+			map[string]interface{}{"variations": []interface{}{variation("v1", 10)}},
+			map[string]interface{}{"variations": []interface{}{}},
+			[]models.FieldChange{{Field: "variations[v1]", Before: variation("v1", 10), After: nil}}},
+		{"lists without ids are compared whole",
+			map[string]interface{}{"category_ids": []interface{}{"a", "b"}},
+			map[string]interface{}{"category_ids": []interface{}{"a", "c"}},
+			[]models.FieldChange{{Field: "category_ids", Before: []interface{}{"a", "b"}, After: []interface{}{"a", "c"}}}},
+		{"control fields are ignored",
+			map[string]interface{}{"version": 1.0, "updatedAt": "x"},
+			map[string]interface{}{"version": 2.0, "updatedAt": "y"},
+			[]models.FieldChange{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiffDocs(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffDocs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWritesRecordRevisions(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t, tee())
+	if _, err := repo.Update(ctx, "tee", 1, map[string]interface{}{"name": "T-shirt"}); err != nil {
+		t.Fatal(err)
+	}
+
+	revisions, err := repo.ListRevisions(ctx, "tee", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 2 || revisions[0].Revision != 2 || revisions[1].Revision != 1 {
+		t.Fatalf("revisions = %+v", revisions)
+	}
+	if revisions[0].Snapshot != nil {
+		t.Error("ListRevisions returned snapshots")
+	}
+	want := []models.FieldChange{{Field: "name", Before: "Tee", After: "T-shirt"}}
+	if !reflect.DeepEqual(revisions[0].Changes, want) {
+		t.Errorf("changes = %+v, want %+v", revisions[0].Changes, want)
+	}
+	revision, err := repo.GetRevision(ctx, "tee", 1)
+	if err != nil || revision.Snapshot["name"] != "Tee" {
+		t.Fatalf("GetRevision(1) = %+v, %v", revision, err)
+	}
+	if _, err := repo.GetRevision(ctx, "tee", 3); !errors.Is(err, ErrRevisionNotFound) {
+		t.Errorf("GetRevision(3) error = %v, want %v", err, ErrRevisionNotFound)
+	}
+}
+
+func TestRestoreRevision(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t, tee())
+
+	// Después de la revisión 1: cambia el contenido, el stock y el estado, se
+	// da de baja TEE-S y se crea TEE-L.
+	_, err := repo.Mutate(ctx, "tee", AnyVersion, func(p *models.Product) error {
+		p.Name, p.Brand = "T-shirt", "Acme"
+		p.Variations[0].Price, p.Variations[0].Stock = 900, 1
+		p.Variations[1].Price = 1200
+		now := time.Now().UTC()
+		p.Variations[0].Active, p.Variations[0].DeletedAt = false, &now
+		p.Status = models.StatusArchived
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := repo.AddVariation(ctx, "tee", AnyVersion, models.Variation{ID: "tee-l", SKU: "TEE-L", Price: 1500, Stock: 7, Active: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	revision, err := repo.GetRevision(ctx, "tee", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := RestoreRevision(current, revision)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Name != "Tee" || restored.Brand != "" {
+		t.Errorf("content not restored: name %q, brand %q", restored.Name, restored.Brand)
+	}
+	if restored.Status != models.StatusArchived || restored.Active || restored.Version != current.Version {
+		t.Errorf("lifecycle not kept: status %s, active %t, version %d", restored.Status, restored.Active, restored.Version)
+	}
+	if len(restored.Variations) != 3 {
+		t.Fatalf("variations = %+v", restored.Variations)
+	}
+	s, m, l := restored.Variations[0], restored.Variations[1], restored.Variations[2]
+	if s.Price != 1000 || s.Stock != 1 || s.Active || s.DeletedAt == nil {
+		t.Errorf("TEE-S = %+v, want the old price with the current stock and deletion", s)
+	}
+	if m.Price != 1000 || m.Stock != 3 {
+		t.Errorf("TEE-M = %+v", m)
+	}
+	if l.ID != "tee-l" || l.Stock != 7 || !l.Active {
+		t.Errorf("newer variation not kept: %+v", l)
+	}
+
+	// Rollback falla si el producto cambió después de RestoreRevision.
+	if _, err := repo.Update(ctx, "tee", AnyVersion, map[string]interface{}{"description": "new"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Mutate(ctx, "tee", AnyVersion, Rollback(restored)); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("stale Rollback() error = %v, want %v", err, ErrVersionConflict)
+	}
+
+	current, err = repo.Get(ctx, "tee")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored, err = RestoreRevision(current, revision); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := repo.Mutate(ctx, "tee", current.Version, Rollback(restored))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Version != current.Version+1 || updated.Name != "Tee" || updated.Description != "" || updated.TotalStock != 7 {
+		t.Errorf("rolled back product = %+v", updated)
+	}
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+var (
+	// ErrInvalidTransition se devuelve al pedir un cambio de estado no permitido.
+	ErrInvalidTransition = errors.New("invalid status transition")
+	// ErrNotDraft se devuelve al programar la publicación de un producto que
+	// no es un borrador.
+	ErrNotDraft = errors.New("only draft products can be scheduled for publishing")
+)
+
+// syncStatus completa el estado de los documentos anteriores al ciclo de
+// vida a partir de Active y mantiene Active como reflejo del estado.
+func syncStatus(product *models.Product) {
+	if product.Status == "" {
+		if product.Active {
+			product.Status = models.StatusPublished
+		} else {
+			product.Status = models.StatusArchived
+		}
+	}
+	product.Active = product.Status == models.StatusPublished
+	if product.Status != models.StatusDraft {
+		product.PublishAt = nil
+	}
+}
+
+// SetStatus cambia el estado del producto si la transición está permitida.
+// Pedir el estado actual no es un error.
+func SetStatus(to models.ProductStatus) func(*models.Product) error {
+	return func(product *models.Product) error {
+		syncStatus(product)
+		if product.Status == to {
+			return nil
+		}
+		if !product.Status.CanTransitionTo(to) {
+			return fmt.Errorf("%w: %s → %s", ErrInvalidTransition, product.Status, to)
+		}
+		product.Status = to
+		return nil
+	}
+}
+
+// SchedulePublish programa la publicación de un borrador en at.
+func SchedulePublish(at time.Time) func(*models.Product) error {
+	return func(product *models.Product) error {
+		syncStatus(product)
+		if product.Status != models.StatusDraft {
+			return ErrNotDraft
+		}
+		at = at.UTC().Truncate(time.Second)
+		product.PublishAt = &at
+		return nil
+	}
+}
+
+// PublishIfDue publica el borrador si su publicación programada ya llegó.
+func PublishIfDue(now time.Time) func(*models.Product) error {
+	return func(product *models.Product) error {
+		if product.Status == models.StatusDraft && product.PublishAt != nil && !product.PublishAt.After(now) {
+			product.Status = models.StatusPublished
+		}
+		return nil
+	}
+}
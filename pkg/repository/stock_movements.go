@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+	"github.com/google/uuid"
+)
+
+// StockMovementsCollection es la colección del libro de movimientos de stock.
+const StockMovementsCollection = "stock_movements"
+
+// StockMovementRepository da acceso de lectura al libro de movimientos. Las
+// escrituras ocurren solas: cualquier escritura de producto que cambie un
+// stock agrega sus movimientos en la misma transacción.
+type StockMovementRepository interface {
+	// ListStockMovements devuelve los movimientos del producto, del más
+	// reciente al más antiguo. limit <= 0 devuelve todos.
+	ListStockMovements(ctx context.Context, productID string, limit, offset int) ([]models.StockMovement, error)
+}
+
+// StockChange describe el motivo y el autor de los cambios de stock que haga
+// una escritura. Viaja en el context para que cada operación del repositorio
+// pueda registrarlos sin cambiar su firma. Actor y Source también se guardan
+// en la revisión del producto.
+type StockChange struct {
+	Reason    models.MovementReason
+	Actor     models.Actor
+	Reference string
+	Note      string
+	// Source es el endpoint que originó la escritura.
+	Source string
+}
+
+type stockChangeKey struct{}
+
+// WithStockChange asocia el motivo y el autor de los cambios de stock al context.
+func WithStockChange(ctx context.Context, change StockChange) context.Context {
+	return context.WithValue(ctx, stockChangeKey{}, change)
+}
+
+// stockChangeFrom obtiene el StockChange del context. Si no hay, o no trae
+// motivo, usa defaultReason.
+func stockChangeFrom(ctx context.Context, defaultReason models.MovementReason) StockChange {
+	change, _ := ctx.Value(stockChangeKey{}).(StockChange)
+	if change.Reason == "" {
+		change.Reason = defaultReason
+	}
+	return change
+}
+
+// stockLevel es el stock de un SKU dentro de un producto. La clave del mapa
+// es el ID de la variación, o "" para el producto simple.
+type stockLevel struct {
+	VariationID string
+	SKU         string
+	Stock       int
+}
+
+// stockLevels captura el stock actual de cada SKU del producto.
+func stockLevels(product *models.Product) map[string]stockLevel {
+	levels := make(map[string]stockLevel)
+	if product == nil {
+		return levels
+	}
+	if len(product.Variations) == 0 {
+		levels[""] = stockLevel{SKU: product.SKU, Stock: product.Stock}
+		return levels
+	}
+	for _, v := range product.Variations {
+		levels[v.ID] = stockLevel{VariationID: v.ID, SKU: v.SKU, Stock: v.Stock}
+	}
+	return levels
+}
+
+// stockMovements compara el stock antes y después de una escritura y genera
+// un movimiento por cada SKU que cambió.
+func stockMovements(product *models.Product, before map[string]stockLevel, change StockChange, now time.Time) []models.StockMovement {
+	after := stockLevels(product)
+
+	keys := make([]string, 0, len(after))
+	for key := range after {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	movements := []models.StockMovement{}
+	for _, key := range keys {
+		level := after[key]
+		delta := level.Stock - before[key].Stock
+		if delta == 0 {
+			continue
+		}
+		movements = append(movements, models.StockMovement{
+			ID:             "mov-" + uuid.New().String(),
+			ProductID:      product.ID,
+			VariationID:    level.VariationID,
+			SKU:            level.SKU,
+			Subdomain:      product.Subdomain,
+			Reason:         change.Reason,
+			Delta:          delta,
+			Balance:        level.Stock,
+			Actor:          change.Actor,
+			Reference:      change.Reference,
+			Note:           change.Note,
+			ProductVersion: product.Version,
+			CreatedAt:      now,
+		})
+	}
+	return movements
+}
+
+// sortMovementsNewestFirst ordena por versión del producto y luego por SKU.
+func sortMovementsNewestFirst(movements []models.StockMovement) {
+	sort.SliceStable(movements, func(i, j int) bool {
+		if movements[i].ProductVersion != movements[j].ProductVersion {
+			return movements[i].ProductVersion > movements[j].ProductVersion
+		}
+		return movements[i].SKU < movements[j].SKU
+	})
+}
+
+// captureStock guarda los niveles de stock de varios productos antes de modificarlos.
+func captureStock(products map[string]*models.Product) map[string]map[string]stockLevel {
+	before := make(map[string]map[string]stockLevel, len(products))
+	for id, product := range products {
+		before[id] = stockLevels(product)
+	}
+	return before
+}
+
+// movementsFor genera los movimientos de varios productos modificados juntos.
+func movementsFor(products map[string]*models.Product, before map[string]map[string]stockLevel, change StockChange, now time.Time) []models.StockMovement {
+	ids := make([]string, 0, len(products))
+	for id := range products {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	movements := []models.StockMovement{}
+	for _, id := range ids {
+		movements = append(movements, stockMovements(products[id], before[id], change, now)...)
+	}
+	return movements
+}
+
+// reservationStockChange arma el StockChange de una operación de reserva,
+// conservando el autor que traiga el context.
+func reservationStockChange(ctx context.Context, reason models.MovementReason, reservationID string) StockChange {
+	change := stockChangeFrom(ctx, reason)
+	change.Reason = reason
+	change.Reference = reservationID
+	return change
+}
+
+// AdjustStock suma delta al stock de una variación (variationID) o SKU del
+// producto, en la ubicación indicada si el SKU lleva stock por ubicación.
+// Nunca deja el stock en negativo.
+func AdjustStock(ctx context.Context, repo ProductRepository, productID string, expectedVersion int64, variationID, sku, locationID string, delta int) (*models.Product, error) {
+	return repo.Mutate(ctx, productID, expectedVersion, func(product *models.Product) error {
+		line := models.ReservationLine{ProductID: productID, VariationID: variationID, SKU: sku}
+		slot, err := stockTarget(product, &line)
+		if err != nil {
+			return err
+		}
+		if available := slot.available(locationID); available+delta < 0 {
+			return &InsufficientStockError{Shortages: []StockShortage{{
+				ProductID:   productID,
+				VariationID: line.VariationID,
+				SKU:         line.SKU,
+				Requested:   -delta,
+				Available:   available,
+			}}}
+		}
+		return slot.add(locationID, delta)
+	})
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+// TaxTablesCollection guarda una tabla de impuestos por proyecto.
+const TaxTablesCollection = "tax_tables"
+
+// ErrTaxTableNotFound se devuelve cuando el proyecto no tiene tabla de
+// impuestos.
+var ErrTaxTableNotFound = errors.New("tax table not found")
+
+// TaxTableRepository gestiona las tablas de impuestos.
+type TaxTableRepository interface {
+	GetTaxTable(ctx context.Context, projectID string) (*models.TaxTable, error)
+	// SaveTaxTable reemplaza la tabla del proyecto.
+	SaveTaxTable(ctx context.Context, table *models.TaxTable) error
+}
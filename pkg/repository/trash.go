@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+// ErrNotDeleted se devuelve al restaurar un producto o una variación que no
+// está en la papelera.
+var ErrNotDeleted = errors.New("not in the trash")
+
+// RestoreProduct saca el producto de la papelera. Vuelve como borrador para
+// que se revise antes de publicarlo de nuevo.
+func RestoreProduct(product *models.Product) error {
+	if product.DeletedAt == nil {
+		return ErrNotDeleted
+	}
+	product.DeletedAt = nil
+	product.DeletedBy = nil
+	product.Status = models.StatusDraft
+	return nil
+}
+
+// RestoreVariation reactiva una variación dada de baja.
+func RestoreVariation(v *models.Variation) error {
+	if v.DeletedAt == nil {
+		return ErrNotDeleted
+	}
+	v.DeletedAt = nil
+	v.DeletedBy = nil
+	v.Active = true
+	return nil
+}
+
+// PurgeVariations elimina definitivamente las variaciones dadas de baja
+// antes de cutoff y guarda en removed sus SKUs.
+func PurgeVariations(cutoff time.Time, removed *[]string) func(*models.Product) error {
+	return func(product *models.Product) error {
+		kept := product.Variations[:0]
+		for _, v := range product.Variations {
+			if v.DeletedAt != nil && !v.DeletedAt.After(cutoff) {
+				*removed = append(*removed, v.SKU)
+				continue
+			}
+			kept = append(kept, v)
+		}
+		product.Variations = kept
+		return nil
+	}
+}
+
+// syncTrash saca de la papelera lo que se reactivó por otra vía (p. ej.
+// unpublish de un producto archivado o PATCH con active=true en una
+// variación) y recalcula VariationDeletedAt.
+func syncTrash(product *models.Product) {
+	if product.Status != models.StatusArchived {
+		product.DeletedAt = nil
+		product.DeletedBy = nil
+	}
+	for i := range product.Variations {
+		if product.Variations[i].Active {
+			product.Variations[i].DeletedAt = nil
+			product.Variations[i].DeletedBy = nil
+		}
+	}
+	product.VariationDeletedAt = oldestVariationDeletion(product)
+}
+
+// oldestVariationDeletion devuelve la baja más antigua entre las variaciones,
+// o nil si ninguna está en la papelera.
+func oldestVariationDeletion(product *models.Product) *time.Time {
+	var oldest *time.Time
+	for _, v := range product.Variations {
+		if v.DeletedAt != nil && (oldest == nil || v.DeletedAt.Before(*oldest)) {
+			at := *v.DeletedAt
+			oldest = &at
+		}
+	}
+	return oldest
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+func TestDeletePurgesProductHistory(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t, tee(), mug())
+	if _, err := repo.Deactivate(ctx, "tee", AnyVersion); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reparte a un webhook todos los eventos de los dos productos.
+	events, err := repo.ListPendingEvents(ctx, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, event := range events {
+		delivery := models.WebhookDelivery{ID: "del-" + event.ID, WebhookID: "wh", Subdomain: "s", Event: event, Status: models.DeliveryPending}
+		if err := repo.DispatchEvent(ctx, event.ID, []models.WebhookDelivery{delivery}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := repo.Delete(ctx, "tee"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Get(ctx, "tee"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() after Delete error = %v, want %v", err, ErrNotFound)
+	}
+	if err := repo.Delete(ctx, "tee"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Delete() error = %v, want %v", err, ErrNotFound)
+	}
+
+	counts := func(productID string) (revisions, movements, events, deliveries int) {
+		t.Helper()
+		revs, err := repo.ListRevisions(ctx, productID, 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		moves, err := repo.ListStockMovements(ctx, productID, 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		evts, err := repo.ListEvents(ctx, "s", "", 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dels, err := repo.ListDeliveries(ctx, "wh", "", 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, event := range evts {
+			if event.ProductID == productID {
+				events++
+			}
+		}
+		for _, delivery := range dels {
+			if delivery.Event.ProductID == productID {
+				deliveries++
+			}
+		}
+		return len(revs), len(moves), events, deliveries
+	}
+
+	if revisions, movements, events, deliveries := counts("tee"); revisions+movements+events+deliveries != 0 {
+		t.Errorf("purged product kept %d revisions, %d movements, %d events and %d deliveries", revisions, movements, events, deliveries)
+	}
+	if revisions, movements, events, deliveries := counts("mug"); revisions == 0 || movements == 0 || events == 0 || deliveries == 0 {
+		t.Errorf("other product lost its history: %d revisions, %d movements, %d events, %d deliveries", revisions, movements, events, deliveries)
+	}
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+const (
+	// WebhooksCollection guarda las suscripciones de webhooks.
+	WebhooksCollection = "webhooks"
+	// WebhookDeliveriesCollection guarda cada envío de un evento a un webhook.
+	WebhookDeliveriesCollection = "webhook_deliveries"
+)
+
+var (
+	// ErrWebhookNotFound se devuelve cuando el webhook no existe.
+	ErrWebhookNotFound = errors.New("webhook not found")
+	// ErrDeliveryNotFound se devuelve cuando el envío no existe.
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+	// ErrInvalidWebhook se devuelve cuando la URL o los eventos del webhook no son válidos.
+	ErrInvalidWebhook = errors.New("invalid webhook")
+)
+
+// WebhookRepository gestiona las suscripciones, el outbox de eventos y los
+// envíos. Los eventos los escriben solas las escrituras de productos.
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, webhook *models.Webhook) error
+	GetWebhook(ctx context.Context, id string) (*models.Webhook, error)
+	// ListWebhooks devuelve los webhooks del subdominio, del más antiguo al más nuevo.
+	ListWebhooks(ctx context.Context, subdomain string) ([]models.Webhook, error)
+	// UpdateWebhook aplica fn sobre el webhook y lo guarda.
+	UpdateWebhook(ctx context.Context, id string, fn func(*models.Webhook) error) (*models.Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+
+	// ListPendingEvents devuelve los eventos del outbox que aún no se
+	// repartieron entre los webhooks, del más antiguo al más nuevo.
+	ListPendingEvents(ctx context.Context, limit int) ([]models.ProductEvent, error)
+	// DispatchEvent marca el evento como repartido y crea sus envíos en una
+	// sola transacción.
+	DispatchEvent(ctx context.Context, eventID string, deliveries []models.WebhookDelivery) error
+
+	// ListDueDeliveries devuelve los envíos pendientes cuyo próximo intento
+	// ya llegó, del más atrasado al más reciente.
+	ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error)
+	// ListDeliveries devuelve los envíos de un webhook, del más reciente al
+	// más antiguo. status vacío no filtra.
+	ListDeliveries(ctx context.Context, webhookID string, status models.DeliveryStatus, limit, offset int) ([]models.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, id string) (*models.WebhookDelivery, error)
+	// UpdateDelivery aplica fn sobre el envío y lo guarda.
+	UpdateDelivery(ctx context.Context, id string, fn func(*models.WebhookDelivery) error) (*models.WebhookDelivery, error)
+}
+
+// ValidateWebhook comprueba que la URL sea https absoluta, que si el host es
+// una IP sea pública y que los eventos existan. Los nombres de host se
+// comprueban aparte con CheckWebhookHost, que necesita resolverlos.
+func ValidateWebhook(webhook *models.Webhook) error {
+	u, err := url.Parse(webhook.URL)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return fmt.Errorf("%w: url must be an absolute https URL", ErrInvalidWebhook)
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !PublicIP(ip) {
+		return fmt.Errorf("%w: url must not point to a private, loopback or link-local address", ErrInvalidWebhook)
+	}
+	for _, eventType := range webhook.Events {
+		if !knownEventType(eventType) {
+			return fmt.Errorf("%w: unknown event %q", ErrInvalidWebhook, eventType)
+		}
+	}
+	return nil
+}
+
+// CheckWebhookHost resuelve el host de la URL y rechaza que alguna de sus
+// direcciones no sea pública. Evita registrar webhooks contra la red interna
+// (metadatos del proveedor, servicios en loopback o en la VPC); el envío lo
+// vuelve a comprobar al conectar, porque el DNS puede cambiar.
+func CheckWebhookHost(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("%w: cannot resolve %s", ErrInvalidWebhook, u.Hostname())
+	}
+	for _, addr := range addrs {
+		if !PublicIP(addr.IP) {
+			return fmt.Errorf("%w: %s resolves to a private, loopback or link-local address", ErrInvalidWebhook, u.Hostname())
+		}
+	}
+	return nil
+}
+
+// sharedAddressSpace es 100.64.0.0/10 (CGNAT), donde algunos proveedores
+// publican su servicio de metadatos.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// PublicIP indica si ip es una dirección unicast pública: no es loopback,
+// privada (RFC 1918, fc00::/7), link-local (169.254.0.0/16, fe80::/10),
+// CGNAT, 0.0.0.0/8, multicast ni sin especificar.
+func PublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil && ip4[0] == 0 {
+		return false
+	}
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+func knownEventType(eventType models.EventType) bool {
+	for _, t := range models.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
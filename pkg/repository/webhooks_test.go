@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+func TestValidateWebhook(t *testing.T) {
+	tests := []struct {
+		name    string
+		webhook models.Webhook
+		valid   bool
+	}{
+		{"https host", models.Webhook{URL: "https://hooks.example.com/catalog"}, true},
+		{"public ip", models.Webhook{URL: "https://93.184.216.34/hook"}, true},
+		{"known events", models.Webhook{URL: "https://example.com", Events: []models.EventType{models.EventStockChanged}}, true},
+		{"plain http", models.Webhook{URL: "http://hooks.example.com/catalog"}, false},
+		{"relative", models.Webhook{URL: "/hook"}, false},
+		{"loopback", models.Webhook{URL: "https://127.0.0.1:8080/hook"}, false},
+		{"ipv6 loopback", models.Webhook{URL: "https://[::1]/hook"}, false},
+		{"metadata", models.Webhook{URL: "https://169.254.169.254/latest/meta-data"}, false},
+		{"rfc1918", models.Webhook{URL: "https://10.0.0.5/hook"}, false},
+		{"unknown event", models.Webhook{URL: "https://example.com", Events: []models.EventType{"product.exploded"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateWebhook(&tt.webhook)
+			if tt.valid && err != nil {
+				t.Fatalf("ValidateWebhook(%s) = %v, want nil", tt.webhook.URL, err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidWebhook) {
+				t.Fatalf("ValidateWebhook(%s) = %v, want ErrInvalidWebhook", tt.webhook.URL, err)
+			}
+		})
+	}
+}
+
+func TestPublicIP(t *testing.T) {
+	tests := []struct {
+		ip     string
+		public bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.100.100.200", false},
+		{"0.0.0.0", false},
+		{"0.1.2.3", false},
+		{"224.0.0.1", false},
+		{"::1", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"::ffff:127.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := PublicIP(net.ParseIP(tt.ip)); got != tt.public {
+			t.Errorf("PublicIP(%s) = %v, want %v", tt.ip, got, tt.public)
+		}
+	}
+}
+
+func TestCheckWebhookHostRejectsLoopback(t *testing.T) {
+	if err := CheckWebhookHost(context.Background(), "https://localhost/hook"); !errors.Is(err, ErrInvalidWebhook) {
+		t.Fatalf("CheckWebhookHost(localhost) = %v, want ErrInvalidWebhook", err)
+	}
+}
@@ -0,0 +1,46 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/andrescris/products/pkg/repository"
+)
+
+// StartEventRetention elimina periódicamente del registro de eventos los ya
+// repartidos que tienen más de retention. Se detiene cuando ctx se cancela.
+func StartEventRetention(ctx context.Context, repo repository.EventRepository, interval, retention time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				cutoff := time.Now().UTC().Add(-retention)
+				purged, err := PurgeEvents(ctx, repo, cutoff)
+				if err != nil {
+					log.Printf("WORKER ERROR: purging events: %v", err)
+				}
+				if purged > 0 {
+					log.Printf("WORKER: event retention purged %d events created before %s", purged, cutoff.Format(time.RFC3339))
+				}
+			}
+		}
+	}()
+}
+
+// PurgeEvents elimina por lotes los eventos repartidos anteriores a cutoff.
+func PurgeEvents(ctx context.Context, repo repository.EventRepository, cutoff time.Time) (int, error) {
+	total := 0
+	for {
+		purged, err := repo.PurgeEvents(ctx, cutoff, sweepBatchSize)
+		total += purged
+		if err != nil || purged < sweepBatchSize {
+			return total, err
+		}
+	}
+}
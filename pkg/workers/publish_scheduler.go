@@ -0,0 +1,59 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/andrescris/firestore/lib/firebase"
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/repository"
+)
+
+// StartPublishScheduler publica periódicamente los borradores cuya
+// publicación programada ya llegó. Se detiene cuando ctx se cancela.
+func StartPublishScheduler(ctx context.Context, repo repository.ProductRepository, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				PublishScheduledProducts(ctx, repo, time.Now().UTC())
+			}
+		}
+	}()
+}
+
+// PublishScheduledProducts publica los borradores con publishAt anterior o
+// igual a now y devuelve cuántos se publicaron. Como ApplyScheduledPrices,
+// recorre los productos por ID para que un fallo no frene a los siguientes.
+func PublishScheduledProducts(ctx context.Context, repo repository.ProductRepository, now time.Time) int {
+	options := firebase.QueryOptions{
+		Filters: []firebase.QueryFilter{
+			{Field: "publishAt", Operator: "<=", Value: now.UTC().Format(time.RFC3339)},
+		},
+	}
+
+	published := 0
+	err := repository.EachProduct(ctx, repo, options, sweepBatchSize, func(product models.Product) error {
+		// PublishIfDue vuelve a comprobar el estado con los datos de la
+		// transacción; al publicar, el repositorio limpia publishAt.
+		result, err := repo.Mutate(ctx, product.ID, repository.AnyVersion, repository.PublishIfDue(now))
+		if err != nil {
+			log.Printf("WORKER ERROR: publishing product %s: %v", product.ID, err)
+			return nil
+		}
+		if result.PublishAt == nil {
+			published++
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("WORKER ERROR: listing scheduled publications: %v", err)
+	}
+	return published
+}
@@ -0,0 +1,29 @@
+package workers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+)
+
+func TestPublishScheduledProductsSkipsFailedProducts(t *testing.T) {
+	now := time.Now().UTC()
+	publishAt := now.Add(-time.Hour)
+	repo := newDueProducts(t, func(product *models.Product) {
+		product.Status = models.StatusDraft
+		product.PublishAt = &publishAt
+	})
+
+	if published := PublishScheduledProducts(context.Background(), repo, now); published != 1 {
+		t.Errorf("published = %d, want 1", published)
+	}
+	product, err := repo.Get(context.Background(), "z-ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if product.Status != models.StatusPublished || product.PublishAt != nil {
+		t.Errorf("z-ok status = %s, publishAt = %v", product.Status, product.PublishAt)
+	}
+}
@@ -0,0 +1,62 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/andrescris/products/pkg/repository"
+)
+
+// sweepBatchSize limita cuántas reservas vencidas se procesan por pasada.
+const sweepBatchSize = 100
+
+// StartReservationSweeper devuelve periódicamente al stock las reservas cuyo
+// TTL venció. Se detiene cuando ctx se cancela.
+func StartReservationSweeper(ctx context.Context, repo repository.ReservationRepository, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				SweepExpiredReservations(ctx, repo, time.Now().UTC())
+			}
+		}
+	}()
+}
+
+// SweepExpiredReservations libera todas las reservas vencidas a la fecha now y
+// devuelve cuántas se liberaron.
+func SweepExpiredReservations(ctx context.Context, repo repository.ReservationRepository, now time.Time) int {
+	released := 0
+	for {
+		expired, err := repo.ListExpiredReservations(ctx, now, sweepBatchSize)
+		if err != nil {
+			log.Printf("WORKER ERROR: listing expired reservations: %v", err)
+			return released
+		}
+
+		progressed := false
+		for _, reservation := range expired {
+			result, err := repo.ExpireReservation(ctx, reservation.ID, now)
+			if err != nil {
+				log.Printf("WORKER ERROR: expiring reservation %s: %v", reservation.ID, err)
+				continue
+			}
+			if result.Status != reservation.Status {
+				released++
+				progressed = true
+			}
+		}
+
+		// Sin avances (errores o reservas cerradas entretanto) no hay nada
+		// más que hacer en esta pasada.
+		if len(expired) < sweepBatchSize || !progressed {
+			return released
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/andrescris/firestore/lib/firebase"
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/repository"
+)
+
+// StartSaleScheduler vuelve a derivar periódicamente los productos cuya oferta
+// empezó o terminó, para que filter_price y on_sale reflejen el precio
+// vigente. Se detiene cuando ctx se cancela.
+func StartSaleScheduler(ctx context.Context, repo repository.ProductRepository, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				ApplyScheduledPrices(ctx, repo, time.Now().UTC())
+			}
+		}
+	}()
+}
+
+// ApplyScheduledPrices vuelve a derivar los productos con un cambio de precio
+// pendiente a la fecha now y devuelve cuántos se actualizaron. Recorre los
+// productos por ID, así un producto que no se puede guardar no frena a los
+// siguientes: queda pendiente para la próxima pasada.
+func ApplyScheduledPrices(ctx context.Context, repo repository.ProductRepository, now time.Time) int {
+	options := firebase.QueryOptions{
+		Filters: []firebase.QueryFilter{
+			{Field: "next_price_change_at", Operator: "<=", Value: now.UTC().Format(time.RFC3339)},
+		},
+	}
+
+	updated := 0
+	err := repository.EachProduct(ctx, repo, options, sweepBatchSize, func(product models.Product) error {
+		// Una mutación vacía basta: el repositorio deriva los campos al guardar.
+		if _, err := repo.Mutate(ctx, product.ID, repository.AnyVersion, func(*models.Product) error { return nil }); err != nil {
+			log.Printf("WORKER ERROR: applying scheduled prices to product %s: %v", product.ID, err)
+			return nil
+		}
+		updated++
+		return nil
+	})
+	if err != nil {
+		log.Printf("WORKER ERROR: listing scheduled price changes: %v", err)
+	}
+	return updated
+}
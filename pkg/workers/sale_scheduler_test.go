@@ -0,0 +1,72 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/repository"
+)
+
+// failingRepository es un MemoryRepository cuyas mutaciones fallan para los
+// productos con el prefijo indicado.
+type failingRepository struct {
+	*repository.MemoryRepository
+	prefix string
+}
+
+func (r failingRepository) Mutate(ctx context.Context, id string, expectedVersion int64, fn func(*models.Product) error) (*models.Product, error) {
+	if strings.HasPrefix(id, r.prefix) {
+		return nil, errors.New("mutation failed")
+	}
+	return r.MemoryRepository.Mutate(ctx, id, expectedVersion, fn)
+}
+
+// newDueProducts crea más de un lote de productos que fallan ("a-...") y,
+// después de ellos por ID, el producto "z-ok"; edit programa cada uno.
+func newDueProducts(t *testing.T, edit func(*models.Product)) failingRepository {
+	t.Helper()
+	repo := failingRepository{MemoryRepository: repository.NewMemoryRepository(), prefix: "a-"}
+	var ids []string
+	for i := 0; i <= sweepBatchSize; i++ {
+		ids = append(ids, fmt.Sprintf("a-%03d", i))
+	}
+	ids = append(ids, "z-ok")
+	for _, id := range ids {
+		product := &models.Product{
+			ID: id, Name: id, SKU: strings.ToUpper(id), Currency: "USD", Subdomain: "s", ProjectID: "proj",
+			Status: models.StatusPublished, Price: 1000,
+		}
+		edit(product)
+		if err := repo.Create(context.Background(), product); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo
+}
+
+func TestApplyScheduledPricesSkipsFailedProducts(t *testing.T) {
+	// El repositorio deriva los precios con la hora real: la oferta empieza
+	// en el segundo siguiente, después de crear los productos.
+	starts := time.Now().UTC().Add(300 * time.Millisecond).Truncate(time.Second).Add(time.Second)
+	repo := newDueProducts(t, func(product *models.Product) {
+		product.SalePrice = 800
+		product.SaleStartsAt = &starts
+	})
+	time.Sleep(time.Until(starts))
+
+	if updated := ApplyScheduledPrices(context.Background(), repo, time.Now().UTC()); updated != 1 {
+		t.Errorf("updated = %d, want 1", updated)
+	}
+	product, err := repo.Get(context.Background(), "z-ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if product.NextPriceChangeAt != nil || product.EffectivePrice != 800 {
+		t.Errorf("z-ok effective price = %d, next_price_change_at = %v", product.EffectivePrice, product.NextPriceChangeAt)
+	}
+}
@@ -0,0 +1,38 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/andrescris/products/pkg/maintenance"
+	"github.com/andrescris/products/pkg/repository"
+)
+
+// StartTrashRetention elimina periódicamente los productos y variaciones que
+// llevan más de retention en la papelera. Con dryRun solo registra en el log
+// lo que eliminaría. Se detiene cuando ctx se cancela.
+func StartTrashRetention(ctx context.Context, repo repository.ProductRepository, interval, retention time.Duration, dryRun bool) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				cutoff := time.Now().UTC().Add(-retention)
+				result, err := maintenance.PurgeTrash(ctx, repo, cutoff, dryRun, log.Writer())
+				if err != nil {
+					log.Printf("WORKER ERROR: purging trash: %v", err)
+					continue
+				}
+				if len(result.ProductsPurged) > 0 || len(result.VariationsPurged) > 0 {
+					log.Printf("WORKER: trash retention (dry run: %v) purged %d products and variations of %d products deleted before %s",
+						dryRun, len(result.ProductsPurged), len(result.VariationsPurged), cutoff.Format(time.RFC3339))
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,292 @@
+package workers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/repository"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxDeliveryAttempts es el número de intentos tras el cual un envío pasa
+	// a dead letter.
+	maxDeliveryAttempts = 8
+	// Los reintentos esperan baseRetryDelay y se duplican hasta maxRetryDelay.
+	baseRetryDelay = 30 * time.Second
+	maxRetryDelay  = 6 * time.Hour
+)
+
+// StartWebhookDelivery reparte periódicamente los eventos del outbox entre
+// los webhooks suscritos y envía los que toquen. Se detiene cuando ctx se
+// cancela.
+func StartWebhookDelivery(ctx context.Context, repo repository.WebhookRepository, client *http.Client, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				now := time.Now().UTC()
+				DispatchOutbox(ctx, repo, now)
+				DeliverWebhooks(ctx, repo, client, now)
+			}
+		}
+	}()
+}
+
+// NewWebhookClient devuelve el cliente HTTP de los envíos. Solo conecta con
+// direcciones públicas: resuelve el host en cada conexión y rechaza las IPs
+// privadas, de loopback o link-local, así que un webhook cuyo DNS cambie
+// después de registrarlo tampoco alcanza la red interna. No sigue
+// redirecciones (un 3xx cuenta como fallo) ni usa el proxy del entorno.
+func NewWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	transport := &http.Transport{
+		Proxy: nil,
+		DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			host, port, err := net.SplitHostPort(address)
+			if err != nil {
+				return nil, err
+			}
+			addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+			for _, addr := range addrs {
+				if !repository.PublicIP(addr.IP) {
+					return nil, fmt.Errorf("webhook host %s resolves to non-public address %s", host, addr.IP)
+				}
+			}
+			if len(addrs) == 0 {
+				return nil, fmt.Errorf("webhook host %s has no addresses", host)
+			}
+			// Se conecta a la IP ya comprobada, no al nombre, para que no se
+			// resuelva otra vez.
+			return dialer.DialContext(ctx, network, net.JoinHostPort(addrs[0].IP.String(), port))
+		},
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConnsPerHost:   2,
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// DispatchOutbox crea un envío por cada evento pendiente del outbox y cada
+// webhook activo de su subdominio que lo quiera. Devuelve cuántos eventos
+// se repartieron.
+func DispatchOutbox(ctx context.Context, repo repository.WebhookRepository, now time.Time) int {
+	dispatched := 0
+	subscribers := map[string][]models.Webhook{}
+	for {
+		events, err := repo.ListPendingEvents(ctx, sweepBatchSize)
+		if err != nil {
+			log.Printf("WORKER ERROR: listing outbox events: %v", err)
+			return dispatched
+		}
+
+		progressed := false
+		for _, event := range events {
+			webhooks, ok := subscribers[event.Subdomain]
+			if !ok {
+				webhooks, err = repo.ListWebhooks(ctx, event.Subdomain)
+				if err != nil {
+					log.Printf("WORKER ERROR: listing webhooks of %s: %v", event.Subdomain, err)
+					continue
+				}
+				subscribers[event.Subdomain] = webhooks
+			}
+
+			deliveries := []models.WebhookDelivery{}
+			for _, webhook := range webhooks {
+				if webhook.Active && webhook.Wants(event.Type) {
+					deliveries = append(deliveries, newDelivery(event, webhook.ID, now))
+				}
+			}
+			if err := repo.DispatchEvent(ctx, event.ID, deliveries); err != nil {
+				log.Printf("WORKER ERROR: dispatching event %s: %v", event.ID, err)
+				continue
+			}
+			dispatched++
+			progressed = true
+		}
+
+		if len(events) < sweepBatchSize || !progressed {
+			return dispatched
+		}
+	}
+}
+
+func newDelivery(event models.ProductEvent, webhookID string, now time.Time) models.WebhookDelivery {
+	return models.WebhookDelivery{
+		ID:            "dlv-" + uuid.New().String(),
+		WebhookID:     webhookID,
+		Subdomain:     event.Subdomain,
+		Event:         event,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// DeliverWebhooks hace un intento de cada envío pendiente cuyo turno ya
+// llegó (hasta sweepBatchSize por pasada) y devuelve cuántos se entregaron y
+// cuántos fallaron.
+func DeliverWebhooks(ctx context.Context, repo repository.WebhookRepository, client *http.Client, now time.Time) (delivered, failed int) {
+	due, err := repo.ListDueDeliveries(ctx, now, sweepBatchSize)
+	if err != nil {
+		log.Printf("WORKER ERROR: listing webhook deliveries: %v", err)
+		return 0, 0
+	}
+
+	webhooks := map[string]*models.Webhook{}
+	for _, delivery := range due {
+		webhook, ok := webhooks[delivery.WebhookID]
+		if !ok {
+			webhook, err = repo.GetWebhook(ctx, delivery.WebhookID)
+			if err != nil && !errors.Is(err, repository.ErrWebhookNotFound) {
+				log.Printf("WORKER ERROR: loading webhook %s: %v", delivery.WebhookID, err)
+				continue
+			}
+			webhooks[delivery.WebhookID] = webhook
+		}
+
+		var attempt func(*models.WebhookDelivery) error
+		switch {
+		case webhook == nil:
+			attempt = giveUp("webhook was deleted")
+		case !webhook.Active:
+			attempt = giveUp("webhook is inactive")
+		case repository.ValidateWebhook(webhook) != nil:
+			// Webhooks registrados antes de exigir https y direcciones públicas.
+			attempt = giveUp(repository.ValidateWebhook(webhook).Error())
+		default:
+			status, err := send(ctx, client, webhook, &delivery, now)
+			attempt = recordAttempt(status, err, now)
+		}
+
+		updated, err := repo.UpdateDelivery(ctx, delivery.ID, attempt)
+		if err != nil {
+			log.Printf("WORKER ERROR: updating webhook delivery %s: %v", delivery.ID, err)
+			continue
+		}
+		if updated.Status == models.DeliveryDelivered {
+			delivered++
+		} else {
+			failed++
+		}
+	}
+	return delivered, failed
+}
+
+// send publica el evento en la URL del webhook. Una respuesta 2xx cuenta
+// como entregada.
+func send(ctx context.Context, client *http.Client, webhook *models.Webhook, delivery *models.WebhookDelivery, now time.Time) (int, error) {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", string(delivery.Event.Type))
+	req.Header.Set("X-Webhook-Delivery", delivery.ID)
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", "sha256="+SignPayload(webhook.Secret, timestamp, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// SignPayload firma "<timestamp>.<body>" con HMAC-SHA256 y devuelve la firma
+// en hexadecimal. Los receptores la recalculan con el secreto del webhook y
+// la cabecera X-Webhook-Timestamp.
+func SignPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// recordAttempt anota el resultado de un intento: entregado, reintento con
+// espera exponencial o dead letter si se agotaron los intentos.
+func recordAttempt(status int, sendErr error, now time.Time) func(*models.WebhookDelivery) error {
+	return func(d *models.WebhookDelivery) error {
+		if d.Status != models.DeliveryPending {
+			return nil
+		}
+		d.Attempts++
+		d.LastStatusCode = status
+		if sendErr == nil {
+			d.Status = models.DeliveryDelivered
+			d.DeliveredAt = &now
+			d.LastError = ""
+			return nil
+		}
+		d.LastError = sendErr.Error()
+		if d.Attempts >= maxDeliveryAttempts {
+			d.Status = models.DeliveryDead
+			return nil
+		}
+		d.NextAttemptAt = now.Add(retryDelay(d.Attempts))
+		return nil
+	}
+}
+
+// giveUp pasa el envío a dead letter sin intentarlo.
+func giveUp(reason string) func(*models.WebhookDelivery) error {
+	return func(d *models.WebhookDelivery) error {
+		if d.Status != models.DeliveryPending {
+			return nil
+		}
+		d.Status = models.DeliveryDead
+		d.LastError = reason
+		return nil
+	}
+}
+
+// retryDelay es la espera antes del intento siguiente a attempts intentos fallidos.
+func retryDelay(attempts int) time.Duration {
+	delay := baseRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
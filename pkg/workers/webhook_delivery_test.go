@@ -0,0 +1,156 @@
+package workers
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrescris/products/pkg/models"
+	"github.com/andrescris/products/pkg/repository"
+)
+
+// testWebhookServer arranca un servidor TLS y devuelve un cliente que lo
+// alcanza con el nombre example.com, incluido en el certificado de httptest.
+func testWebhookServer(t *testing.T, handler http.HandlerFunc) *http.Client {
+	t.Helper()
+	server := httptest.NewTLSServer(handler)
+	t.Cleanup(server.Close)
+	client := server.Client()
+	transport := client.Transport.(*http.Transport)
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+	}
+	return client
+}
+
+// newWebhookStore crea un webhook de s y un producto cuyo evento queda en el outbox.
+func newWebhookStore(t *testing.T, url string) *repository.MemoryRepository {
+	t.Helper()
+	ctx := context.Background()
+	store := repository.NewMemoryRepository()
+	err := store.CreateWebhook(ctx, &models.Webhook{ID: "whk-1", Subdomain: "s", URL: url, Secret: "whsec_test", Active: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Create(ctx, &models.Product{ID: "p1", Name: "Tee", Subdomain: "s", Currency: "USD", SKU: "A", Price: 100, Stock: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := DispatchOutbox(ctx, store, time.Now().UTC()); n == 0 {
+		t.Fatal("DispatchOutbox dispatched no events")
+	}
+	return store
+}
+
+func deliveries(t *testing.T, store *repository.MemoryRepository) []models.WebhookDelivery {
+	t.Helper()
+	list, err := store.ListDeliveries(context.Background(), "whk-1", "", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
+
+func TestDeliverWebhooksSignsPayload(t *testing.T) {
+	var signatureOK bool
+	client := testWebhookServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get("X-Webhook-Timestamp"), 10, 64)
+		want := "sha256=" + SignPayload("whsec_test", timestamp, body)
+		signatureOK = r.Header.Get("X-Webhook-Signature") == want && r.Header.Get("X-Webhook-Event") != ""
+	})
+	store := newWebhookStore(t, "https://example.com/hook")
+
+	delivered, failed := DeliverWebhooks(context.Background(), store, client, time.Now().UTC())
+	if delivered == 0 || failed != 0 {
+		t.Fatalf("DeliverWebhooks = %d delivered, %d failed", delivered, failed)
+	}
+	if !signatureOK {
+		t.Fatal("the receiver could not verify the signature")
+	}
+	for _, d := range deliveries(t, store) {
+		if d.Status != models.DeliveryDelivered || d.Attempts != 1 {
+			t.Errorf("delivery %s: status %s after %d attempts", d.ID, d.Status, d.Attempts)
+		}
+	}
+}
+
+func TestDeliverWebhooksRetriesUntilDead(t *testing.T) {
+	client := testWebhookServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	store := newWebhookStore(t, "https://example.com/hook")
+
+	now := time.Now().UTC()
+	for attempt := 1; attempt <= maxDeliveryAttempts; attempt++ {
+		DeliverWebhooks(context.Background(), store, client, now)
+		for _, d := range deliveries(t, store) {
+			if d.Attempts != attempt || d.LastStatusCode != http.StatusInternalServerError {
+				t.Fatalf("attempt %d: delivery has %d attempts, last status %d", attempt, d.Attempts, d.LastStatusCode)
+			}
+			if attempt < maxDeliveryAttempts && (d.Status != models.DeliveryPending || !d.NextAttemptAt.Equal(now.Add(retryDelay(attempt)))) {
+				t.Fatalf("attempt %d: status %s, next attempt %s", attempt, d.Status, d.NextAttemptAt)
+			}
+		}
+		now = now.Add(maxRetryDelay)
+	}
+	for _, d := range deliveries(t, store) {
+		if d.Status != models.DeliveryDead {
+			t.Errorf("delivery %s: status %s, want dead", d.ID, d.Status)
+		}
+	}
+}
+
+func TestDeliverWebhooksDropsInsecureWebhooks(t *testing.T) {
+	called := false
+	client := testWebhookServer(t, func(w http.ResponseWriter, r *http.Request) { called = true })
+	store := newWebhookStore(t, "http://example.com/hook")
+
+	DeliverWebhooks(context.Background(), store, client, time.Now().UTC())
+	if called {
+		t.Fatal("the payload was sent over plain http")
+	}
+	for _, d := range deliveries(t, store) {
+		if d.Status != models.DeliveryDead || !strings.Contains(d.LastError, "https") {
+			t.Errorf("delivery %s: status %s, error %q", d.ID, d.Status, d.LastError)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{10, 256 * time.Minute},
+		{11, maxRetryDelay},
+	}
+	for _, tt := range tests {
+		if got := retryDelay(tt.attempts); got != tt.want {
+			t.Errorf("retryDelay(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestNewWebhookClientRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client := NewWebhookClient(time.Second)
+	_, err := client.Get(server.URL)
+	if err == nil || !strings.Contains(err.Error(), "non-public") {
+		t.Fatalf("GET %s = %v, want a non-public address error", server.URL, err)
+	}
+	if err := client.CheckRedirect(nil, nil); err != http.ErrUseLastResponse {
+		t.Fatalf("CheckRedirect = %v, want http.ErrUseLastResponse", err)
+	}
+}